@@ -0,0 +1,119 @@
+// Package apperr holds the typed errors handlers return. The central Fiber
+// error handler turns them into the JSON error envelope, so a handler never
+// writes an error response itself.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// machine readable error codes
+const (
+	CodeBadRequest         = "bad_request"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeGone               = "gone"
+	CodeTooLarge           = "payload_too_large"
+	CodeUnsupportedMedia   = "unsupported_media_type"
+	CodeValidation         = "validation_failed"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal_error"
+	CodeServiceUnavailable = "service_unavailable"
+)
+
+// Error is an error with the HTTP status and code it should be reported with
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Fields holds per field messages of a validation error
+	Fields map[string]string
+	// Err is the underlying cause; it is logged but never sent to clients
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New builds an error with any status and code
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func Unauthenticated(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func Gone(message string) *Error {
+	return New(http.StatusGone, CodeGone, message)
+}
+
+func TooLarge(message string) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodeTooLarge, message)
+}
+
+func UnsupportedMedia(message string) *Error {
+	return New(http.StatusUnsupportedMediaType, CodeUnsupportedMedia, message)
+}
+
+// Validation reports a request that was understood but is not acceptable
+func Validation(message string) *Error {
+	return New(http.StatusUnprocessableEntity, CodeValidation, message)
+}
+
+// InvalidField reports a single invalid field with its message
+func InvalidField(field string, message string) *Error {
+	return Validation(field + " " + message).WithFields(map[string]string{field: message})
+}
+
+// Internal wraps an unexpected failure; message is what the client sees
+func Internal(err error, message string) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: message, Err: err}
+}
+
+// Unavailable reports that the service cannot take requests right now
+func Unavailable(err error, message string) *Error {
+	return &Error{Status: http.StatusServiceUnavailable, Code: CodeServiceUnavailable, Message: message, Err: err}
+}
+
+// WithFields attaches per field messages to the error
+func (e *Error) WithFields(fields map[string]string) *Error {
+	e.Fields = fields
+	return e
+}
+
+// As returns the *Error in err's chain, nil when there is none
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
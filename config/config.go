@@ -0,0 +1,344 @@
+// Package config loads the server settings. Each setting starts at its
+// default, is overridden by the JSON config file when there is one, and then
+// by its environment variable. A .env file is read into the environment
+// first when it exists.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/swayanshu-2003/classroom-backend/storage"
+)
+
+// Duration is a time.Duration written like "30s" or "5m" in the config file
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return fmt.Errorf("durations are strings like \"30s\": %w", err)
+	}
+
+	d.Duration, err = time.ParseDuration(text)
+	return err
+}
+
+type Config struct {
+	Port      int    `json:"port"`
+	PublicURL string `json:"public_url"`
+	JWTSecret string `json:"jwt_secret"`
+	// AvatarProvider is "initials" to draw avatars offline or "remote"
+	AvatarProvider string `json:"avatar_provider"`
+
+	HTTP HTTPConfig `json:"http"`
+	DB   DBConfig   `json:"db"`
+	Blob BlobConfig `json:"blob"`
+}
+
+type HTTPConfig struct {
+	ReadTimeout  Duration `json:"read_timeout"`
+	WriteTimeout Duration `json:"write_timeout"`
+	IdleTimeout  Duration `json:"idle_timeout"`
+	// BodyLimit is the largest request body in bytes
+	BodyLimit int `json:"body_limit"`
+	// ShutdownTimeout is how long requests in flight may finish after SIGTERM
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+}
+
+type DBConfig struct {
+	Host            string   `json:"host"`
+	Port            int      `json:"port"`
+	User            string   `json:"user"`
+	Password        string   `json:"password"`
+	Name            string   `json:"name"`
+	SSLMode         string   `json:"ssl_mode"`
+	ConnectTimeout  Duration `json:"connect_timeout"`
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time"`
+}
+
+type BlobConfig struct {
+	// Store is "local" for a directory on disk or "s3"
+	Store string           `json:"store"`
+	Dir   string           `json:"dir"`
+	S3    storage.S3Config `json:"s3"`
+}
+
+var sslModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// Default is the configuration before any file or environment is applied
+func Default() *Config {
+	return &Config{
+		Port:           5600,
+		AvatarProvider: "initials",
+		HTTP: HTTPConfig{
+			ReadTimeout:  Duration{30 * time.Second},
+			WriteTimeout: Duration{60 * time.Second},
+			IdleTimeout:  Duration{2 * time.Minute},
+			// leave room for a full batch of attachments in one request
+			BodyLimit:       64 << 20,
+			ShutdownTimeout: Duration{20 * time.Second},
+		},
+		DB: DBConfig{
+			Port: 5432,
+			// libpq's own default, TLS when the server offers it
+			SSLMode:         "prefer",
+			ConnectTimeout:  Duration{10 * time.Second},
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration{30 * time.Minute},
+			ConnMaxIdleTime: Duration{5 * time.Minute},
+		},
+		Blob: BlobConfig{
+			Store: "local",
+			Dir:   "uploads",
+			S3:    storage.S3Config{UseSSL: true},
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the file named by
+// CONFIG_FILE (config.json when that exists) and the environment, and
+// validates it
+func Load() (*Config, error) {
+	err := godotenv.Load(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read .env: %w", err)
+	}
+
+	config := Default()
+
+	path, required := os.LookupEnv("CONFIG_FILE")
+	if !required {
+		path = "config.json"
+	}
+
+	err = config.loadFile(path, required)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.loadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.PublicURL) == 0 {
+		config.PublicURL = fmt.Sprintf("http://localhost:%d", config.Port)
+	}
+	config.PublicURL = strings.TrimSuffix(config.PublicURL, "/")
+
+	return config, config.Validate()
+}
+
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(c)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	env := envReader{}
+
+	env.int("PORT", &c.Port)
+	env.string("PUBLIC_URL", &c.PublicURL)
+	env.string("JWT_SECRET", &c.JWTSecret)
+	env.string("AVATAR_PROVIDER", &c.AvatarProvider)
+
+	env.duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	env.int("HTTP_BODY_LIMIT", &c.HTTP.BodyLimit)
+	env.duration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+
+	env.string("DB_HOST", &c.DB.Host)
+	env.int("DB_PORT", &c.DB.Port)
+	env.string("DB_USER", &c.DB.User)
+	env.string("DB_PASSWORD", &c.DB.Password)
+	env.string("DB_DATABASE", &c.DB.Name)
+	env.string("DB_SSLMODE", &c.DB.SSLMode)
+	env.duration("DB_CONNECT_TIMEOUT", &c.DB.ConnectTimeout)
+	env.int("DB_MAX_OPEN_CONNS", &c.DB.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &c.DB.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &c.DB.ConnMaxLifetime)
+	env.duration("DB_CONN_MAX_IDLE_TIME", &c.DB.ConnMaxIdleTime)
+
+	env.string("BLOB_STORE", &c.Blob.Store)
+	env.string("BLOB_DIR", &c.Blob.Dir)
+	env.string("S3_ENDPOINT", &c.Blob.S3.Endpoint)
+	env.string("S3_REGION", &c.Blob.S3.Region)
+	env.string("S3_BUCKET", &c.Blob.S3.Bucket)
+	env.string("S3_ACCESS_KEY", &c.Blob.S3.AccessKey)
+	env.string("S3_SECRET_KEY", &c.Blob.S3.SecretKey)
+	env.bool("S3_USE_SSL", &c.Blob.S3.UseSSL)
+
+	return errors.Join(env.errs...)
+}
+
+// Validate reports every setting that cannot be used. The token secret is
+// checked separately by RequireSecret, as migrations run without one.
+func (c *Config) Validate() error {
+	errs := []error{}
+
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Port > 0 && c.Port < 65536, "port %d is not a valid port", c.Port)
+	check(c.AvatarProvider == "initials" || c.AvatarProvider == "remote", "avatar provider must be initials or remote, not %q", c.AvatarProvider)
+
+	check(c.HTTP.ReadTimeout.Duration > 0, "http read timeout must be positive")
+	check(c.HTTP.WriteTimeout.Duration > 0, "http write timeout must be positive")
+	check(c.HTTP.IdleTimeout.Duration > 0, "http idle timeout must be positive")
+	check(c.HTTP.BodyLimit > 0, "http body limit must be positive")
+	check(c.HTTP.ShutdownTimeout.Duration > 0, "http shutdown timeout must be positive")
+
+	check(len(c.DB.Host) != 0, "DB_HOST must be set")
+	check(len(c.DB.User) != 0, "DB_USER must be set")
+	check(len(c.DB.Name) != 0, "DB_DATABASE must be set")
+	check(c.DB.Port > 0 && c.DB.Port < 65536, "db port %d is not a valid port", c.DB.Port)
+	check(sslModes[c.DB.SSLMode], "db ssl mode must be one of disable, allow, prefer, require, verify-ca or verify-full, not %q", c.DB.SSLMode)
+	check(c.DB.ConnectTimeout.Duration > 0, "db connect timeout must be positive")
+	check(c.DB.MaxOpenConns > 0, "db max open connections must be positive")
+	check(c.DB.MaxIdleConns >= 0 && c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db max idle connections must be between 0 and max open connections")
+	check(c.DB.ConnMaxLifetime.Duration >= 0, "db connection max lifetime cannot be negative")
+	check(c.DB.ConnMaxIdleTime.Duration >= 0, "db connection max idle time cannot be negative")
+
+	switch c.Blob.Store {
+	case "local":
+		check(len(c.Blob.Dir) != 0, "BLOB_DIR must be set for local blob storage")
+	case "s3":
+		check(len(c.Blob.S3.Endpoint) != 0 && len(c.Blob.S3.Bucket) != 0, "S3_ENDPOINT and S3_BUCKET must be set for s3 blob storage")
+	default:
+		check(false, "blob store must be local or s3, not %q", c.Blob.Store)
+	}
+
+	return errors.Join(errs...)
+}
+
+// minSecretLength is the shortest token secret accepted, in bytes
+const minSecretLength = 32
+
+// placeholderSecrets are sample values from docs and examples; anyone can
+// forge tokens signed with them
+var placeholderSecrets = map[string]bool{
+	"change-me-in-production": true,
+	"change-me":               true,
+	"changeme":                true,
+	"secret":                  true,
+	"your-secret":             true,
+	"your-secret-key":         true,
+	"jwt-secret":              true,
+}
+
+// RequireSecret fails when there is no usable secret to sign session tokens
+// with: it must be set, not a known placeholder and at least 32 bytes long
+func (c *Config) RequireSecret() error {
+	secret := strings.TrimSpace(c.JWTSecret)
+
+	switch {
+	case len(secret) == 0:
+		return errors.New("JWT_SECRET must be set to sign session tokens")
+	case placeholderSecrets[strings.ToLower(secret)]:
+		return errors.New("JWT_SECRET is a placeholder, set it to a random value")
+	case len(secret) < minSecretLength:
+		return fmt.Errorf("JWT_SECRET must be at least %d bytes long", minSecretLength)
+	}
+	return nil
+}
+
+// Storage is the database connection configuration
+func (c *Config) Storage() *storage.Config {
+	return &storage.Config{
+		Host:            c.DB.Host,
+		Port:            c.DB.Port,
+		DBName:          c.DB.Name,
+		Username:        c.DB.User,
+		Password:        c.DB.Password,
+		SSLmode:         c.DB.SSLMode,
+		ConnectTimeout:  c.DB.ConnectTimeout.Duration,
+		MaxOpenConns:    c.DB.MaxOpenConns,
+		MaxIdleConns:    c.DB.MaxIdleConns,
+		ConnMaxLifetime: c.DB.ConnMaxLifetime.Duration,
+		ConnMaxIdleTime: c.DB.ConnMaxIdleTime.Duration,
+	}
+}
+
+// envReader applies environment variables that are set, collecting parse errors
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(name string, target *string) {
+	if value, ok := os.LookupEnv(name); ok {
+		*target = value
+	}
+}
+
+func (e *envReader) int(name string, target *int) {
+	if value, ok := os.LookupEnv(name); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a whole number, not %q", name, value))
+			return
+		}
+		*target = n
+	}
+}
+
+func (e *envReader) bool(name string, target *bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be true or false, not %q", name, value))
+			return
+		}
+		*target = b
+	}
+}
+
+func (e *envReader) duration(name string, target *Duration) {
+	if value, ok := os.LookupEnv(name); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a duration like 30s, not %q", name, value))
+			return
+		}
+		target.Duration = d
+	}
+}
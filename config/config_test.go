@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequireSecret(t *testing.T) {
+	for _, tc := range []struct {
+		secret string
+		ok     bool
+	}{
+		{"", false},
+		{"   ", false},
+		{"change-me-in-production", false},
+		{"CHANGE-ME-IN-PRODUCTION", false},
+		{"short but random 7Hq2", false},
+		{strings.Repeat("x", 31), false},
+		{strings.Repeat("x", 32), true},
+		{"kX3p9Vf0mZ2qL8rT5wY1bN6cJ4hG7dSa", true},
+	} {
+		config := Default()
+		config.JWTSecret = tc.secret
+
+		err := config.RequireSecret()
+		if (err == nil) != tc.ok {
+			t.Errorf("RequireSecret(%q) = %v, want ok %v", tc.secret, err, tc.ok)
+		}
+	}
+}
+
+// valid fills in the settings that have no default
+func valid() *Config {
+	config := Default()
+	config.DB.Host, config.DB.User, config.DB.Name = "localhost", "classroom", "classroom"
+	return config
+}
+
+func TestValidate(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("expected the defaults to be valid once the database is named, got %v", err)
+	}
+
+	if err := Default().Validate(); err == nil || !strings.Contains(err.Error(), "DB_HOST") {
+		t.Fatalf("expected the missing database settings to be reported, got %v", err)
+	}
+
+	for name, change := range map[string]func(config *Config){
+		"port":        func(config *Config) { config.Port = 70000 },
+		"avatar":      func(config *Config) { config.AvatarProvider = "gravatar" },
+		"ssl mode":    func(config *Config) { config.DB.SSLMode = "" },
+		"idle conns":  func(config *Config) { config.DB.MaxIdleConns = config.DB.MaxOpenConns + 1 },
+		"open conns":  func(config *Config) { config.DB.MaxOpenConns = 0 },
+		"timeout":     func(config *Config) { config.DB.ConnectTimeout.Duration = 0 },
+		"body limit":  func(config *Config) { config.HTTP.BodyLimit = 0 },
+		"blob store":  func(config *Config) { config.Blob.Store = "ftp" },
+		"blob dir":    func(config *Config) { config.Blob.Dir = "" },
+		"s3 settings": func(config *Config) { config.Blob.Store = "s3" },
+	} {
+		config := valid()
+		change(config)
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("expected a broken %s to be refused", name)
+		}
+	}
+}
+
+// writeFile writes a file into a temporary directory and returns its path
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.json", `{
+		"port": 8080,
+		"db": {"host": "file-host", "user": "file-user", "name": "classroom", "ssl_mode": "require", "connect_timeout": "3s"}
+	}`))
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("DB_MAX_OPEN_CONNS", "40")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the environment beats the file, which beats the defaults
+	if config.DB.Host != "env-host" || config.DB.MaxOpenConns != 40 {
+		t.Errorf("expected the environment to win, got %+v", config.DB)
+	}
+	if config.Port != 8080 || config.DB.User != "file-user" || config.DB.SSLMode != "require" || config.DB.ConnectTimeout.Duration != 3*time.Second {
+		t.Errorf("expected the file to override the defaults, got %+v", config)
+	}
+	if config.DB.Port != 5432 || config.DB.MaxIdleConns != 5 {
+		t.Errorf("expected the defaults to fill the rest, got %+v", config.DB)
+	}
+	if config.PublicURL != "http://localhost:8080" {
+		t.Errorf("expected the public url to follow the port, got %q", config.PublicURL)
+	}
+
+	if storage := config.Storage(); storage.SSLmode != "require" || storage.Port != 5432 {
+		t.Errorf("expected the ssl mode to reach the database, got %+v", storage)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	// a config file that was asked for has to exist
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "could not read config file") {
+		t.Errorf("expected a missing config file to be refused, got %v", err)
+	}
+
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.json", `{"prot": 8080}`))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Errorf("expected an unknown setting to be refused, got %v", err)
+	}
+
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.json", `{"db": {"host": "db", "user": "u", "name": "n", "connect_timeout": 10}}`))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "durations are strings") {
+		t.Errorf("expected a duration without a unit to be refused, got %v", err)
+	}
+
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.json", `{"db": {"host": "db", "user": "u", "name": "n"}}`))
+	t.Setenv("DB_PORT", "five")
+	t.Setenv("S3_USE_SSL", "maybe")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "DB_PORT") || !strings.Contains(err.Error(), "S3_USE_SSL") {
+		t.Errorf("expected every unreadable variable to be reported, got %v", err)
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	dir := filepath.Dir(writeFile(t, ".env", "DB_HOST=dotenv-host\nDB_USER=u\nDB_DATABASE=n\n"))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		// godotenv sets what it reads for the whole process
+		for _, name := range []string{"DB_HOST", "DB_USER", "DB_DATABASE"} {
+			os.Unsetenv(name)
+		}
+	})
+
+	config, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DB.Host != "dotenv-host" {
+		t.Fatalf("expected .env to be read, got %q", config.DB.Host)
+	}
+
+	// and without one the server still starts from the environment
+	os.Remove(filepath.Join(dir, ".env"))
+	t.Setenv("DB_HOST", "env-host")
+
+	if config, err = Load(); err != nil || config.DB.Host != "env-host" {
+		t.Fatalf("expected .env to be optional, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Assignment is the response shape of an assignment
+type Assignment struct {
+	ID          *string           `json:"id"`
+	IsDeleted   bool              `json:"is_deleted"`
+	Title       *string           `json:"title"`
+	Type        *string           `json:"type"`
+	Description *string           `json:"description"`
+	Link        *string           `json:"link"`
+	ClassID     *string           `json:"class_id"`
+	AutherId    *string           `json:"auther_id"`
+	Points      *float64          `json:"points"`
+	CategoryID  *uint             `json:"category_id"`
+	DueAt       *time.Time        `json:"due_at"`
+	DueTimezone *string           `json:"due_timezone"`
+	DueAtLocal  *string           `json:"due_at_local"`
+	CloseAt     *time.Time        `json:"close_at"`
+	LatePenalty float64           `json:"late_penalty"`
+	MaxPenalty  float64           `json:"max_penalty"`
+	IsDraft     bool              `json:"is_draft"`
+	PublishAt   *time.Time        `json:"publish_at"`
+	IsPublished bool              `json:"is_published"`
+	IsOverdue   bool              `json:"is_overdue"`
+	IsClosed    bool              `json:"is_closed"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Classroom   *Classroom        `json:"classroom,omitempty"`
+	CreatedBy   *PublicUser       `json:"created_by,omitempty"`
+	Rubric      []RubricCriterion `json:"rubric,omitempty"`
+}
+
+// NewAssignment maps an assignment model and whatever relations were preloaded
+func NewAssignment(assignment models.Assignments) Assignment {
+	now := time.Now()
+
+	return Assignment{
+		ID:          assignment.ID,
+		IsDeleted:   assignment.IsDeleted,
+		Title:       assignment.Title,
+		Type:        assignment.Type,
+		Description: assignment.Description,
+		Link:        assignment.Link,
+		ClassID:     assignment.ClassID,
+		AutherId:    assignment.AutherId,
+		Points:      assignment.Points,
+		CategoryID:  assignment.CategoryID,
+		DueAt:       assignment.DueAt,
+		DueTimezone: assignment.DueTimezone,
+		DueAtLocal:  assignment.LocalDueAt(),
+		CloseAt:     assignment.CloseAt,
+		LatePenalty: assignment.LatePenalty,
+		MaxPenalty:  assignment.MaxPenalty,
+		IsDraft:     assignment.IsDraft,
+		PublishAt:   assignment.PublishAt,
+		IsPublished: assignment.IsPublished(now),
+		IsOverdue:   assignment.IsOverdue(now),
+		IsClosed:    assignment.IsClosed(now),
+		CreatedAt:   assignment.CreatedAt,
+		Classroom:   newClassroomRef(assignment.Classroom),
+		CreatedBy:   NewPublicUser(assignment.CreatedBy),
+		Rubric:      NewRubric(assignment.Rubric),
+	}
+}
+
+// NewAssignments maps a list of assignments
+func NewAssignments(assignments []models.Assignments) []Assignment {
+	res := make([]Assignment, 0, len(assignments))
+	for _, assignment := range assignments {
+		res = append(res, NewAssignment(assignment))
+	}
+	return res
+}
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Attachment is the response shape of an uploaded file; content is fetched through a signed url
+type Attachment struct {
+	ID          *string   `json:"id"`
+	OwnerType   string    `json:"owner_type"`
+	OwnerID     string    `json:"owner_id"`
+	UploadedBy  *string   `json:"uploaded_by"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewAttachments maps a list of attachments
+func NewAttachments(attachments []models.Attachment) []Attachment {
+	res := make([]Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		res = append(res, Attachment{
+			ID:          attachment.ID,
+			OwnerType:   attachment.OwnerType,
+			OwnerID:     attachment.OwnerID,
+			UploadedBy:  attachment.UploadedBy,
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			CreatedAt:   attachment.CreatedAt,
+		})
+	}
+	return res
+}
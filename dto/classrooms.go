@@ -0,0 +1,126 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Classroom is the response shape of a classroom
+type Classroom struct {
+	ClassId       *string        `json:"class_id"`
+	ClassName     *string        `json:"class_name"`
+	Description   *string        `json:"description"`
+	Done          bool           `json:"done"`
+	OwnerID       *string        `json:"owner_id"`
+	IsDeleted     bool           `json:"is_deleted"`
+	ArchivedAt    *time.Time     `json:"archived_at"`
+	TrashedAt     *time.Time     `json:"trashed_at,omitempty"`
+	Shared        bool           `json:"shared"`
+	JoinCode      *string        `json:"join_code"`
+	JoinEnabled   bool           `json:"join_enabled"`
+	JoinExpiresAt *time.Time     `json:"join_expires_at"`
+	JoinMode      string         `json:"join_mode"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Owner         *PublicUser    `json:"owner,omitempty"`
+	Collaborators []Collaborator `json:"collaborators,omitempty"`
+	Assignments   []Assignment   `json:"assignments,omitempty"`
+}
+
+// Collaborator is the response shape of a classroom membership
+type Collaborator struct {
+	UserID      *string     `json:"user_id"`
+	ClassID     *string     `json:"class_id"`
+	Role        string      `json:"role"`
+	IsRemoved   bool        `json:"is_removed"`
+	Status      string      `json:"status"`
+	RequestedAt *time.Time  `json:"requested_at,omitempty"`
+	DecidedAt   *time.Time  `json:"decided_at,omitempty"`
+	User        *PublicUser `json:"users,omitempty"`
+	Classroom   *Classroom  `json:"classroom,omitempty"`
+}
+
+// NewClassroom maps a classroom model and whatever relations were preloaded
+func NewClassroom(classroom models.Classroom) Classroom {
+	return Classroom{
+		ClassId:       classroom.ClassId,
+		ClassName:     classroom.ClassName,
+		Description:   classroom.Description,
+		Done:          classroom.Done,
+		OwnerID:       classroom.OwnerID,
+		IsDeleted:     classroom.IsDeleted,
+		ArchivedAt:    classroom.ArchivedAt,
+		TrashedAt:     classroom.TrashedAt,
+		Shared:        classroom.Shared,
+		JoinCode:      classroom.JoinCode,
+		JoinEnabled:   classroom.JoinEnabled,
+		JoinExpiresAt: classroom.JoinExpiresAt,
+		JoinMode:      classroom.JoinMode,
+		CreatedAt:     classroom.CreatedAt,
+		Owner:         NewPublicUser(classroom.Owner),
+		Collaborators: NewCollaborators(classroom.Collaborators),
+		Assignments:   NewAssignments(classroom.Assignments),
+	}
+}
+
+// NewClassrooms maps a list of classrooms
+func NewClassrooms(classrooms []models.Classroom) []Classroom {
+	res := make([]Classroom, 0, len(classrooms))
+	for _, classroom := range classrooms {
+		res = append(res, NewClassroom(classroom))
+	}
+	return res
+}
+
+// newClassroomRef maps a preloaded classroom relation, nil when it was not loaded
+func newClassroomRef(classroom models.Classroom) *Classroom {
+	if classroom.ClassId == nil {
+		return nil
+	}
+
+	res := NewClassroom(classroom)
+	return &res
+}
+
+// NewCollaborator maps a classroom membership
+func NewCollaborator(collaborator models.ClassroomCollaborator) Collaborator {
+	return Collaborator{
+		UserID:      collaborator.UserID,
+		ClassID:     collaborator.ClassID,
+		Role:        collaborator.Role,
+		IsRemoved:   collaborator.IsRemoved,
+		Status:      collaborator.Status,
+		RequestedAt: collaborator.RequestedAt,
+		DecidedAt:   collaborator.DecidedAt,
+		User:        NewPublicUser(collaborator.User),
+		Classroom:   newClassroomRef(collaborator.Classroom),
+	}
+}
+
+// NewCollaborators maps a list of classroom memberships
+func NewCollaborators(collaborators []models.ClassroomCollaborator) []Collaborator {
+	res := make([]Collaborator, 0, len(collaborators))
+	for _, collaborator := range collaborators {
+		res = append(res, NewCollaborator(collaborator))
+	}
+	return res
+}
+
+// MemberClassroom is a classroom as listed for one of its members, with the
+// role they hold in it
+type MemberClassroom struct {
+	Classroom
+	Role string `json:"role"`
+}
+
+// NewMemberClassrooms maps classrooms with the caller's role in each, keyed by class id
+func NewMemberClassrooms(classrooms []models.Classroom, roles map[string]string) []MemberClassroom {
+	res := make([]MemberClassroom, 0, len(classrooms))
+	for _, classroom := range classrooms {
+		res = append(res, MemberClassroom{
+			Classroom: NewClassroom(classroom),
+			Role:      roles[*classroom.ClassId],
+		})
+	}
+	return res
+}
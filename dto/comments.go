@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Comment is the response shape of a stream post or reply
+type Comment struct {
+	ID         uint        `json:"id"`
+	Content    *string     `json:"content"`
+	AuthorID   *string     `json:"author_id"`
+	ClassID    *string     `json:"class_id"`
+	ParentID   *uint       `json:"parent_id"`
+	Edited     bool        `json:"edited"`
+	Deleted    bool        `json:"deleted"`
+	EditedAt   *time.Time  `json:"edited_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+	ReplyCount int64       `json:"reply_count"`
+	Author     *PublicUser `json:"author,omitempty"`
+}
+
+// CommentEdit is a previous version of a comment
+type CommentEdit struct {
+	ID        uint      `json:"id"`
+	Content   *string   `json:"content"`
+	EditedBy  *string   `json:"edited_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// deletedContent stands in for a deleted comment kept so its replies stay in
+// their thread
+const deletedContent = "[deleted]"
+
+// NewComment maps a comment model with its author, if preloaded. A deleted
+// comment is mapped as a tombstone without its content or author.
+func NewComment(comment models.Comment) Comment {
+	if comment.DeletedAt.Valid {
+		content := deletedContent
+		return Comment{
+			ID:        comment.ID,
+			Content:   &content,
+			ClassID:   comment.ClassID,
+			ParentID:  comment.ParentID,
+			Deleted:   true,
+			CreatedAt: comment.CreatedAt,
+		}
+	}
+
+	return Comment{
+		ID:        comment.ID,
+		Content:   comment.Content,
+		AuthorID:  comment.AuthorID,
+		ClassID:   comment.ClassID,
+		ParentID:  comment.ParentID,
+		Edited:    comment.EditedAt != nil,
+		EditedAt:  comment.EditedAt,
+		CreatedAt: comment.CreatedAt,
+		Author:    NewPublicUser(comment.Author),
+	}
+}
+
+// NewComments maps a list of comments, filling reply counts from the given map
+func NewComments(comments []models.Comment, replyCounts map[uint]int64) []Comment {
+	res := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		c := NewComment(comment)
+		c.ReplyCount = replyCounts[comment.ID]
+		res = append(res, c)
+	}
+	return res
+}
+
+// NewCommentEdits maps the edit history of a comment
+func NewCommentEdits(edits []models.CommentEdit) []CommentEdit {
+	res := make([]CommentEdit, 0, len(edits))
+	for _, edit := range edits {
+		res = append(res, CommentEdit{
+			ID:        edit.ID,
+			Content:   edit.Content,
+			EditedBy:  edit.EditedBy,
+			CreatedAt: edit.CreatedAt,
+		})
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// GradeCategory is the response shape of a weighted grade category
+type GradeCategory struct {
+	ID        uint      `json:"id"`
+	ClassID   *string   `json:"class_id"`
+	Name      *string   `json:"name"`
+	Weight    float64   `json:"weight"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RubricCriterion is the response shape of one rubric line
+type RubricCriterion struct {
+	ID          uint    `json:"id"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Points      float64 `json:"points"`
+	Position    int     `json:"position"`
+}
+
+// RubricScore is the response shape of the points earned on a rubric line
+type RubricScore struct {
+	CriterionID uint    `json:"criterion_id"`
+	Points      float64 `json:"points"`
+	Comment     *string `json:"comment"`
+}
+
+// GradebookColumn is an assignment as a gradebook column
+type GradebookColumn struct {
+	AssignmentID *string `json:"assignment_id"`
+	Title        *string `json:"title"`
+	Points       float64 `json:"points"`
+	CategoryID   *uint   `json:"category_id"`
+}
+
+// GradebookRow is one student's line of the gradebook. Grades are keyed by
+// assignment id and averages are percentages, nil while nothing is graded.
+type GradebookRow struct {
+	Student    *PublicUser         `json:"student"`
+	Average    *float64            `json:"average"`
+	Categories map[uint]*float64   `json:"categories"`
+	Grades     map[string]*float64 `json:"grades"`
+}
+
+// NewGradeCategory maps a grade category
+func NewGradeCategory(category models.GradeCategory) GradeCategory {
+	return GradeCategory{
+		ID:        category.ID,
+		ClassID:   category.ClassID,
+		Name:      category.Name,
+		Weight:    category.Weight,
+		CreatedAt: category.CreatedAt,
+	}
+}
+
+// NewGradeCategories maps a list of grade categories
+func NewGradeCategories(categories []models.GradeCategory) []GradeCategory {
+	res := make([]GradeCategory, 0, len(categories))
+	for _, category := range categories {
+		res = append(res, NewGradeCategory(category))
+	}
+	return res
+}
+
+// NewRubric maps the criteria of a rubric
+func NewRubric(criteria []models.RubricCriterion) []RubricCriterion {
+	res := make([]RubricCriterion, 0, len(criteria))
+	for _, criterion := range criteria {
+		res = append(res, RubricCriterion{
+			ID:          criterion.ID,
+			Title:       criterion.Title,
+			Description: criterion.Description,
+			Points:      criterion.Points,
+			Position:    criterion.Position,
+		})
+	}
+	return res
+}
+
+// NewRubricScores maps the rubric scores of a submission
+func NewRubricScores(scores []models.RubricScore) []RubricScore {
+	res := make([]RubricScore, 0, len(scores))
+	for _, score := range scores {
+		res = append(res, RubricScore{
+			CriterionID: score.CriterionID,
+			Points:      score.Points,
+			Comment:     score.Comment,
+		})
+	}
+	return res
+}
@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Invitation is the response shape of a classroom invitation
+type Invitation struct {
+	ID         *string     `json:"id"`
+	ClassID    *string     `json:"class_id"`
+	Token      string      `json:"token"`
+	Role       string      `json:"role"`
+	InviteeID  *string     `json:"invitee_id"`
+	MaxUses    *int        `json:"max_uses"`
+	Uses       int         `json:"uses"`
+	ExpiresAt  *time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time  `json:"revoked_at"`
+	DeclinedAt *time.Time  `json:"declined_at"`
+	CreatedBy  *string     `json:"created_by"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Usable     bool        `json:"usable"`
+	Classroom  *Classroom  `json:"classroom,omitempty"`
+	Invitee    *PublicUser `json:"invitee,omitempty"`
+}
+
+// NewInvitation maps an invitation and whatever relations were preloaded
+func NewInvitation(invitation models.Invitation) Invitation {
+	return Invitation{
+		ID:         invitation.ID,
+		ClassID:    invitation.ClassID,
+		Token:      invitation.Token,
+		Role:       invitation.Role,
+		InviteeID:  invitation.InviteeID,
+		MaxUses:    invitation.MaxUses,
+		Uses:       invitation.Uses,
+		ExpiresAt:  invitation.ExpiresAt,
+		RevokedAt:  invitation.RevokedAt,
+		DeclinedAt: invitation.DeclinedAt,
+		CreatedBy:  invitation.CreatedBy,
+		CreatedAt:  invitation.CreatedAt,
+		Usable:     invitation.Usable(time.Now()),
+		Classroom:  newClassroomRef(invitation.Classroom),
+		Invitee:    NewPublicUser(invitation.Invitee),
+	}
+}
+
+// NewInvitations maps a list of invitations
+func NewInvitations(invitations []models.Invitation) []Invitation {
+	res := make([]Invitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		res = append(res, NewInvitation(invitation))
+	}
+	return res
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Notification is the response shape of a notification
+type Notification struct {
+	ID        uint       `json:"id"`
+	ClassID   *string    `json:"class_id"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewNotifications maps a list of notifications
+func NewNotifications(notifications []models.Notification) []Notification {
+	res := make([]Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		res = append(res, Notification{
+			ID:        notification.ID,
+			ClassID:   notification.ClassID,
+			Type:      notification.Type,
+			Message:   notification.Message,
+			ReadAt:    notification.ReadAt,
+			CreatedAt: notification.CreatedAt,
+		})
+	}
+	return res
+}
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Session is the response shape of a login session, without its token digests
+type Session struct {
+	ID         *string    `json:"id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewSessions maps a list of sessions
+func NewSessions(sessions []models.Session) []Session {
+	res := make([]Session, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, Session{
+			ID:         session.ID,
+			ExpiresAt:  session.ExpiresAt,
+			RevokedAt:  session.RevokedAt,
+			LastUsedAt: session.LastUsedAt,
+			CreatedAt:  session.CreatedAt,
+		})
+	}
+	return res
+}
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// Submission is the response shape of a student's work on an assignment
+type Submission struct {
+	ID             *string       `json:"id"`
+	AssignmentID   *string       `json:"assignment_id"`
+	StudentID      *string       `json:"student_id"`
+	ClassID        *string       `json:"class_id"`
+	State          string        `json:"state"`
+	Link           *string       `json:"link"`
+	Note           *string       `json:"note"`
+	TurnedInAt     *time.Time    `json:"turned_in_at"`
+	ReturnedAt     *time.Time    `json:"returned_at"`
+	Grade          *float64      `json:"grade"`
+	PenaltyPercent float64       `json:"penalty_percent"`
+	AdjustedGrade  *float64      `json:"adjusted_grade"`
+	Feedback       *string       `json:"feedback"`
+	GradedAt       *time.Time    `json:"graded_at"`
+	GradedBy       *string       `json:"graded_by"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Student        *PublicUser   `json:"student,omitempty"`
+	RubricScores   []RubricScore `json:"rubric_scores,omitempty"`
+}
+
+// StudentSubmission is one row of the teacher view: a student and where their work stands
+type StudentSubmission struct {
+	Student    *PublicUser `json:"student"`
+	State      string      `json:"state"`
+	Submission *Submission `json:"submission"`
+}
+
+// NewAssignmentSubmission maps a submission with the state and late penalty
+// that follow from its assignment's deadlines
+func NewAssignmentSubmission(assignment models.Assignments, submission models.Submission) Submission {
+	res := NewSubmission(submission)
+	res.State = assignment.SubmissionState(&submission, time.Now())
+	res.PenaltyPercent = assignment.PenaltyPercent(&submission)
+	res.AdjustedGrade = assignment.AdjustedGrade(&submission)
+	return res
+}
+
+// NewStudentSubmission is NewAssignmentSubmission as the student sees it,
+// without the grade and feedback until they are returned
+func NewStudentSubmission(assignment models.Assignments, submission models.Submission) Submission {
+	res := NewAssignmentSubmission(assignment, submission)
+
+	if !submission.GradeReleased() {
+		res.Grade = nil
+		res.AdjustedGrade = nil
+		res.Feedback = nil
+		res.GradedAt = nil
+		res.GradedBy = nil
+		res.RubricScores = nil
+	}
+	return res
+}
+
+// NewSubmission maps a submission model
+func NewSubmission(submission models.Submission) Submission {
+	return Submission{
+		ID:           submission.ID,
+		AssignmentID: submission.AssignmentID,
+		StudentID:    submission.StudentID,
+		ClassID:      submission.ClassID,
+		State:        submission.State,
+		Link:         submission.Link,
+		Note:         submission.Note,
+		TurnedInAt:   submission.TurnedInAt,
+		ReturnedAt:   submission.ReturnedAt,
+		Grade:        submission.Grade,
+		Feedback:     submission.Feedback,
+		GradedAt:     submission.GradedAt,
+		GradedBy:     submission.GradedBy,
+		CreatedAt:    submission.CreatedAt,
+		UpdatedAt:    submission.UpdatedAt,
+		Student:      NewPublicUser(submission.Student),
+		RubricScores: NewRubricScores(submission.RubricScores),
+	}
+}
@@ -0,0 +1,44 @@
+package dto
+
+import "github.com/swayanshu-2003/classroom-backend/models"
+
+// PublicUser is the view of a user that any logged in user may see
+type PublicUser struct {
+	Uuid           *string `json:"uuid"`
+	Username       *string `json:"username"`
+	Name           *string `json:"name"`
+	ProfilePicture *string `json:"profile_picture"`
+}
+
+// PrivateUser is the view of a user returned to that same user
+type PrivateUser struct {
+	PublicUser
+	Collaborations []Collaborator `json:"collaborations,omitempty"`
+}
+
+// NewPublicUser maps a user model to its public view, nil when the relation was not loaded
+func NewPublicUser(user models.Users) *PublicUser {
+	if user.Uuid == nil {
+		return nil
+	}
+
+	return &PublicUser{
+		Uuid:           user.Uuid,
+		Username:       user.Username,
+		Name:           user.Name,
+		ProfilePicture: user.ProfilePicture,
+	}
+}
+
+// NewPrivateUser maps a user model to the view its owner sees
+func NewPrivateUser(user models.Users) *PrivateUser {
+	public := NewPublicUser(user)
+	if public == nil {
+		return nil
+	}
+
+	return &PrivateUser{
+		PublicUser:     *public,
+		Collaborations: NewCollaborators(user.Collaborations),
+	}
+}
@@ -0,0 +1,197 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+func TestCreateAssignment(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	outsider := register(t, "outsider")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	call(t, "POST", "/assignment/create", "", fiber.Map{"class_id": class.ID, "title": "homework"}).expectError(t, 401, "unauthenticated")
+	call(t, "POST", "/assignment/create", student.Token, fiber.Map{"class_id": class.ID, "title": "homework"}).expectError(t, 403, "forbidden")
+	call(t, "POST", "/assignment/create", outsider.Token, fiber.Map{"class_id": class.ID, "title": "homework"}).expectError(t, 403, "forbidden")
+
+	res := call(t, "POST", "/assignment/create", teacher.Token, fiber.Map{
+		"class_id": class.ID,
+		"type":     "essay",
+	}).expectError(t, 422, "validation_failed")
+
+	if res.get("fields.title") == nil || res.get("fields.type") == nil {
+		t.Fatalf("expected field errors for title and type, got %v", res.Body)
+	}
+
+	due := time.Now().Add(48 * time.Hour)
+
+	call(t, "POST", "/assignment/create", teacher.Token, fiber.Map{
+		"class_id": class.ID,
+		"title":    "homework",
+		"due_at":   due,
+		"close_at": due.Add(-time.Hour),
+	}).expectError(t, 422, "validation_failed")
+
+	call(t, "POST", "/assignment/create", teacher.Token, fiber.Map{
+		"class_id":    class.ID,
+		"title":       "homework",
+		"category_id": 999999,
+	}).expectError(t, 422, "validation_failed")
+
+	id := createAssignment(t, class, teacher, fiber.Map{"due_at": due, "points": 10})
+
+	list := call(t, "GET", "/assignments/"+class.ID, student.Token, nil).expect(t, 200)
+
+	if list.get("meta.total") != float64(1) {
+		t.Fatalf("expected the student to see one assignment, got %v", list.Body)
+	}
+
+	call(t, "GET", "/assignments/"+class.ID, outsider.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "GET", "/assignment/"+id+"/attachments", student.Token, nil).expect(t, 200)
+}
+
+func TestCreateAssignmentWithMaterials(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	class := createClassroom(t, teacher)
+
+	fields := func(title string) map[string]string {
+		encoded, _ := json.Marshal(fiber.Map{"class_id": class.ID, "title": title})
+		return map[string]string{"assignment": string(encoded)}
+	}
+
+	res := upload(t, "/assignment/create", teacher.Token, fields("reading"), map[string]string{
+		"notes.txt": "read chapter one",
+	}).expect(t, 200)
+
+	id := res.str(t, "data.id")
+
+	materials := call(t, "GET", "/assignment/"+id+"/attachments", teacher.Token, nil).expect(t, 200)
+
+	data, _ := materials.get("data").([]interface{})
+	if len(data) != 1 {
+		t.Fatalf("expected the uploaded material, got %v", materials.Body)
+	}
+
+	// a rejected file leaves no assignment behind
+	upload(t, "/assignment/create", teacher.Token, fields("broken"), map[string]string{
+		"notes.txt": "fine",
+		"tool.exe":  "MZ not allowed",
+	}).expectError(t, 415, "unsupported_media_type")
+
+	if total := call(t, "GET", "/assignments/"+class.ID, teacher.Token, nil).expect(t, 200).get("meta.total"); total != float64(1) {
+		t.Fatalf("expected only the first assignment, got %v", total)
+	}
+
+	upload(t, "/assignment/create", teacher.Token, map[string]string{"assignment": "{"}, nil).expectError(t, 400, "bad_request")
+}
+
+func TestDraftAssignments(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	id := createAssignment(t, class, teacher, fiber.Map{"is_draft": true})
+
+	// drafts do not exist for students
+	call(t, "GET", "/assignment/"+id+"/attachments", student.Token, nil).expectError(t, 404, "not_found")
+
+	if total := call(t, "GET", "/assignments/"+class.ID, student.Token, nil).expect(t, 200).get("meta.total"); total != float64(0) {
+		t.Fatalf("expected the draft to be hidden, got %v", total)
+	}
+
+	call(t, "POST", "/assignment/"+id+"/publish", student.Token, nil).expectError(t, 404, "not_found")
+
+	res := call(t, "POST", "/assignment/"+id+"/publish", teacher.Token, nil).expect(t, 200)
+
+	if res.get("data.is_draft") != false {
+		t.Fatalf("expected the assignment to be published, got %v", res.Body)
+	}
+
+	call(t, "GET", "/assignment/"+id+"/attachments", student.Token, nil).expect(t, 200)
+
+	call(t, "POST", "/assignment/"+id+"/unpublish", teacher.Token, nil).expect(t, 200)
+	call(t, "GET", "/assignment/"+id+"/attachments", student.Token, nil).expectError(t, 404, "not_found")
+}
+
+func TestEditAssignment(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	coTeacher := register(t, "coteacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+	join(t, class, coTeacher)
+
+	promote(t, class, coTeacher)
+
+	id := createAssignment(t, class, teacher, nil)
+
+	edit := func(editor account, body fiber.Map) response {
+		return call(t, "PATCH", "/assignment/"+id+"/edit", editor.Token, body)
+	}
+
+	edit(student, fiber.Map{"title": "renamed"}).expectError(t, 403, "forbidden")
+
+	// only the author edits an assignment
+	edit(coTeacher, fiber.Map{"title": "renamed"}).expectError(t, 403, "forbidden")
+
+	edit(teacher, fiber.Map{}).expectError(t, 422, "validation_failed")
+	edit(teacher, fiber.Map{"due_timezone": "Nowhere/Special"}).expectError(t, 422, "validation_failed")
+	edit(teacher, fiber.Map{"late_penalty": 150}).expectError(t, 422, "validation_failed")
+
+	edit(teacher, fiber.Map{"title": "renamed", "due_timezone": "Asia/Kolkata"}).expect(t, 200)
+
+	list := call(t, "GET", "/assignments/"+class.ID, teacher.Token, nil).expect(t, 200)
+
+	data, _ := list.get("data").([]interface{})
+	if len(data) != 1 || data[0].(map[string]interface{})["title"] != "renamed" {
+		t.Fatalf("expected the renamed assignment, got %v", list.Body)
+	}
+
+	call(t, "PATCH", "/assignment/"+unique("missing")+"/edit", teacher.Token, fiber.Map{"title": "x"}).expectError(t, 404, "not_found")
+}
+
+func TestZeroPenaltiesAreKept(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	class := createClassroom(t, teacher)
+
+	// 0 means never deduct, and must not fall back to a column default
+	id := createAssignment(t, class, teacher, fiber.Map{"late_penalty": 0, "max_penalty": 0, "is_draft": false})
+	lenient := createAssignment(t, class, teacher, nil)
+
+	var stored models.Assignments
+	if err := db.Where("id = ?", id).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.MaxPenalty != 0 || stored.LatePenalty != 0 || stored.IsDraft {
+		t.Fatalf("expected the penalties to be stored as 0, got %v and %v", stored.LatePenalty, stored.MaxPenalty)
+	}
+
+	if err := db.Where("id = ?", lenient).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.MaxPenalty != 100 {
+		t.Fatalf("expected the penalty to be capped at 100 by default, got %v", stored.MaxPenalty)
+	}
+}
@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSubmissionAttachments(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	classmate := register(t, "classmate")
+	outsider := register(t, "outsider")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+	join(t, class, classmate)
+
+	id := createAssignment(t, class, teacher, nil)
+	assignment := "/assignment/" + id
+
+	upload(t, assignment+"/submission/attachments", teacher.Token, nil, map[string]string{"essay.txt": "by the teacher"}).expectError(t, 403, "forbidden")
+	upload(t, assignment+"/submission/attachments", student.Token, nil, nil).expectError(t, 422, "validation_failed")
+
+	res := upload(t, assignment+"/submission/attachments", student.Token, nil, map[string]string{"essay.txt": "my essay"}).expect(t, 200)
+	attachment := "/attachments/" + res.items(t, "data")[0].(map[string]interface{})["id"].(string)
+
+	// a student's files are theirs and their teachers' to see
+	call(t, "GET", attachment+"/url", classmate.Token, nil).expectError(t, 404, "not_found")
+	call(t, "GET", attachment+"/url", outsider.Token, nil).expectError(t, 403, "forbidden")
+
+	signed := call(t, "GET", attachment+"/url", teacher.Token, nil).expect(t, 200).str(t, "url")
+
+	status, content := download(t, signed)
+	if status != 200 || content != "my essay" {
+		t.Fatalf("expected the file through the signed url, got %d %q", status, content)
+	}
+
+	if status, _ := download(t, signed+"x"); status != 403 {
+		t.Fatalf("expected a tampered url to be refused, got %d", status)
+	}
+
+	listed := call(t, "GET", assignment+"/submissions/"+student.ID+"/attachments", teacher.Token, nil).expect(t, 200)
+	if len(listed.items(t, "data")) != 1 {
+		t.Fatalf("expected the student's file, got %v", listed.Body)
+	}
+
+	// teachers do not take files off a student's work, and nobody does once it is handed in
+	call(t, "DELETE", attachment, teacher.Token, nil).expectError(t, 403, "forbidden")
+
+	call(t, "POST", assignment+"/turn-in", student.Token, fiber.Map{}).expect(t, 200)
+	call(t, "DELETE", attachment, student.Token, nil).expectError(t, 409, "conflict")
+	upload(t, assignment+"/submission/attachments", student.Token, nil, map[string]string{"late.txt": "more"}).expectError(t, 409, "conflict")
+
+	call(t, "POST", assignment+"/unsubmit", student.Token, nil).expect(t, 200)
+	call(t, "DELETE", attachment, student.Token, nil).expect(t, 200)
+	call(t, "GET", attachment+"/url", student.Token, nil).expectError(t, 404, "not_found")
+
+	// graded and returned work keeps its files, both ways
+	res = upload(t, assignment+"/submission/attachments", student.Token, nil, map[string]string{"essay.txt": "my essay again"}).expect(t, 200)
+	attachment = "/attachments/" + res.items(t, "data")[0].(map[string]interface{})["id"].(string)
+
+	call(t, "POST", assignment+"/turn-in", student.Token, fiber.Map{}).expect(t, 200)
+	call(t, "PUT", assignment+"/submissions/"+student.ID+"/grade", teacher.Token, fiber.Map{"grade": 9}).expect(t, 200)
+	call(t, "POST", assignment+"/submissions/"+student.ID+"/return", teacher.Token, nil).expect(t, 200)
+
+	upload(t, assignment+"/submission/attachments", student.Token, nil, map[string]string{"extra.txt": "more"}).expectError(t, 409, "conflict")
+	call(t, "DELETE", attachment, student.Token, nil).expectError(t, 409, "conflict")
+}
+
+func TestCommentAttachments(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	classmate := register(t, "classmate")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+	join(t, class, classmate)
+
+	comments := "/classroom/" + class.ID + "/comments/"
+
+	commentId := call(t, "POST", comments, student.Token, fiber.Map{"content": "see attached"}).expect(t, 200).id(t, "data.id")
+
+	res := upload(t, comments+commentId+"/attachments", student.Token, nil, map[string]string{"notes.txt": "shared notes"}).expect(t, 200)
+	attachment := "/attachments/" + res.items(t, "data")[0].(map[string]interface{})["id"].(string)
+
+	// the whole class can read it, only the uploader and teachers remove it
+	call(t, "GET", attachment+"/url", classmate.Token, nil).expect(t, 200)
+	call(t, "DELETE", attachment, classmate.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "DELETE", attachment, teacher.Token, nil).expect(t, 200)
+
+	if listed := call(t, "GET", comments+commentId+"/attachments", student.Token, nil).expect(t, 200); len(listed.items(t, "data")) != 0 {
+		t.Fatalf("expected the file to be gone, got %v", listed.Body)
+	}
+	// deleting a comment takes its files along
+	upload(t, comments+commentId+"/attachments", student.Token, nil, map[string]string{"notes.txt": "more notes"}).expect(t, 200)
+	call(t, "DELETE", comments+commentId, student.Token, nil).expect(t, 200)
+
+	var left int64
+	db.Table("attachments").Where("owner_type = ? AND owner_id = ?", "comment", commentId).Count(&left)
+	if left != 0 {
+		t.Fatalf("expected the comment's files to be deleted, %d left", left)
+	}
+}
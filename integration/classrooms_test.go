@@ -0,0 +1,161 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateClassroom(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+
+	call(t, "POST", "/classroom/create", "", fiber.Map{"class_name": "maths"}).expectError(t, 401, "unauthenticated")
+	call(t, "POST", "/classroom/create", teacher.Token, fiber.Map{}).expectError(t, 422, "validation_failed")
+
+	class := createClassroom(t, teacher)
+
+	res := call(t, "GET", "/classroom/"+class.ID, teacher.Token, nil).expect(t, 200)
+
+	if res.get("role") != "owner" || res.get("data.owner_id") != teacher.ID {
+		t.Fatalf("expected the creator to own the classroom, got %v", res.Body)
+	}
+
+	list := call(t, "GET", "/classrooms", teacher.Token, nil).expect(t, 200)
+
+	if list.get("meta.total") != float64(1) {
+		t.Fatalf("expected one classroom, got %v", list.Body)
+	}
+}
+
+func TestClassroomAccess(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	outsider := register(t, "outsider")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	call(t, "GET", "/classroom/"+class.ID, outsider.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "GET", "/classroom/"+unique("missing"), teacher.Token, nil).expectError(t, 404, "not_found")
+	call(t, "GET", "/classroom/members/"+class.ID, outsider.Token, nil).expectError(t, 403, "forbidden")
+
+	// only the owner edits the classroom
+	call(t, "PATCH", "/classroom/edit/"+class.ID, student.Token, fiber.Map{"class_name": "renamed"}).expectError(t, 403, "forbidden")
+
+	res := call(t, "PATCH", "/classroom/edit/"+class.ID, teacher.Token, fiber.Map{"class_name": "renamed"}).expect(t, 200)
+
+	if res.get("data.class_name") != "renamed" {
+		t.Fatalf("expected the new name, got %v", res.Body)
+	}
+
+	call(t, "PATCH", "/classroom/edit/"+class.ID, teacher.Token, fiber.Map{}).expectError(t, 422, "validation_failed")
+}
+
+func TestJoinClassroom(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+
+	call(t, "POST", "/classroom/join", student.Token, fiber.Map{"join_code": "NOPE0000"}).expectError(t, 404, "not_found")
+	call(t, "POST", "/classroom/join", "", fiber.Map{"join_code": class.JoinCode}).expectError(t, 401, "unauthenticated")
+
+	join(t, class, student)
+
+	call(t, "POST", "/classroom/join", student.Token, fiber.Map{"join_code": class.JoinCode}).expectError(t, 409, "conflict")
+
+	res := call(t, "GET", "/classroom/"+class.ID, student.Token, nil).expect(t, 200)
+
+	if res.get("role") != "student" {
+		t.Fatalf("expected to join as a student, got %v", res.Body)
+	}
+
+	members := call(t, "GET", "/classroom/members/"+class.ID, student.Token, nil).expect(t, 200)
+
+	if members.get("meta.total") != float64(2) {
+		t.Fatalf("expected the teacher and the student, got %v", members.Body)
+	}
+
+	// a switched off code stops working
+	call(t, "PATCH", "/classroom/"+class.ID+"/join-code", teacher.Token, fiber.Map{"enabled": false}).expect(t, 200)
+
+	late := register(t, "late")
+
+	call(t, "POST", "/classroom/join", late.Token, fiber.Map{"join_code": class.JoinCode}).expectError(t, 403, "forbidden")
+}
+
+func TestTeachersManageJoinCode(t *testing.T) {
+	requireDB(t)
+
+	owner := register(t, "owner")
+	coTeacher := register(t, "coteacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, owner)
+	join(t, class, coTeacher)
+	join(t, class, student)
+	promote(t, class, coTeacher)
+
+	joinCode := "/classroom/" + class.ID + "/join-code"
+
+	call(t, "POST", joinCode+"/regenerate", student.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "PATCH", joinCode, student.Token, fiber.Map{"enabled": false}).expectError(t, 403, "forbidden")
+
+	// co-teachers look after the code as well as the owner
+	res := call(t, "POST", joinCode+"/regenerate", coTeacher.Token, nil).expect(t, 200)
+
+	if code := res.str(t, "data.join_code"); code == class.JoinCode {
+		t.Fatalf("expected a new join code, got %v", code)
+	}
+
+	call(t, "PATCH", joinCode, coTeacher.Token, fiber.Map{"enabled": false}).expect(t, 200)
+
+	// renaming the classroom stays with the owner
+	call(t, "PATCH", "/classroom/edit/"+class.ID, coTeacher.Token, fiber.Map{"class_name": "renamed"}).expectError(t, 403, "forbidden")
+}
+
+func TestExitClassroom(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	classmate := register(t, "classmate")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+	join(t, class, classmate)
+
+	exit := func(actor account, member account) response {
+		return call(t, "PATCH", "/classroom/exit/"+class.ID+"/"+member.ID, actor.Token, nil)
+	}
+
+	// students cannot remove others, and the owner has to transfer the classroom first
+	exit(student, classmate).expectError(t, 403, "forbidden")
+	exit(student, teacher).expectError(t, 409, "conflict")
+	exit(teacher, teacher).expectError(t, 409, "conflict")
+
+	exit(student, student).expect(t, 200)
+	exit(teacher, classmate).expect(t, 200)
+
+	call(t, "GET", "/classroom/"+class.ID, student.Token, nil).expectError(t, 403, "forbidden")
+	exit(teacher, classmate).expectError(t, 404, "not_found")
+}
+
+func TestArchivedClassroomIsReadOnly(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	class := createClassroom(t, teacher)
+
+	call(t, "POST", "/classroom/"+class.ID+"/archive", teacher.Token, nil).expect(t, 200)
+
+	call(t, "GET", "/classroom/"+class.ID, teacher.Token, nil).expect(t, 200)
+	call(t, "PATCH", "/classroom/edit/"+class.ID, teacher.Token, fiber.Map{"class_name": "renamed"}).expectError(t, 403, "forbidden")
+	call(t, "POST", "/assignment/create", teacher.Token, fiber.Map{"class_id": class.ID, "title": "homework"}).expectError(t, 403, "forbidden")
+}
@@ -0,0 +1,197 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestComments(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	outsider := register(t, "outsider")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	comments := "/classroom/" + class.ID + "/comments/"
+
+	call(t, "POST", comments, outsider.Token, fiber.Map{"content": "hello"}).expectError(t, 403, "forbidden")
+	call(t, "GET", comments, outsider.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "POST", comments, student.Token, fiber.Map{"content": ""}).expectError(t, 422, "validation_failed")
+
+	post := call(t, "POST", comments, teacher.Token, fiber.Map{"content": "welcome"}).expect(t, 200)
+	postId := post.id(t, "data.id")
+
+	reply := call(t, "POST", comments, student.Token, fiber.Map{"content": "thanks", "parent_id": post.get("data.id")}).expect(t, 200)
+	replyId := reply.id(t, "data.id")
+
+	call(t, "POST", comments, student.Token, fiber.Map{"content": "orphan", "parent_id": 999999}).expectError(t, 404, "not_found")
+
+	// replies are not part of the stream
+	stream := call(t, "GET", comments, student.Token, nil).expect(t, 200)
+
+	if stream.get("meta.total") != float64(1) || stream.items(t, "data")[0].(map[string]interface{})["reply_count"] != float64(1) {
+		t.Fatalf("expected the post with one reply, got %v", stream.Body)
+	}
+
+	replies := call(t, "GET", comments+postId+"/replies", teacher.Token, nil).expect(t, 200)
+
+	if replies.get("meta.total") != float64(1) {
+		t.Fatalf("expected one reply, got %v", replies.Body)
+	}
+
+	// only the author edits, and the old content is kept
+	call(t, "PATCH", comments+replyId, teacher.Token, fiber.Map{"content": "changed"}).expectError(t, 403, "forbidden")
+	call(t, "PATCH", comments+replyId, student.Token, fiber.Map{"content": "thank you"}).expect(t, 200)
+
+	history := call(t, "GET", comments+replyId+"/history", teacher.Token, nil).expect(t, 200)
+
+	edits := history.items(t, "data")
+	if len(edits) != 1 || edits[0].(map[string]interface{})["content"] != "thanks" || history.get("current.content") != "thank you" {
+		t.Fatalf("expected the first version in the history, got %v", history.Body)
+	}
+
+	// students delete their own comments, teachers anyone's
+	call(t, "DELETE", comments+postId, student.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "DELETE", comments+replyId, teacher.Token, nil).expect(t, 200)
+	call(t, "GET", comments+replyId, student.Token, nil).expectError(t, 404, "not_found")
+
+	call(t, "DELETE", comments+postId, teacher.Token, nil).expect(t, 200)
+
+	if total := call(t, "GET", comments, student.Token, nil).expect(t, 200).get("meta.total"); total != float64(0) {
+		t.Fatalf("expected an empty stream, got %v", total)
+	}
+}
+
+func TestDeletedCommentKeepsReplies(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	comments := "/classroom/" + class.ID + "/comments/"
+
+	post := call(t, "POST", comments, student.Token, fiber.Map{"content": "question"}).expect(t, 200)
+	postId := post.id(t, "data.id")
+
+	call(t, "POST", comments, teacher.Token, fiber.Map{"content": "answer", "parent_id": post.get("data.id")}).expect(t, 200)
+	call(t, "DELETE", comments+postId, student.Token, nil).expect(t, 200)
+
+	call(t, "GET", comments+postId, teacher.Token, nil).expectError(t, 404, "not_found")
+
+	// the post stays in the stream as a tombstone while it has replies
+	stream := call(t, "GET", comments, teacher.Token, nil).expect(t, 200)
+
+	items := stream.items(t, "data")
+	if len(items) != 1 {
+		t.Fatalf("expected the deleted post in the stream, got %v", stream.Body)
+	}
+
+	tombstone := items[0].(map[string]interface{})
+	if tombstone["deleted"] != true || tombstone["content"] != "[deleted]" || tombstone["author"] != nil || tombstone["reply_count"] != float64(1) {
+		t.Fatalf("expected a tombstone with one reply, got %v", tombstone)
+	}
+
+	replies := call(t, "GET", comments+postId+"/replies", student.Token, nil).expect(t, 200)
+
+	if len(replies.items(t, "data")) != 1 {
+		t.Fatalf("expected the reply under the deleted post, got %v", replies.Body)
+	}
+
+	// nothing can be added to a deleted post
+	call(t, "POST", comments, teacher.Token, fiber.Map{"content": "late", "parent_id": post.get("data.id")}).expectError(t, 404, "not_found")
+}
+
+func TestDeletedChainKeepsLiveLeaf(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	comments := "/classroom/" + class.ID + "/comments/"
+
+	// live post, then two replies that get deleted, then a live reply at the bottom
+	chain := []string{}
+	parent := interface{}(nil)
+
+	for _, content := range []string{"post", "first", "second", "leaf"} {
+		body := fiber.Map{"content": content}
+		if parent != nil {
+			body["parent_id"] = parent
+		}
+
+		res := call(t, "POST", comments, student.Token, body).expect(t, 200)
+		chain = append(chain, res.id(t, "data.id"))
+		parent = res.get("data.id")
+	}
+
+	call(t, "DELETE", comments+chain[1], student.Token, nil).expect(t, 200)
+	call(t, "DELETE", comments+chain[2], student.Token, nil).expect(t, 200)
+
+	// every step down from the stream is still there
+	for i, id := range chain[:3] {
+		replies := call(t, "GET", comments+id+"/replies", teacher.Token, nil).expect(t, 200)
+
+		items := replies.items(t, "data")
+		if len(items) != 1 {
+			t.Fatalf("expected one reply under step %d, got %v", i, replies.Body)
+		}
+
+		reply := items[0].(map[string]interface{})
+		if deleted := i+1 < 3; reply["deleted"] != deleted {
+			t.Fatalf("expected step %d to be deleted=%v, got %v", i+1, deleted, reply)
+		}
+	}
+
+	// once the leaf goes, so do the tombstones above it
+	call(t, "DELETE", comments+chain[3], student.Token, nil).expect(t, 200)
+
+	if replies := call(t, "GET", comments+chain[0]+"/replies", teacher.Token, nil).expect(t, 200); len(replies.items(t, "data")) != 0 {
+		t.Fatalf("expected nothing left under the post, got %v", replies.Body)
+	}
+}
+
+func TestCommentPages(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	class := createClassroom(t, teacher)
+
+	comments := "/classroom/" + class.ID + "/comments/"
+
+	for _, content := range []string{"first", "second", "third"} {
+		call(t, "POST", comments, teacher.Token, fiber.Map{"content": content}).expect(t, 200)
+	}
+
+	seen := []string{}
+	path := comments + "?limit=2"
+
+	for len(path) != 0 {
+		page := call(t, "GET", path, teacher.Token, nil).expect(t, 200)
+
+		for _, item := range page.items(t, "data") {
+			seen = append(seen, item.(map[string]interface{})["content"].(string))
+		}
+
+		path = ""
+		if cursor, ok := page.get("meta.next_cursor").(string); ok {
+			path = comments + "?limit=2&cursor=" + cursor
+		}
+	}
+
+	if len(seen) != 3 || seen[0] != "third" || seen[2] != "first" {
+		t.Fatalf("expected every comment once, newest first, got %v", seen)
+	}
+
+	call(t, "GET", comments+"?cursor=garbage", teacher.Token, nil).expectError(t, 400, "bad_request")
+	call(t, "GET", comments+"?sort=content", teacher.Token, nil).expectError(t, 400, "bad_request")
+}
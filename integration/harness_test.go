@@ -0,0 +1,450 @@
+// Package integration runs the HTTP API end to end against a real Postgres.
+//
+// The database comes from TEST_DATABASE_URL, a postgres:// url of a server
+// the tests may create and drop databases on; every run gets a fresh
+// database. Without it, a throwaway cluster is started in a temp directory
+// when initdb and pg_ctl are on the PATH. When neither is available the
+// tests are skipped.
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/middlewares"
+	"github.com/swayanshu-2003/classroom-backend/migrations"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+	"github.com/swayanshu-2003/classroom-backend/storage"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"gorm.io/gorm"
+)
+
+const tokenSecret = "integration-test-secret-of-enough-length"
+
+var (
+	// app, db and repo are nil when there is no database to test against
+	app        *fiber.App
+	db         *gorm.DB
+	repo       *middlewares.Repository
+	skipReason string
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dsn, cleanup, err := database()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integration:", err)
+		return 1
+	}
+	defer cleanup()
+
+	if len(dsn) == 0 {
+		skipReason = "no database: set TEST_DATABASE_URL or put initdb and pg_ctl on the PATH"
+		return m.Run()
+	}
+
+	db, err = storage.Open(dsn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integration: could not connect:", err)
+		return 1
+	}
+
+	_, err = migrations.Up(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integration: could not migrate:", err)
+		return 1
+	}
+
+	blobDir, err := os.MkdirTemp("", "classroom-blobs-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integration:", err)
+		return 1
+	}
+	defer os.RemoveAll(blobDir)
+
+	blobs, err := storage.NewLocalBlobStore(blobDir, "http://localhost", []byte(tokenSecret))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integration:", err)
+		return 1
+	}
+
+	repo = &middlewares.Repository{
+		DB:          db,
+		TokenSecret: []byte(tokenSecret),
+		Blobs:       blobs,
+		Avatars:     utils.InitialsAvatar{},
+		Services:    services.New(gormstore.New(db), utils.InitialsAvatar{}),
+	}
+
+	app = fiber.New(fiber.Config{ErrorHandler: middlewares.ErrorHandler})
+	repo.SetupRoutes(app)
+
+	return m.Run()
+}
+
+// database provides a connection string for a database of this run, empty
+// when there is none, and a cleanup that removes it again
+func database() (string, func(), error) {
+	if raw := os.Getenv("TEST_DATABASE_URL"); len(raw) != 0 {
+		return freshDatabase(raw)
+	}
+
+	_, initdbErr := exec.LookPath("initdb")
+	_, pgCtlErr := exec.LookPath("pg_ctl")
+
+	// initdb refuses to run as root
+	if initdbErr != nil || pgCtlErr != nil || os.Geteuid() == 0 {
+		return "", func() {}, nil
+	}
+	return throwawayCluster()
+}
+
+// freshDatabase creates a uniquely named database on the server at raw
+func freshDatabase(raw string) (string, func(), error) {
+	server, err := url.Parse(raw)
+	if err != nil || (server.Scheme != "postgres" && server.Scheme != "postgresql") {
+		return "", nil, errors.New("TEST_DATABASE_URL must be a postgres:// url")
+	}
+
+	admin, err := storage.Open(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not connect to TEST_DATABASE_URL: %w", err)
+	}
+
+	name := fmt.Sprintf("classroom_test_%d", time.Now().UnixNano())
+
+	err = admin.Exec("CREATE DATABASE " + name).Error
+	if err != nil {
+		return "", nil, fmt.Errorf("could not create a test database: %w", err)
+	}
+
+	cleanup := func() {
+		if db != nil {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		}
+		admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)")
+	}
+
+	server.Path = "/" + name
+	return server.String(), cleanup, nil
+}
+
+// throwawayCluster starts a postgres server on a unix socket in a temp directory
+func throwawayCluster() (string, func(), error) {
+	dir, err := os.MkdirTemp("", "classroom-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	data := filepath.Join(dir, "data")
+
+	output, err := exec.Command("initdb", "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb failed: %v\n%s", err, output)
+	}
+
+	options := fmt.Sprintf("-k %s -c listen_addresses='' -F", dir)
+
+	output, err = exec.Command("pg_ctl", "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start failed: %v\n%s", err, output)
+	}
+
+	cleanup := func() {
+		exec.Command("pg_ctl", "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+
+	return fmt.Sprintf("host=%s user=postgres dbname=postgres sslmode=disable", dir), cleanup, nil
+}
+
+// requireDB skips the test when there is no database
+func requireDB(t *testing.T) {
+	t.Helper()
+	if app == nil {
+		t.Skip(skipReason)
+	}
+}
+
+/*------------------------------------------------ requests ------------------------------------------------------*/
+
+// response is a decoded JSON body with its status
+type response struct {
+	Status int
+	Body   map[string]interface{}
+}
+
+// call sends a request to the app, with a bearer token when one is given
+func call(t *testing.T, method string, path string, token string, body interface{}) response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, "/api/v1"+path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return send(t, req)
+}
+
+// upload sends a multipart form with the fields and files, each file given
+// as its name and content
+func upload(t *testing.T, path string, token string, fields map[string]string, files map[string]string) response {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	for filename, content := range files {
+		part, err := form.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/v1"+path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if len(token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return send(t, req)
+}
+
+// send runs a request through the app and decodes its response
+func send(t *testing.T, req *http.Request) response {
+	t.Helper()
+
+	method, path := req.Method, req.URL.Path
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	decoded := response{Status: res.StatusCode, Body: map[string]interface{}{}}
+
+	raw, _ := io.ReadAll(res.Body)
+	if len(raw) != 0 {
+		err = json.Unmarshal(raw, &decoded.Body)
+		if err != nil {
+			t.Fatalf("%s %s: body is not JSON: %s", method, path, raw)
+		}
+	}
+	return decoded
+}
+
+// download fetches a signed file url and returns its status and raw body
+func download(t *testing.T, rawURL string) (int, string) {
+	t.Helper()
+
+	res, err := app.Test(httptest.NewRequest("GET", rawURL, nil), -1)
+	if err != nil {
+		t.Fatalf("GET %s: %v", rawURL, err)
+	}
+	defer res.Body.Close()
+
+	raw, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(raw)
+}
+
+// expect fails the test unless the response has the status
+func (r response) expect(t *testing.T, status int) response {
+	t.Helper()
+	if r.Status != status {
+		t.Fatalf("expected status %d, got %d: %v", status, r.Status, r.Body)
+	}
+	return r
+}
+
+// expectError fails the test unless the response is an error with the status and code
+func (r response) expectError(t *testing.T, status int, code string) response {
+	t.Helper()
+	r.expect(t, status)
+	if r.Body["success"] != false || r.Body["code"] != code {
+		t.Fatalf("expected error code %q, got %v", code, r.Body)
+	}
+	return r
+}
+
+// get reads a value at a dotted path such as "data.class_id"
+func (r response) get(path string) interface{} {
+	var value interface{} = r.Body
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// str reads a string at a dotted path, failing when it is not one
+func (r response) str(t *testing.T, path string) string {
+	t.Helper()
+	value, ok := r.get(path).(string)
+	if !ok {
+		t.Fatalf("expected a string at %s, got %v", path, r.Body)
+	}
+	return value
+}
+
+// id reads a numeric id at a dotted path as the string routes take
+func (r response) id(t *testing.T, path string) string {
+	t.Helper()
+	value, ok := r.get(path).(float64)
+	if !ok {
+		t.Fatalf("expected a number at %s, got %v", path, r.Body)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// items reads the list at a dotted path, failing when it is not one
+func (r response) items(t *testing.T, path string) []interface{} {
+	t.Helper()
+	value, ok := r.get(path).([]interface{})
+	if !ok {
+		t.Fatalf("expected a list at %s, got %v", path, r.Body)
+	}
+	return value
+}
+
+/*------------------------------------------------ fixtures ------------------------------------------------------*/
+
+var sequence int64
+
+// unique makes a name no other test uses
+func unique(prefix string) string {
+	return fmt.Sprintf("%s_%d", prefix, atomic.AddInt64(&sequence, 1))
+}
+
+type account struct {
+	ID       string
+	Username string
+	Token    string
+	Refresh  string
+}
+
+// register signs up a new user through the API
+func register(t *testing.T, prefix string) account {
+	t.Helper()
+
+	username := unique(prefix)
+
+	res := call(t, "POST", "/user/create", "", fiber.Map{
+		"username": username,
+		"name":     strings.ToUpper(prefix[:1]) + prefix[1:],
+		"password": "correct horse battery",
+	}).expect(t, 200)
+
+	token := res.str(t, "access_token")
+
+	claims, err := utils.ParseAccessToken([]byte(tokenSecret), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return account{ID: claims.Subject, Username: username, Token: token, Refresh: res.str(t, "refresh_token")}
+}
+
+// classroom is a classroom created through the API by its owner
+type classroom struct {
+	ID       string
+	JoinCode string
+	Owner    account
+}
+
+func createClassroom(t *testing.T, owner account) classroom {
+	t.Helper()
+
+	res := call(t, "POST", "/classroom/create", owner.Token, fiber.Map{
+		"class_name": unique("class"),
+	}).expect(t, 200)
+
+	return classroom{
+		ID:       res.str(t, "classroom.class_id"),
+		JoinCode: res.str(t, "classroom.join_code"),
+		Owner:    owner,
+	}
+}
+
+// join enrols a student with the classroom's join code
+func join(t *testing.T, class classroom, student account) {
+	t.Helper()
+
+	call(t, "POST", "/classroom/join", student.Token, fiber.Map{"join_code": class.JoinCode}).expect(t, 200)
+}
+
+// promote makes a member of the classroom a teacher
+func promote(t *testing.T, class classroom, member account) {
+	t.Helper()
+
+	call(t, "PATCH", "/classroom/"+class.ID+"/members/"+member.ID+"/role", class.Owner.Token, fiber.Map{"role": "teacher"}).expect(t, 200)
+}
+
+// notificationTypes lists the types of a user's notifications, newest first
+func notificationTypes(t *testing.T, user account) []string {
+	t.Helper()
+
+	res := call(t, "GET", "/notifications", user.Token, nil).expect(t, 200)
+
+	types := []string{}
+	for _, item := range res.items(t, "data") {
+		types = append(types, item.(map[string]interface{})["type"].(string))
+	}
+	return types
+}
+
+// createAssignment adds an assignment through the API
+func createAssignment(t *testing.T, class classroom, author account, fields fiber.Map) string {
+	t.Helper()
+
+	body := fiber.Map{"class_id": class.ID, "title": unique("assignment")}
+	for key, value := range fields {
+		body[key] = value
+	}
+
+	return call(t, "POST", "/assignment/create", author.Token, body).expect(t, 200).str(t, "data.id")
+}
@@ -0,0 +1,15 @@
+package integration
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbes(t *testing.T) {
+	requireDB(t)
+
+	send(t, httptest.NewRequest("GET", "/healthz", nil)).expect(t, 200)
+
+	// the suite migrates its database, so it is ready
+	send(t, httptest.NewRequest("GET", "/readyz", nil)).expect(t, 200)
+}
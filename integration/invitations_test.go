@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPersonalInvitation(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	invitee := register(t, "invitee")
+	stranger := register(t, "stranger")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	invitations := "/classroom/" + class.ID + "/invitations"
+
+	call(t, "POST", invitations, student.Token, fiber.Map{"username": invitee.Username}).expectError(t, 403, "forbidden")
+	call(t, "POST", invitations, teacher.Token, fiber.Map{"username": unique("nobody")}).expectError(t, 422, "validation_failed")
+	call(t, "POST", invitations, teacher.Token, fiber.Map{"username": ""}).expectError(t, 422, "validation_failed")
+
+	token := call(t, "POST", invitations, teacher.Token, fiber.Map{"username": invitee.Username}).expect(t, 200).str(t, "data.token")
+
+	// personal invitations do not exist for anyone else
+	call(t, "GET", "/invitations/"+token, stranger.Token, nil).expectError(t, 404, "not_found")
+	call(t, "POST", "/invitations/"+token+"/accept", stranger.Token, nil).expectError(t, 404, "not_found")
+
+	waiting := call(t, "GET", "/invitations", invitee.Token, nil).expect(t, 200)
+
+	if waiting.get("meta.total") != float64(1) {
+		t.Fatalf("expected the invitation to wait on the invitee, got %v", waiting.Body)
+	}
+
+	res := call(t, "POST", "/invitations/"+token+"/accept", invitee.Token, nil).expect(t, 200)
+
+	if res.get("data.role") != "student" {
+		t.Fatalf("expected to join as a student, got %v", res.Body)
+	}
+
+	call(t, "GET", "/classroom/"+class.ID, invitee.Token, nil).expect(t, 200)
+	call(t, "POST", "/invitations/"+token+"/accept", invitee.Token, nil).expectError(t, 410, "gone")
+
+	if total := call(t, "GET", "/invitations", invitee.Token, nil).expect(t, 200).get("meta.total"); total != float64(0) {
+		t.Fatalf("expected no invitations left, got %v", total)
+	}
+
+	// a declined invitation cannot be accepted any more
+	other := register(t, "other")
+	token = call(t, "POST", invitations, teacher.Token, fiber.Map{"username": other.Username}).expect(t, 200).str(t, "data.token")
+
+	call(t, "POST", "/invitations/"+token+"/decline", other.Token, nil).expect(t, 200)
+	call(t, "POST", "/invitations/"+token+"/accept", other.Token, nil).expectError(t, 410, "gone")
+}
+
+func TestInvitationLinks(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	coTeacher := register(t, "coteacher")
+	first := register(t, "first")
+	second := register(t, "second")
+
+	class := createClassroom(t, teacher)
+	join(t, class, coTeacher)
+	promote(t, class, coTeacher)
+
+	invitations := "/classroom/" + class.ID + "/invitations"
+
+	// only the owner brings in teachers
+	call(t, "POST", invitations, coTeacher.Token, fiber.Map{"role": "teacher"}).expectError(t, 403, "forbidden")
+	call(t, "POST", invitations, coTeacher.Token, fiber.Map{"max_uses": 0}).expectError(t, 422, "validation_failed")
+
+	// a classroom closed to join codes still lets invitees in
+	call(t, "PATCH", "/classroom/"+class.ID+"/join-mode", teacher.Token, fiber.Map{"join_mode": "invite_only"}).expect(t, 200)
+	call(t, "POST", "/classroom/join", first.Token, fiber.Map{"join_code": class.JoinCode}).expectError(t, 403, "forbidden")
+
+	link := call(t, "POST", invitations, teacher.Token, fiber.Map{"role": "teacher", "max_uses": 1}).expect(t, 200)
+	token := link.str(t, "data.token")
+
+	call(t, "POST", "/invitations/"+token+"/accept", teacher.Token, nil).expectError(t, 409, "conflict")
+
+	res := call(t, "POST", "/invitations/"+token+"/accept", first.Token, nil).expect(t, 200)
+
+	if res.get("data.role") != "teacher" {
+		t.Fatalf("expected to join as a teacher, got %v", res.Body)
+	}
+
+	// the one use is spent
+	call(t, "POST", "/invitations/"+token+"/accept", second.Token, nil).expectError(t, 410, "gone")
+
+	// links cannot be declined, and revoked ones stop working
+	token = call(t, "POST", invitations, coTeacher.Token, fiber.Map{}).expect(t, 200).str(t, "data.token")
+	invitationId := call(t, "GET", "/invitations/"+token, second.Token, nil).expect(t, 200).str(t, "data.id")
+
+	call(t, "POST", "/invitations/"+token+"/decline", second.Token, nil).expectError(t, 409, "conflict")
+
+	listed := call(t, "GET", invitations, coTeacher.Token, nil).expect(t, 200)
+	if listed.get("meta.total") != float64(2) {
+		t.Fatalf("expected both invitations that are not revoked, got %v", listed.Body)
+	}
+
+	call(t, "DELETE", invitations+"/"+invitationId, coTeacher.Token, nil).expect(t, 200)
+	call(t, "POST", "/invitations/"+token+"/accept", second.Token, nil).expectError(t, 410, "gone")
+}
@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	coTeacher := register(t, "coteacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, coTeacher)
+	join(t, class, student)
+	promote(t, class, coTeacher)
+
+	// only the owner throws a classroom away
+	call(t, "DELETE", "/classroom/"+class.ID, student.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "DELETE", "/classroom/"+class.ID, coTeacher.Token, nil).expectError(t, 403, "forbidden")
+
+	call(t, "DELETE", "/classroom/"+class.ID, teacher.Token, nil).expect(t, 200)
+
+	call(t, "GET", "/classroom/"+class.ID, teacher.Token, nil).expectError(t, 404, "not_found")
+	call(t, "GET", "/classroom/"+class.ID, student.Token, nil).expectError(t, 404, "not_found")
+
+	trash := call(t, "GET", "/classrooms/trash", teacher.Token, nil).expect(t, 200)
+
+	if trash.get("meta.total") != float64(1) {
+		t.Fatalf("expected the classroom in the trash, got %v", trash.Body)
+	}
+
+	if total := call(t, "GET", "/classrooms/trash", coTeacher.Token, nil).expect(t, 200).get("meta.total"); total != float64(0) {
+		t.Fatalf("expected the trash to be the owner's alone, got %v", total)
+	}
+
+	// only the owner knows a trashed classroom exists
+	call(t, "POST", "/classroom/"+class.ID+"/restore", coTeacher.Token, nil).expectError(t, 404, "not_found")
+	call(t, "POST", "/classroom/"+class.ID+"/restore", teacher.Token, nil).expect(t, 200)
+
+	call(t, "GET", "/classroom/"+class.ID, student.Token, nil).expect(t, 200)
+	call(t, "POST", "/classroom/"+class.ID+"/restore", teacher.Token, nil).expectError(t, 404, "not_found")
+}
+
+func TestPurgeTrash(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	kept := createClassroom(t, teacher)
+	join(t, class, student)
+
+	// the role change leaves a notification about the classroom
+	promote(t, class, student)
+	call(t, "PATCH", "/classroom/"+class.ID+"/members/"+student.ID+"/role", teacher.Token, fiber.Map{"role": "student"}).expect(t, 200)
+
+	id := createAssignment(t, class, teacher, nil)
+	upload(t, "/assignment/"+id+"/submission/attachments", student.Token, nil, map[string]string{"essay.txt": "my essay"}).expect(t, 200)
+
+	deleted := call(t, "DELETE", "/classroom/"+class.ID, teacher.Token, nil).expect(t, 200)
+
+	purgeAt, err := time.Parse(time.RFC3339Nano, deleted.str(t, "purge_at"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is purged before the retention period is over
+	_, err = repo.PurgeTrash(context.Background(), purgeAt.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call(t, "POST", "/classroom/"+class.ID+"/restore", teacher.Token, nil).expect(t, 200)
+	call(t, "DELETE", "/classroom/"+class.ID, teacher.Token, nil).expect(t, 200)
+
+	purged, err := repo.PurgeTrash(context.Background(), purgeAt.Add(time.Hour))
+	if err != nil || purged == 0 {
+		t.Fatalf("expected the classroom to be purged, got %d, %v", purged, err)
+	}
+
+	call(t, "POST", "/classroom/"+class.ID+"/restore", teacher.Token, nil).expectError(t, 404, "not_found")
+
+	for _, table := range []string{"attachments", "notifications"} {
+		var rows int64
+		db.Table(table).Where("class_id = ?", class.ID).Count(&rows)
+		if rows != 0 {
+			t.Fatalf("expected the classroom's %s to be purged, %d left", table, rows)
+		}
+	}
+
+	if types := notificationTypes(t, student); len(types) != 0 {
+		t.Fatalf("expected the student's notifications about the classroom to be gone, got %v", types)
+	}
+
+	// live classrooms are left alone
+	call(t, "GET", "/classroom/"+kept.ID, teacher.Token, nil).expect(t, 200)
+
+	if total := call(t, "GET", "/classrooms/trash", teacher.Token, nil).expect(t, 200).get("meta.total"); total != float64(0) {
+		t.Fatalf("expected an empty trash, got %v", total)
+	}
+
+	call(t, "POST", "/assignment/"+id+"/turn-in", student.Token, fiber.Map{}).expectError(t, 404, "not_found")
+}
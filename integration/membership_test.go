@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestJoinRequests(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	first := register(t, "first")
+	second := register(t, "second")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	call(t, "PATCH", "/classroom/"+class.ID+"/join-mode", student.Token, fiber.Map{"join_mode": "approval"}).expectError(t, 403, "forbidden")
+	call(t, "PATCH", "/classroom/"+class.ID+"/join-mode", teacher.Token, fiber.Map{"join_mode": "sometimes"}).expectError(t, 422, "validation_failed")
+	call(t, "PATCH", "/classroom/"+class.ID+"/join-mode", teacher.Token, fiber.Map{"join_mode": "approval"}).expect(t, 200)
+
+	for _, user := range []account{first, second} {
+		res := call(t, "POST", "/classroom/join", user.Token, fiber.Map{"join_code": class.JoinCode}).expect(t, 202)
+
+		if res.get("data.status") != "pending" {
+			t.Fatalf("expected a pending request, got %v", res.Body)
+		}
+	}
+
+	// a pending request is not a membership
+	call(t, "GET", "/classroom/"+class.ID, first.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "POST", "/classroom/join", first.Token, fiber.Map{"join_code": class.JoinCode}).expectError(t, 409, "conflict")
+
+	requests := "/classroom/" + class.ID + "/join-requests"
+
+	call(t, "GET", requests, student.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "POST", requests+"/approve", student.Token, fiber.Map{"all": true}).expectError(t, 403, "forbidden")
+	call(t, "POST", requests+"/approve", teacher.Token, fiber.Map{}).expectError(t, 422, "validation_failed")
+
+	listed := call(t, "GET", requests+"?limit=1", teacher.Token, nil).expect(t, 200)
+
+	if listed.get("meta.total") != float64(2) || listed.get("meta.has_more") != true {
+		t.Fatalf("expected a page of the two requests, got %v", listed.Body)
+	}
+
+	approved := call(t, "POST", requests+"/approve", teacher.Token, fiber.Map{"user_ids": []string{first.ID}}).expect(t, 200)
+
+	if approved.get("updated") != float64(1) {
+		t.Fatalf("expected one request approved, got %v", approved.Body)
+	}
+
+	call(t, "GET", "/classroom/"+class.ID, first.Token, nil).expect(t, 200)
+
+	call(t, "POST", requests+"/reject", teacher.Token, fiber.Map{"all": true}).expect(t, 200)
+	call(t, "GET", "/classroom/"+class.ID, second.Token, nil).expectError(t, 403, "forbidden")
+
+	if total := call(t, "GET", requests, teacher.Token, nil).expect(t, 200).get("meta.total"); total != float64(0) {
+		t.Fatalf("expected no requests left, got %v", total)
+	}
+
+	if types := notificationTypes(t, first); len(types) != 1 || types[0] != "join_request_approved" {
+		t.Fatalf("expected the approval to be notified, got %v", types)
+	}
+	if types := notificationTypes(t, second); len(types) != 1 || types[0] != "join_request_rejected" {
+		t.Fatalf("expected the rejection to be notified, got %v", types)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	requireDB(t)
+
+	owner := register(t, "owner")
+	coTeacher := register(t, "coteacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, owner)
+	join(t, class, coTeacher)
+	join(t, class, student)
+
+	transfer := "/classroom/" + class.ID + "/transfer"
+
+	// ownership only goes to a teacher of the classroom
+	call(t, "POST", transfer, owner.Token, fiber.Map{"user_id": student.ID}).expectError(t, 422, "validation_failed")
+
+	promote(t, class, coTeacher)
+
+	call(t, "POST", transfer, coTeacher.Token, fiber.Map{"user_id": coTeacher.ID}).expectError(t, 403, "forbidden")
+	call(t, "POST", transfer, owner.Token, fiber.Map{"user_id": owner.ID}).expectError(t, 409, "conflict")
+
+	res := call(t, "POST", transfer, owner.Token, fiber.Map{"user_id": coTeacher.ID}).expect(t, 200)
+
+	if res.get("data.owner_id") != coTeacher.ID {
+		t.Fatalf("expected the co-teacher to own the classroom, got %v", res.Body)
+	}
+
+	if role := call(t, "GET", "/classroom/"+class.ID, coTeacher.Token, nil).expect(t, 200).get("role"); role != "owner" {
+		t.Fatalf("expected the new owner to be owner, got %v", role)
+	}
+	if role := call(t, "GET", "/classroom/"+class.ID, owner.Token, nil).expect(t, 200).get("role"); role != "teacher" {
+		t.Fatalf("expected the old owner to stay on as a teacher, got %v", role)
+	}
+
+	// the old owner lost what only owners may do
+	call(t, "POST", transfer, owner.Token, fiber.Map{"user_id": owner.ID}).expectError(t, 403, "forbidden")
+	call(t, "PATCH", "/classroom/edit/"+class.ID, owner.Token, fiber.Map{"class_name": "renamed"}).expectError(t, 403, "forbidden")
+
+	if types := notificationTypes(t, coTeacher); len(types) == 0 || types[0] != "ownership_transferred" {
+		t.Fatalf("expected the new owner to be notified, got %v", types)
+	}
+}
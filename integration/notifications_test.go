@@ -0,0 +1,39 @@
+package integration
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMarkNotificationRead(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	// the role change leaves the student a notification
+	promote(t, class, student)
+
+	listed := call(t, "GET", "/notifications", student.Token, nil).expect(t, 200)
+
+	items := listed.items(t, "data")
+	if len(items) == 0 || listed.get("unread") != float64(len(items)) {
+		t.Fatalf("expected unread notifications, got %v", listed.Body)
+	}
+	read := "/notifications/" + strconv.FormatFloat(items[0].(map[string]interface{})["id"].(float64), 'f', -1, 64) + "/read"
+
+	// someone else's notification is as good as missing
+	call(t, "POST", read, teacher.Token, nil).expectError(t, 404, "not_found")
+	call(t, "POST", "/notifications/999999999/read", student.Token, nil).expectError(t, 404, "not_found")
+	call(t, "POST", "/notifications/nope/read", student.Token, nil).expectError(t, 404, "not_found")
+
+	call(t, "POST", read, student.Token, nil).expect(t, 200)
+	call(t, "POST", read, student.Token, nil).expect(t, 200)
+
+	if unread := call(t, "GET", "/notifications", student.Token, nil).expect(t, 200).get("unread"); unread != float64(len(items)-1) {
+		t.Fatalf("expected one notification fewer unread, got %v", unread)
+	}
+}
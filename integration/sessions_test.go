@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// login opens another session for an account
+func login(t *testing.T, user account) account {
+	t.Helper()
+
+	res := call(t, "POST", "/user/login", "", fiber.Map{
+		"username": user.Username,
+		"password": "correct horse battery",
+	}).expect(t, 200)
+
+	user.Token = res.str(t, "access_token")
+	user.Refresh = res.str(t, "refresh_token")
+	return user
+}
+
+func TestRefreshRotation(t *testing.T) {
+	requireDB(t)
+
+	user := register(t, "frank")
+
+	call(t, "POST", "/user/token/refresh", "", fiber.Map{}).expectError(t, 422, "validation_failed")
+	call(t, "POST", "/user/token/refresh", "", fiber.Map{"refresh_token": "made up"}).expectError(t, 401, "unauthenticated")
+
+	res := call(t, "POST", "/user/token/refresh", "", fiber.Map{"refresh_token": user.Refresh}).expect(t, 200)
+
+	rotated := res.str(t, "refresh_token")
+	if rotated == user.Refresh {
+		t.Fatal("expected the refresh token to be rotated")
+	}
+
+	call(t, "POST", "/user/"+user.ID, res.str(t, "access_token"), nil).expect(t, 200)
+
+	// replaying the old token means it leaked, so the whole session dies
+	call(t, "POST", "/user/token/refresh", "", fiber.Map{"refresh_token": user.Refresh}).expectError(t, 401, "unauthenticated")
+	call(t, "POST", "/user/token/refresh", "", fiber.Map{"refresh_token": rotated}).expectError(t, 401, "unauthenticated")
+	call(t, "POST", "/user/"+user.ID, res.str(t, "access_token"), nil).expectError(t, 401, "unauthenticated")
+
+	// other sessions are untouched
+	other := login(t, user)
+	call(t, "POST", "/user/token/refresh", "", fiber.Map{"refresh_token": other.Refresh}).expect(t, 200)
+}
+
+func TestSessions(t *testing.T) {
+	requireDB(t)
+
+	user := register(t, "grace")
+	stranger := register(t, "heidi")
+	second := login(t, user)
+
+	listed := call(t, "GET", "/user/sessions", user.Token, nil).expect(t, 200)
+
+	if listed.get("meta.total") != float64(2) {
+		t.Fatalf("expected two sessions, got %v", listed.Body)
+	}
+
+	// the session of the second login is the other one
+	var secondId string
+	for _, item := range listed.items(t, "data") {
+		if id := item.(map[string]interface{})["id"].(string); id != listed.get("current") {
+			secondId = id
+		}
+	}
+
+	call(t, "DELETE", "/user/sessions/"+secondId, stranger.Token, nil).expectError(t, 404, "not_found")
+	call(t, "DELETE", "/user/sessions/"+secondId, user.Token, nil).expect(t, 200)
+
+	call(t, "GET", "/user/sessions", second.Token, nil).expectError(t, 401, "unauthenticated")
+	call(t, "POST", "/user/token/refresh", "", fiber.Map{"refresh_token": second.Refresh}).expectError(t, 401, "unauthenticated")
+
+	// logging out everywhere ends every session, including the current one
+	third := login(t, user)
+
+	call(t, "POST", "/user/logout/all", third.Token, nil).expect(t, 200)
+
+	call(t, "GET", "/user/sessions", user.Token, nil).expectError(t, 401, "unauthenticated")
+	call(t, "GET", "/user/sessions", third.Token, nil).expectError(t, 401, "unauthenticated")
+	call(t, "POST", "/user/token/refresh", "", fiber.Map{"refresh_token": user.Refresh}).expectError(t, 401, "unauthenticated")
+
+	call(t, "GET", "/user/sessions", stranger.Token, nil).expect(t, 200)
+}
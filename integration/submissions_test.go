@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTurnInAndReturn(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+	classmate := register(t, "classmate")
+	outsider := register(t, "outsider")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+	join(t, class, classmate)
+
+	id := createAssignment(t, class, teacher, fiber.Map{"points": 10})
+	assignment := "/assignment/" + id
+
+	call(t, "POST", assignment+"/turn-in", teacher.Token, fiber.Map{}).expectError(t, 403, "forbidden")
+	call(t, "POST", assignment+"/turn-in", outsider.Token, fiber.Map{}).expectError(t, 403, "forbidden")
+	call(t, "POST", assignment+"/turn-in", student.Token, fiber.Map{"link": "not a url"}).expectError(t, 422, "validation_failed")
+
+	mine := call(t, "GET", assignment+"/submission", student.Token, nil).expect(t, 200)
+
+	if mine.get("state") != "assigned" || mine.get("data") != nil {
+		t.Fatalf("expected nothing handed in yet, got %v", mine.Body)
+	}
+
+	res := call(t, "POST", assignment+"/turn-in", student.Token, fiber.Map{"link": "https://example.com/essay"}).expect(t, 200)
+
+	if res.get("data.state") != "turned_in" {
+		t.Fatalf("expected the work to be turned in, got %v", res.Body)
+	}
+
+	call(t, "POST", assignment+"/turn-in", student.Token, fiber.Map{}).expectError(t, 409, "conflict")
+
+	// only teachers see everyone's work
+	call(t, "GET", assignment+"/submissions", student.Token, nil).expectError(t, 403, "forbidden")
+
+	list := call(t, "GET", assignment+"/submissions?limit=1", teacher.Token, nil).expect(t, 200)
+
+	if list.get("summary.turned_in") != float64(1) || list.get("summary.assigned") != float64(1) || list.get("meta.total") != float64(2) || len(list.items(t, "data")) != 1 {
+		t.Fatalf("expected a page of one with a summary of both students, got %v", list.Body)
+	}
+
+	// work can be taken back and handed in again
+	call(t, "POST", assignment+"/unsubmit", student.Token, nil).expect(t, 200)
+	call(t, "POST", assignment+"/unsubmit", student.Token, nil).expectError(t, 409, "conflict")
+	call(t, "POST", assignment+"/turn-in", student.Token, fiber.Map{"note": "second try"}).expect(t, 200)
+
+	call(t, "POST", assignment+"/submissions/"+student.ID+"/return", student.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "POST", assignment+"/submissions/"+outsider.ID+"/return", teacher.Token, nil).expectError(t, 404, "not_found")
+
+	call(t, "POST", assignment+"/submissions/"+student.ID+"/return", teacher.Token, nil).expect(t, 200)
+
+	if state := call(t, "GET", assignment+"/submission", student.Token, nil).expect(t, 200).get("state"); state != "returned" {
+		t.Fatalf("expected the work to be returned, got %v", state)
+	}
+
+	// returned work is no longer handed in
+	call(t, "POST", assignment+"/unsubmit", student.Token, nil).expectError(t, 409, "conflict")
+	call(t, "POST", assignment+"/submissions/"+student.ID+"/return", teacher.Token, nil).expectError(t, 409, "conflict")
+}
+
+func TestGrading(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	id := createAssignment(t, class, teacher, fiber.Map{"points": 10})
+	assignment := "/assignment/" + id
+
+	rubric := call(t, "PUT", assignment+"/rubric", teacher.Token, fiber.Map{
+		"criteria": []fiber.Map{{"title": "argument", "points": 6}, {"title": "style", "points": 4}},
+	}).expect(t, 200)
+
+	criteria := rubric.items(t, "data")
+	if len(criteria) != 2 {
+		t.Fatalf("expected two criteria, got %v", rubric.Body)
+	}
+	argument := criteria[0].(map[string]interface{})["id"]
+	style := criteria[1].(map[string]interface{})["id"]
+
+	call(t, "PUT", assignment+"/rubric", student.Token, fiber.Map{"criteria": []fiber.Map{}}).expectError(t, 403, "forbidden")
+
+	call(t, "POST", assignment+"/turn-in", student.Token, fiber.Map{}).expect(t, 200)
+
+	grade := assignment + "/submissions/" + student.ID + "/grade"
+
+	call(t, "PUT", grade, student.Token, fiber.Map{"grade": 10}).expectError(t, 403, "forbidden")
+	call(t, "PUT", grade, teacher.Token, fiber.Map{"rubric_scores": []fiber.Map{{"criterion_id": argument, "points": 7}}}).expectError(t, 422, "validation_failed")
+
+	twice := call(t, "PUT", grade, teacher.Token, fiber.Map{
+		"rubric_scores": []fiber.Map{{"criterion_id": argument, "points": 2}, {"criterion_id": argument, "points": 3}},
+	}).expectError(t, 422, "validation_failed")
+
+	if twice.get("fields.rubric_scores") == nil {
+		t.Fatalf("expected a criterion scored twice to be refused, got %v", twice.Body)
+	}
+
+	res := call(t, "PUT", grade, teacher.Token, fiber.Map{
+		"feedback":      "good work",
+		"rubric_scores": []fiber.Map{{"criterion_id": argument, "points": 5}, {"criterion_id": style, "points": 3}},
+	}).expect(t, 200)
+
+	if res.get("data.grade") != float64(8) {
+		t.Fatalf("expected the rubric total as the grade, got %v", res.Body)
+	}
+
+	// the grade stays with the teacher until the work is returned
+	mine := call(t, "GET", assignment+"/submission", student.Token, nil).expect(t, 200)
+
+	if mine.get("data.grade") != nil || mine.get("data.feedback") != nil {
+		t.Fatalf("expected no grade before the work is returned, got %v", mine.Body)
+	}
+
+	if value := call(t, "GET", "/classroom/"+class.ID+"/grades", student.Token, nil).expect(t, 200).get("data.grades." + id); value != nil {
+		t.Fatalf("expected no grade in the student's gradebook yet, got %v", value)
+	}
+
+	call(t, "GET", "/classroom/"+class.ID+"/gradebook", student.Token, nil).expectError(t, 403, "forbidden")
+	call(t, "GET", "/classroom/"+class.ID+"/grades", teacher.Token, nil).expectError(t, 403, "forbidden")
+
+	book := call(t, "GET", "/classroom/"+class.ID+"/gradebook", teacher.Token, nil).expect(t, 200)
+
+	rows := book.items(t, "data")
+	if len(rows) != 1 || rows[0].(map[string]interface{})["grades"].(map[string]interface{})[id] != float64(8) || book.get("meta.total") != float64(1) {
+		t.Fatalf("expected the grade in the teacher's gradebook, got %v", book.Body)
+	}
+
+	call(t, "POST", assignment+"/submissions/"+student.ID+"/return", teacher.Token, nil).expect(t, 200)
+
+	mine = call(t, "GET", assignment+"/submission", student.Token, nil).expect(t, 200)
+
+	if mine.get("data.grade") != float64(8) || mine.get("data.feedback") != "good work" {
+		t.Fatalf("expected the returned grade, got %v", mine.Body)
+	}
+
+	grades := call(t, "GET", "/classroom/"+class.ID+"/grades", student.Token, nil).expect(t, 200)
+
+	if grades.get("data.grades."+id) != float64(8) || grades.get("data.average") != float64(80) {
+		t.Fatalf("expected the grade and an average of 80, got %v", grades.Body)
+	}
+}
+
+func TestGradeCategories(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	student := register(t, "student")
+
+	class := createClassroom(t, teacher)
+	join(t, class, student)
+
+	categories := "/classroom/" + class.ID + "/grade-categories"
+
+	call(t, "POST", categories, student.Token, fiber.Map{"name": "homework", "weight": 40}).expectError(t, 403, "forbidden")
+	call(t, "POST", categories, teacher.Token, fiber.Map{"weight": 40}).expectError(t, 422, "validation_failed")
+
+	call(t, "POST", categories, teacher.Token, fiber.Map{"name": "homework", "weight": 40}).expect(t, 200)
+	call(t, "POST", categories, teacher.Token, fiber.Map{"name": "exams", "weight": 60}).expect(t, 200)
+
+	// weights cannot add up to more than 100
+	call(t, "POST", categories, teacher.Token, fiber.Map{"name": "extra", "weight": 1}).expectError(t, 422, "validation_failed")
+
+	list := call(t, "GET", categories+"?sort=name", student.Token, nil).expect(t, 200)
+
+	items := list.items(t, "data")
+	if list.get("meta.total") != float64(2) || items[0].(map[string]interface{})["name"] != "exams" {
+		t.Fatalf("expected both categories by name, got %v", list.Body)
+	}
+}
+
+func TestGradebookPages(t *testing.T) {
+	requireDB(t)
+
+	teacher := register(t, "teacher")
+	class := createClassroom(t, teacher)
+
+	for _, prefix := range []string{"anna", "bert", "cleo"} {
+		join(t, class, register(t, prefix))
+	}
+
+	gradebook := "/classroom/" + class.ID + "/gradebook"
+
+	first := call(t, "GET", gradebook+"?limit=2&sort=username", teacher.Token, nil).expect(t, 200)
+
+	rows := first.items(t, "data")
+	if len(rows) != 2 || first.get("meta.total") != float64(3) || first.get("meta.has_more") != true {
+		t.Fatalf("expected the first two of three students, got %v", first.Body)
+	}
+
+	second := call(t, "GET", gradebook+"?limit=2&sort=username&page=2", teacher.Token, nil).expect(t, 200)
+
+	if len(second.items(t, "data")) != 1 || second.get("meta.has_more") != false {
+		t.Fatalf("expected the last student on the second page, got %v", second.Body)
+	}
+
+	call(t, "GET", gradebook+"?sort=grade", teacher.Token, nil).expectError(t, 400, "bad_request")
+}
@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+func TestRegister(t *testing.T) {
+	requireDB(t)
+
+	user := register(t, "alice")
+
+	// usernames are unique regardless of case
+	call(t, "POST", "/user/create", "", fiber.Map{
+		"username": strings.ToUpper(user.Username),
+		"password": "another password",
+	}).expectError(t, 409, "conflict")
+
+	res := call(t, "POST", "/user/create", "", fiber.Map{
+		"username": "no spaces allowed",
+		"password": "short",
+	}).expectError(t, 422, "validation_failed")
+
+	if res.get("fields.username") == nil || res.get("fields.password") == nil {
+		t.Fatalf("expected field errors for username and password, got %v", res.Body)
+	}
+
+	call(t, "POST", "/user/create", "", "not an object").expectError(t, 400, "bad_request")
+}
+
+func TestLogin(t *testing.T) {
+	requireDB(t)
+
+	user := register(t, "bob")
+
+	res := call(t, "POST", "/user/login", "", fiber.Map{
+		"username": strings.ToUpper(user.Username),
+		"password": "correct horse battery",
+	}).expect(t, 200)
+
+	if res.str(t, "username") != user.Username {
+		t.Fatalf("logged in as %v, want %s", res.get("username"), user.Username)
+	}
+
+	call(t, "POST", "/user/login", "", fiber.Map{
+		"username": user.Username,
+		"password": "wrong password",
+	}).expectError(t, 401, "unauthenticated")
+
+	call(t, "POST", "/user/login", "", fiber.Map{
+		"username": unique("nobody"),
+		"password": "correct horse battery",
+	}).expectError(t, 401, "unauthenticated")
+}
+
+func TestUserData(t *testing.T) {
+	requireDB(t)
+
+	user := register(t, "carol")
+	other := register(t, "dave")
+
+	call(t, "POST", "/user/"+user.ID, "", nil).expectError(t, 401, "unauthenticated")
+	call(t, "POST", "/user/"+user.ID, "not a token", nil).expectError(t, 401, "unauthenticated")
+
+	own := call(t, "POST", "/user/"+user.ID, user.Token, nil).expect(t, 200)
+	public := call(t, "POST", "/user/"+user.ID, other.Token, nil).expect(t, 200)
+
+	if own.str(t, "data.username") != user.Username || public.str(t, "data.username") != user.Username {
+		t.Fatalf("expected %s in both views, got %v and %v", user.Username, own.Body, public.Body)
+	}
+
+	call(t, "POST", "/user/"+unique("missing"), user.Token, nil).expectError(t, 404, "not_found")
+}
+
+func TestLogout(t *testing.T) {
+	requireDB(t)
+
+	user := register(t, "erin")
+
+	call(t, "POST", "/user/logout", user.Token, nil).expect(t, 200)
+
+	// the access token dies with its session
+	call(t, "POST", "/user/"+user.ID, user.Token, nil).expectError(t, 401, "unauthenticated")
+}
+
+func TestMigratePlaintextPasswords(t *testing.T) {
+	requireDB(t)
+
+	username, password := unique("legacy"), "plain password"
+
+	err := db.Create(&models.Users{Uuid: &username, Username: &username, Password: &password}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := func() string {
+		user := models.Users{}
+		if err := db.Where("uuid = ?", username).First(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		return *user.Password
+	}
+
+	if err = models.MigratePlaintextPasswords(db); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := stored()
+	if ok, rehash := utils.CheckPassword(hash, password); !ok || rehash {
+		t.Fatalf("expected a current hash of the password, got %q", hash)
+	}
+
+	// running it again leaves the hash alone
+	if err = models.MigratePlaintextPasswords(db); err != nil {
+		t.Fatal(err)
+	}
+	if stored() != hash {
+		t.Fatal("expected a second run to keep the hash")
+	}
+
+	call(t, "POST", "/user/login", "", fiber.Map{
+		"username": username,
+		"password": password,
+	}).expect(t, 200)
+}
@@ -1,52 +1,145 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
+	"github.com/swayanshu-2003/classroom-backend/config"
 	"github.com/swayanshu-2003/classroom-backend/middlewares"
-	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
 	"github.com/swayanshu-2003/classroom-backend/storage"
+	"github.com/swayanshu-2003/classroom-backend/utils"
 )
 
 func main() {
+	migrate := len(os.Args) > 1 && os.Args[1] == "migrate"
+
+	if migrate && len(os.Args) > 2 && os.Args[2] == "create" {
+		err := runMigrateCreate(os.Args[3:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	fmt.Println("welcome to classroom backend")
 
-	err := godotenv.Load(".env")
+	settings, err := config.Load()
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	db, err := storage.NewConnection(settings.Storage())
+
+	if err != nil {
+		log.Fatal("could not load the database")
 	}
 
-	config := &storage.Config{
-		Host:     os.Getenv("DB_HOST"),
-		Password: os.Getenv("DB_PASSWORD"),
-		Username: os.Getenv("DB_USER"),
-		DBName:   os.Getenv("DB_DATABASE"),
+	if migrate {
+		err = runMigrate(db, os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	db, err := storage.NewConnection(config)
+	err = checkSchema(db)
 
 	if err != nil {
-		log.Fatal("could not load the database")
+		log.Fatal(err)
+	}
+
+	err = settings.RequireSecret()
+
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	err = models.MigrateUser(db)
+	blobs, err := newBlobStore(settings)
 
 	if err != nil {
-		log.Fatal("could not migrate db")
+		log.Fatalf("could not open blob storage: %v", err)
 	}
 
+	avatars := newAvatarProvider(settings)
+
 	r := middlewares.Repository{
-		DB: db,
+		DB:          db,
+		TokenSecret: []byte(settings.JWTSecret),
+		Blobs:       blobs,
+		Avatars:     avatars,
+		Services:    services.New(gormstore.New(db), avatars),
 	}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		BodyLimit:    settings.HTTP.BodyLimit,
+		ReadTimeout:  settings.HTTP.ReadTimeout.Duration,
+		WriteTimeout: settings.HTTP.WriteTimeout.Duration,
+		IdleTimeout:  settings.HTTP.IdleTimeout.Duration,
+		ErrorHandler: middlewares.ErrorHandler,
+	})
 
 	r.SetupRoutes(app)
 
-	log.Fatal(app.Listen(":5600"))
+	// SIGTERM on deploy, ctrl-c when run by hand
+	stop, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer cancel()
+
+	go r.PurgeTrashEvery(stop, time.Hour)
+
+	drained := make(chan struct{})
+
+	go func() {
+		defer close(drained)
+
+		<-stop.Done()
+		log.Printf("shutting down, waiting up to %s for requests in flight", settings.HTTP.ShutdownTimeout.Duration)
+
+		err := app.ShutdownWithTimeout(settings.HTTP.ShutdownTimeout.Duration)
+		if err != nil {
+			log.Printf("could not drain every request: %v", err)
+		}
+	}()
+
+	err = app.Listen(fmt.Sprintf(":%d", settings.Port))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Listen returns once it stops accepting, before the requests in flight are done
+	<-drained
+
+	err = storage.Close(db)
+
+	if err != nil {
+		log.Printf("could not close the database: %v", err)
+	}
+
+	log.Println("shut down")
+}
+
+// newAvatarProvider generates initials avatars offline unless the remote provider is configured
+func newAvatarProvider(settings *config.Config) utils.AvatarProvider {
+	if settings.AvatarProvider == "remote" {
+		return utils.NewRemoteAvatar(3 * time.Second)
+	}
+	return utils.InitialsAvatar{}
+}
+
+// newBlobStore picks the attachment storage, local disk by default
+func newBlobStore(settings *config.Config) (storage.BlobStore, error) {
+	if settings.Blob.Store == "s3" {
+		return storage.NewS3BlobStore(context.Background(), settings.Blob.S3)
+	}
 
+	return storage.NewLocalBlobStore(settings.Blob.Dir, settings.PublicURL, []byte(settings.JWTSecret))
 }
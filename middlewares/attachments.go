@@ -0,0 +1,730 @@
+package middlewares
+
+import (
+	"bytes"
+	stdcontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/storage"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	maxUploadSize  = 25 << 20
+	maxUploadFiles = 10
+	downloadURLTTL = 15 * time.Minute
+)
+
+// uploadType is what an accepted file extension is served as, and what its
+// first bytes may sniff as so a renamed executable is not let through
+type uploadType struct {
+	ContentType string
+	Sniffed     []string
+	// Magic is the prefix the content must start with, for formats the
+	// sniffer cannot tell apart from any other binary
+	Magic []byte
+}
+
+// legacy office documents are OLE2 compound files
+var ole2Magic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+var allowedUploads = map[string]uploadType{
+	".pdf":  {"application/pdf", []string{"application/pdf"}, nil},
+	".png":  {"image/png", []string{"image/png"}, nil},
+	".jpg":  {"image/jpeg", []string{"image/jpeg"}, nil},
+	".jpeg": {"image/jpeg", []string{"image/jpeg"}, nil},
+	".gif":  {"image/gif", []string{"image/gif"}, nil},
+	".webp": {"image/webp", []string{"image/webp"}, nil},
+	".txt":  {"text/plain", []string{"text/plain"}, nil},
+	".md":   {"text/markdown", []string{"text/plain"}, nil},
+	".csv":  {"text/csv", []string{"text/plain"}, nil},
+	".zip":  {"application/zip", []string{"application/zip"}, nil},
+	".doc":  {"application/msword", []string{"application/octet-stream"}, ole2Magic},
+	".xls":  {"application/vnd.ms-excel", []string{"application/octet-stream"}, ole2Magic},
+	".ppt":  {"application/vnd.ms-powerpoint", []string{"application/octet-stream"}, ole2Magic},
+	".docx": {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", []string{"application/zip"}, nil},
+	".xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", []string{"application/zip"}, nil},
+	".pptx": {"application/vnd.openxmlformats-officedocument.presentationml.presentation", []string{"application/zip"}, nil},
+}
+
+// blobKey is where content with the given sha256 is stored
+func blobKey(hash string) string {
+	return "sha256/" + hash[:2] + "/" + hash
+}
+
+// sanitizeFilename keeps the base name of an upload without quotes or control characters
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	name = strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) || c == '"' || c == '/' {
+			return -1
+		}
+		return c
+	}, name)
+
+	if len(name) == 0 || name == "." {
+		return "file"
+	}
+	return name
+}
+
+// hash and type-check one uploaded file; its content is stored later by storeBlobs
+func inspectUpload(header *multipart.FileHeader) (*models.Attachment, error) {
+	filename := sanitizeFilename(header.Filename)
+
+	if header.Size <= 0 {
+		return nil, apperr.Validation(filename + " is empty")
+	}
+	if header.Size > maxUploadSize {
+		return nil, apperr.TooLarge(filename + " is larger than 25MB")
+	}
+
+	kind, ok := allowedUploads[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return nil, apperr.UnsupportedMedia(filename + " is not an allowed file type")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	sniffed := strings.TrimSpace(strings.Split(http.DetectContentType(head), ";")[0])
+	matches := false
+	for _, allowed := range kind.Sniffed {
+		if sniffed == allowed {
+			matches = true
+		}
+	}
+	if kind.Magic != nil && !bytes.HasPrefix(head, kind.Magic) {
+		matches = false
+	}
+	if !matches {
+		return nil, apperr.UnsupportedMedia(filename + " does not look like a " + filepath.Ext(filename) + " file")
+	}
+
+	hasher := sha256.New()
+	hasher.Write(head)
+	if _, err := io.Copy(hasher, file); err != nil {
+		return nil, err
+	}
+
+	id, _ := utils.GenerateUUid()
+
+	return &models.Attachment{
+		ID:          &id,
+		Filename:    filename,
+		ContentType: kind.ContentType,
+		Size:        header.Size,
+		Hash:        hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+// inspect every uploaded file, returning attachments that still need an
+// owner, their content and a row
+func (r *Repository) inspectUploads(context *fiber.Ctx, headers []*multipart.FileHeader) ([]models.Attachment, error) {
+	_, user := r.IsAuthUser(context)
+
+	if len(headers) > maxUploadFiles {
+		return nil, apperr.Validation("at most 10 files can be uploaded at once")
+	}
+
+	attachments := make([]models.Attachment, 0, len(headers))
+
+	for _, header := range headers {
+		attachment, err := inspectUpload(header)
+
+		if rejected := apperr.As(err); rejected != nil {
+			return nil, rejected
+		}
+
+		if err != nil {
+			return nil, apperr.Internal(err, "could not read upload")
+		}
+
+		attachment.UploadedBy = user.Uuid
+
+		attachments = append(attachments, *attachment)
+	}
+
+	return attachments, nil
+}
+
+// lockBlobs holds the advisory locks of content hashes until tx ends, so a
+// blob is not released while a row pointing at it is being written. They are
+// taken in order so two writers cannot deadlock on them.
+func lockBlobs(tx *gorm.DB, hashes ...string) error {
+	sorted := append([]string{}, hashes...)
+	sort.Strings(sorted)
+
+	for i, hash := range sorted {
+		if i > 0 && hash == sorted[i-1] {
+			continue
+		}
+
+		err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", hash).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store the content of uploaded files unless identical blobs already exist;
+// the rows pointing at them must be written in the same transaction
+func (r *Repository) storeBlobs(context *fiber.Ctx, tx *gorm.DB, headers []*multipart.FileHeader, attachments []models.Attachment) error {
+	hashes := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		hashes = append(hashes, attachment.Hash)
+	}
+
+	err := lockBlobs(tx, hashes...)
+	if err != nil {
+		return err
+	}
+
+	for i, attachment := range attachments {
+		key := blobKey(attachment.Hash)
+
+		exists, err := r.Blobs.Exists(context.Context(), key)
+		if err != nil {
+			return apperr.Internal(err, "could not store upload")
+		}
+
+		if exists {
+			continue
+		}
+
+		content, err := headers[i].Open()
+		if err != nil {
+			return apperr.Internal(err, "could not store upload")
+		}
+
+		err = r.Blobs.Put(context.Context(), key, content, attachment.Size, attachment.ContentType)
+		content.Close()
+
+		if err != nil {
+			return apperr.Internal(err, "could not store upload")
+		}
+	}
+	return nil
+}
+
+// drop a blob once no attachment or profile picture refers to its content any more
+func (r *Repository) releaseBlob(ctx stdcontext.Context, hash string) {
+	// read committed rather than storage.Transaction's serializable, so the
+	// counts see rows committed while waiting for the lock
+	r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var attachments, avatars int64
+
+		err := lockBlobs(tx, hash)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Attachment{}).Where("hash = ?", hash).Count(&attachments).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Users{}).Where("avatar_hash = ?", hash).Count(&avatars).Error
+		if err != nil {
+			return err
+		}
+
+		if attachments == 0 && avatars == 0 {
+			return r.Blobs.Delete(ctx, blobKey(hash))
+		}
+		return nil
+	})
+}
+
+// drop the content of attachments whose rows were never written
+func (r *Repository) releaseUploads(ctx stdcontext.Context, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		r.releaseBlob(ctx, attachment.Hash)
+	}
+}
+
+// store every file of the multipart "file" field and record them against an
+// owner; prepare, when given, writes or checks the owner in the same transaction
+func (r *Repository) saveUploads(context *fiber.Ctx, classId *string, ownerType string, ownerId string, prepare func(tx *gorm.DB) error) ([]models.Attachment, error) {
+	form, err := context.MultipartForm()
+
+	if err != nil || len(form.File["file"]) == 0 {
+		return nil, apperr.Validation("upload one or more files in the multipart field \"file\"")
+	}
+
+	headers := form.File["file"]
+
+	attachments, err := r.inspectUploads(context, headers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		attachments[i].ClassID = classId
+		attachments[i].OwnerType = ownerType
+		attachments[i].OwnerID = ownerId
+	}
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		if prepare != nil {
+			if err := prepare(tx); err != nil {
+				return err
+			}
+		}
+
+		err := r.storeBlobs(context, tx, headers, attachments)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&attachments).Error
+	})
+
+	if err != nil {
+		r.releaseUploads(context.Context(), attachments)
+
+		if failed := apperr.As(err); failed != nil {
+			return nil, failed
+		}
+		return nil, writeFailed(err, "database insertion failed")
+	}
+
+	return attachments, nil
+}
+
+// write the attachments of an owner
+func (r *Repository) respondAttachments(context *fiber.Ctx, ownerType string, ownerId string) error {
+	attachments := []models.Attachment{}
+
+	err := r.DB.Where("owner_type = ? AND owner_id = ?", ownerType, ownerId).Order("created_at asc").Find(&attachments).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get attachments")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "attachments fetched",
+		"success": true,
+		"data":    dto.NewAttachments(attachments),
+	})
+	return nil
+}
+
+/*------------------------------------------------ assignment materials ------------------------------------------------------*/
+
+// attach materials to an assignment
+func (r *Repository) UploadAssignmentAttachments(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+
+	attachments, err := r.saveUploads(context, assignment.ClassID, models.AttachmentAssignment, *assignment.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "attachments uploaded",
+		"success": true,
+		"data":    dto.NewAttachments(attachments),
+	})
+	return nil
+}
+
+// list the materials of an assignment
+func (r *Repository) ListAssignmentAttachments(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+
+	return r.respondAttachments(context, models.AttachmentAssignment, *assignment.ID)
+}
+
+/*------------------------------------------------ submission files ------------------------------------------------------*/
+
+// attach files to the caller's work; turned in work must be unsubmitted first
+func (r *Repository) UploadSubmissionAttachments(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	assignment := assignmentAccess(context)
+
+	submission, err := r.findSubmission(*assignment.ID, *user.Uuid)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submission")
+	}
+
+	err = checkFilesEditable(assignment, submission, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// existing work is checked again in the transaction that adds the files,
+	// and the first files of a student start their work in it
+	var prepare func(tx *gorm.DB) error
+
+	if submission != nil {
+		submissionId := *submission.ID
+
+		prepare = func(tx *gorm.DB) error {
+			return guardFilesEditable(tx, submissionId)
+		}
+	} else {
+		id, _ := utils.GenerateUUid()
+
+		submission = &models.Submission{
+			ID:           &id,
+			AssignmentID: assignment.ID,
+			StudentID:    user.Uuid,
+			ClassID:      assignment.ClassID,
+			State:        models.SubmissionAssigned,
+		}
+
+		prepare = func(tx *gorm.DB) error {
+			return tx.Create(submission).Error
+		}
+	}
+
+	attachments, err := r.saveUploads(context, assignment.ClassID, models.AttachmentSubmission, *submission.ID, prepare)
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "attachments uploaded",
+		"success": true,
+		"data":    dto.NewAttachments(attachments),
+	})
+	return nil
+}
+
+// list the files of the caller's own work
+func (r *Repository) ListMySubmissionAttachments(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+
+	return r.listSubmissionAttachments(context, *user.Uuid)
+}
+
+// teacher view of the files a student attached to their work
+func (r *Repository) ListStudentSubmissionAttachments(context *fiber.Ctx) error {
+	return r.listSubmissionAttachments(context, context.Params("student_id"))
+}
+
+func (r *Repository) listSubmissionAttachments(context *fiber.Ctx, studentId string) error {
+	assignment := assignmentAccess(context)
+
+	submission, err := r.findSubmission(*assignment.ID, studentId)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submission")
+	}
+
+	if submission == nil {
+		context.Status(http.StatusOK).JSON(&fiber.Map{
+			"message": "attachments fetched",
+			"success": true,
+			"data":    []dto.Attachment{},
+		})
+		return nil
+	}
+
+	return r.respondAttachments(context, models.AttachmentSubmission, *submission.ID)
+}
+
+/*------------------------------------------------ comment files ------------------------------------------------------*/
+
+// attach files to one of the caller's comments
+func (r *Repository) UploadCommentAttachments(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+
+	comment, err := r.findClassComment(context)
+
+	if err != nil {
+		return apperr.NotFound("comment not found")
+	}
+
+	if comment.AuthorID == nil || *comment.AuthorID != *user.Uuid {
+		return apperr.Forbidden("only the author can attach files to this comment")
+	}
+
+	attachments, err := r.saveUploads(context, comment.ClassID, models.AttachmentComment, commentOwnerId(comment.ID), nil)
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "attachments uploaded",
+		"success": true,
+		"data":    dto.NewAttachments(attachments),
+	})
+	return nil
+}
+
+// list the files attached to a comment
+func (r *Repository) ListCommentAttachments(context *fiber.Ctx) error {
+	comment, err := r.findClassComment(context)
+
+	if err != nil {
+		return apperr.NotFound("comment not found")
+	}
+
+	return r.respondAttachments(context, models.AttachmentComment, commentOwnerId(comment.ID))
+}
+
+// comment ids are numeric while attachment owner ids are strings
+func commentOwnerId(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+/*------------------------------------------------ downloads ------------------------------------------------------*/
+
+// load an attachment and check the caller may see it
+func (r *Repository) authorizeAttachment(context *fiber.Ctx) (*models.Attachment, error) {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return nil, apperr.Unauthenticated("un-authorized")
+	}
+
+	attachment := models.Attachment{}
+
+	err := r.DB.Where("id = ?", context.Params("attachment_id")).First(&attachment).Error
+
+	notFound := apperr.NotFound("attachment not found")
+
+	if err != nil || attachment.ClassID == nil {
+		return nil, notFound
+	}
+
+	switch attachment.OwnerType {
+	case models.AttachmentAssignment:
+		err = r.authorizeClass(context, *attachment.ClassID, services.PermViewAssignments)
+		if err != nil {
+			return nil, err
+		}
+
+		assignment := models.Assignments{}
+		err = r.DB.Where("id = ? AND is_deleted = ?", attachment.OwnerID, false).First(&assignment).Error
+
+		_, role := classAccess(context)
+		if err != nil || (role == services.RoleStudent && !assignment.IsPublished(time.Now())) {
+			return nil, notFound
+		}
+
+	case models.AttachmentSubmission:
+		err = r.authorizeClass(context, *attachment.ClassID, services.PermViewClassroom)
+		if err != nil {
+			return nil, err
+		}
+
+		submission := models.Submission{}
+		err = r.DB.Where("id = ?", attachment.OwnerID).First(&submission).Error
+
+		_, role := classAccess(context)
+		if err != nil || (*submission.StudentID != *user.Uuid && !services.Can(role, services.PermReviewWork)) {
+			return nil, notFound
+		}
+
+	case models.AttachmentComment:
+		err = r.authorizeClass(context, *attachment.ClassID, services.PermViewComments)
+		if err != nil {
+			return nil, err
+		}
+
+		var count int64
+		r.DB.Model(&models.Comment{}).Where("id = ?", attachment.OwnerID).Count(&count)
+
+		if count == 0 {
+			return nil, notFound
+		}
+
+	default:
+		return nil, notFound
+	}
+
+	return &attachment, nil
+}
+
+// hand out a short lived download link for an attachment
+func (r *Repository) GetAttachmentURL(context *fiber.Ctx) error {
+	attachment, err := r.authorizeAttachment(context)
+	if err != nil {
+		return err
+	}
+
+	url, err := r.Blobs.SignedURL(context.Context(), blobKey(attachment.Hash), attachment.Filename, downloadURLTTL)
+
+	if err != nil {
+		return apperr.Internal(err, "could not sign download url")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"success":    true,
+		"url":        url,
+		"expires_at": time.Now().Add(downloadURLTTL),
+	})
+	return nil
+}
+
+// delete an attachment; the uploader can, and so can teachers for materials and comments
+func (r *Repository) DeleteAttachment(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+
+	attachment, err := r.authorizeAttachment(context)
+	if err != nil {
+		return err
+	}
+
+	_, role := classAccess(context)
+	isUploader := attachment.UploadedBy != nil && *attachment.UploadedBy == *user.Uuid
+	isTeacher := services.Can(role, services.PermManageAssignments) && attachment.OwnerType != models.AttachmentSubmission
+
+	if !isUploader && !isTeacher {
+		return apperr.Forbidden("you do not have permission to delete this attachment")
+	}
+
+	if attachment.OwnerType == models.AttachmentSubmission {
+		err = r.checkSubmissionEditable(attachment.OwnerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		if attachment.OwnerType == models.AttachmentSubmission {
+			if err := guardFilesEditable(tx, attachment.OwnerID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(attachment).Error
+	})
+
+	if err != nil {
+		if failed := apperr.As(err); failed != nil {
+			return failed
+		}
+		return writeFailed(err, "database update failed")
+	}
+
+	r.releaseBlob(context.Context(), attachment.Hash)
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "attachment deleted",
+		"success": true,
+	})
+	return nil
+}
+
+// load the submission an attachment belongs to and check its files may change
+func (r *Repository) checkSubmissionEditable(submissionId string) error {
+	submission := models.Submission{}
+
+	err := r.DB.Where("id = ?", submissionId).First(&submission).Error
+
+	if err != nil {
+		return apperr.NotFound("attachment not found")
+	}
+
+	assignment := models.Assignments{}
+
+	err = r.DB.Where("id = ?", submission.AssignmentID).First(&assignment).Error
+
+	if err != nil {
+		return apperr.NotFound("attachment not found")
+	}
+
+	return checkFilesEditable(&assignment, &submission, time.Now())
+}
+
+// files can only be added to or taken off work that is still open and has not
+// been handed in, returned or graded; submission is nil before any work exists
+func checkFilesEditable(assignment *models.Assignments, submission *models.Submission, now time.Time) error {
+	if assignment.IsClosed(now) {
+		return apperr.Forbidden("this assignment no longer accepts work")
+	}
+
+	state := assignment.SubmissionState(submission, now)
+
+	if isTurnedIn(state) {
+		return apperr.Conflict("work is already turned in, unsubmit it first")
+	}
+
+	if state == models.SubmissionReturned || (submission != nil && submission.GradedAt != nil) {
+		return apperr.Conflict("files cannot be changed on graded or returned work")
+	}
+	return nil
+}
+
+// guardFilesEditable holds the checks of checkFilesEditable inside tx, the way
+// writeSubmission guards its states, so work turned in, returned or graded
+// meanwhile fails the change instead of having its files swapped underneath
+func guardFilesEditable(tx *gorm.DB, submissionId string) error {
+	result := tx.Model(&models.Submission{}).
+		Where("id = ? AND state = ? AND graded_at IS NULL", submissionId, models.SubmissionAssigned).
+		Update("updated_at", time.Now())
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperr.Conflict("work was changed meanwhile, reload it and try again")
+	}
+	return nil
+}
+
+// serve a blob from local storage to the holder of a signed url
+func (r *Repository) DownloadFile(context *fiber.Ctx) error {
+	local, ok := r.Blobs.(*storage.LocalBlobStore)
+
+	if !ok {
+		return apperr.NotFound("file not found")
+	}
+
+	key := context.Params("*")
+	filename := context.Query("name")
+
+	if !local.Verify(key, context.Query("expires"), filename, context.Query("sig")) {
+		return apperr.Forbidden("download link is invalid or has expired")
+	}
+
+	content, err := local.Get(context.Context(), key)
+
+	if err != nil {
+		return apperr.NotFound("file not found")
+	}
+
+	contentType := "application/octet-stream"
+	if kind, ok := allowedUploads[strings.ToLower(filepath.Ext(filename))]; ok {
+		contentType = kind.ContentType
+	}
+
+	context.Set(fiber.HeaderContentType, contentType)
+	context.Set(fiber.HeaderContentDisposition, `attachment; filename="`+sanitizeFilename(filename)+`"`)
+	context.Set("X-Content-Type-Options", "nosniff")
+
+	return context.SendStream(content)
+}
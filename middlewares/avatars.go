@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/storage"
+	"gorm.io/gorm"
+)
+
+const maxAvatarSize = 5 << 20
+
+var avatarTypes = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".webp": true,
+}
+
+// pick the default picture for a user; a failing provider leaves them
+// without one rather than failing the request
+func (r *Repository) defaultAvatar(context *fiber.Ctx, user *models.Users) *string {
+	return services.DefaultAvatar(context.Context(), r.Avatars, user)
+}
+
+// the stable url of an uploaded profile picture; it redirects to a fresh signed link
+func avatarPath(userId string) string {
+	return "/api/v1/user/" + userId + "/avatar"
+}
+
+// upload a profile picture for the logged in user
+func (r *Repository) UploadProfilePicture(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	header, err := context.FormFile("file")
+
+	if err != nil {
+		return apperr.Validation("upload an image in the multipart field \"file\"")
+	}
+
+	if !avatarTypes[strings.ToLower(filepath.Ext(header.Filename))] {
+		return apperr.UnsupportedMedia("profile pictures must be png, jpeg, gif or webp images")
+	}
+
+	if header.Size > maxAvatarSize {
+		return apperr.TooLarge("profile pictures must be smaller than 5MB")
+	}
+
+	stored, err := inspectUpload(header)
+
+	if rejected := apperr.As(err); rejected != nil {
+		return rejected
+	}
+
+	if err != nil {
+		return apperr.Internal(err, "could not read upload")
+	}
+
+	previous := user.AvatarHash
+	picture := avatarPath(*user.Uuid)
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		err := r.storeBlobs(context, tx, []*multipart.FileHeader{header}, []models.Attachment{*stored})
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(user).Updates(map[string]interface{}{
+			"avatar_hash":     stored.Hash,
+			"avatar_name":     "avatar" + strings.ToLower(filepath.Ext(stored.Filename)),
+			"profile_picture": picture,
+		}).Error
+	})
+
+	if err != nil {
+		r.releaseBlob(context.Context(), stored.Hash)
+
+		if failed := apperr.As(err); failed != nil {
+			return failed
+		}
+		return writeFailed(err, "database update failed")
+	}
+
+	if previous != nil && *previous != stored.Hash {
+		r.releaseBlob(context.Context(), *previous)
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":         "profile picture updated",
+		"success":         true,
+		"profile_picture": picture,
+	})
+	return nil
+}
+
+// go back to the generated profile picture
+func (r *Repository) RemoveProfilePicture(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	previous := user.AvatarHash
+	picture := r.defaultAvatar(context, user)
+
+	err := r.DB.Model(user).Updates(map[string]interface{}{
+		"avatar_hash":     nil,
+		"avatar_name":     nil,
+		"profile_picture": picture,
+	}).Error
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	if previous != nil {
+		r.releaseBlob(context.Context(), *previous)
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":         "profile picture removed",
+		"success":         true,
+		"profile_picture": picture,
+	})
+	return nil
+}
+
+// send the viewer to a short lived link for a user's uploaded picture
+func (r *Repository) GetProfilePicture(context *fiber.Ctx) error {
+	user := models.Users{}
+
+	err := r.DB.Where("uuid = ?", context.Params("user_id")).First(&user).Error
+
+	if err != nil || user.AvatarHash == nil || user.AvatarName == nil {
+		return apperr.NotFound("profile picture not found")
+	}
+
+	url, err := r.Blobs.SignedURL(context.Context(), blobKey(*user.AvatarHash), *user.AvatarName, downloadURLTTL)
+
+	if err != nil {
+		return apperr.Internal(err, "could not sign download url")
+	}
+
+	return context.Redirect(url, http.StatusFound)
+}
@@ -0,0 +1,381 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/storage"
+	"gorm.io/gorm"
+)
+
+type comingComment struct {
+	Content  string `json:"content" validate:"required,max=5000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type replyCount struct {
+	ParentID uint
+	Count    int64
+}
+
+// find a live comment that belongs to the classroom in the route
+func (r *Repository) findClassComment(context *fiber.Ctx) (*models.Comment, error) {
+	return r.loadClassComment(context, r.DB)
+}
+
+// load the comment in the route from db, which may include deleted ones
+func (r *Repository) loadClassComment(context *fiber.Ctx, db *gorm.DB) (*models.Comment, error) {
+	classroom, _ := classAccess(context)
+	comment := models.Comment{}
+
+	commentId, err := strconv.ParseUint(context.Params("comment_id"), 10, 64)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	err = db.Preload("Author").Where("id = ? AND class_id = ?", commentId, classroom.ClassId).First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// liveDescendant holds for a comment with a live reply anywhere below it
+const liveDescendant = `EXISTS (
+	WITH RECURSIVE descendants AS (
+		SELECT replies.id, replies.deleted_at FROM comments AS replies WHERE replies.parent_id = comments.id
+		UNION ALL
+		SELECT children.id, children.deleted_at FROM comments AS children JOIN descendants ON children.parent_id = descendants.id
+	)
+	SELECT 1 FROM descendants WHERE descendants.deleted_at IS NULL
+)`
+
+// keep deleted comments in a list as tombstones while a live reply is still
+// somewhere below them, so every live reply can be reached from the stream
+func withTombstones(query *gorm.DB) *gorm.DB {
+	return query.Unscoped().Where("(comments.deleted_at IS NULL OR " + liveDescendant + ")")
+}
+
+// count the replies of each comment that a list of them would show
+func (r *Repository) countReplies(comments []models.Comment) (map[uint]int64, error) {
+	counts := map[uint]int64{}
+	if len(comments) == 0 {
+		return counts, nil
+	}
+
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+
+	rows := []replyCount{}
+	err := withTombstones(r.DB.Model(&models.Comment{})).
+		Select("parent_id, count(*) as count").
+		Where("parent_id IN ?", ids).
+		Group("parent_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.ParentID] = row.Count
+	}
+	return counts, nil
+}
+
+// the comment lists page through created_at, newest first for the stream
+// and oldest first for replies
+func commentList(defaultSort string) listSpec {
+	return listSpec{
+		Table:       "comments",
+		IDColumn:    "id",
+		NumericID:   true,
+		Sorts:       map[string]string{"created_at": "comments.created_at"},
+		DefaultSort: defaultSort,
+	}
+}
+
+// run a paginated comment query and write the page
+func (r *Repository) respondCommentPage(context *fiber.Ctx, query *gorm.DB, spec listSpec) error {
+	params, err := parseListParams(context, spec)
+	if err != nil {
+		return err
+	}
+
+	query = query.Model(&models.Comment{})
+
+	var total int64
+
+	err = query.Session(&gorm.Session{}).Count(&total).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get comments")
+	}
+
+	comments := []models.Comment{}
+
+	err = params.apply(query.Preload("Author")).Find(&comments).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get comments")
+	}
+
+	hasMore := len(comments) > params.Limit
+	if hasMore {
+		comments = comments[:params.Limit]
+	}
+
+	var last models.Comment
+	if len(comments) != 0 {
+		last = comments[len(comments)-1]
+	}
+
+	counts, err := r.countReplies(comments)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get comments")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "comments fetched",
+		"success": true,
+		"data":    dto.NewComments(comments, counts),
+		"meta":    params.meta(total, hasMore, last.CreatedAt, strconv.FormatUint(uint64(last.ID), 10)),
+	})
+	return nil
+}
+
+// list the class stream, newest first by default
+func (r *Repository) ListComments(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	query := withTombstones(r.DB).Where("class_id = ? AND parent_id IS NULL", classroom.ClassId)
+
+	return r.respondCommentPage(context, query, commentList("-created_at"))
+}
+
+// list the replies of a comment, oldest first by default; a deleted comment
+// keeps its replies
+func (r *Repository) ListCommentReplies(context *fiber.Ctx) error {
+	comment, err := r.loadClassComment(context, r.DB.Unscoped())
+
+	if err != nil {
+		return apperr.NotFound("comment not found")
+	}
+
+	query := withTombstones(r.DB).Where("parent_id = ?", comment.ID)
+
+	return r.respondCommentPage(context, query, commentList("created_at"))
+}
+
+// get a single comment
+func (r *Repository) GetComment(context *fiber.Ctx) error {
+	comment, err := r.findClassComment(context)
+
+	if err != nil {
+		return apperr.NotFound("comment not found")
+	}
+
+	counts, err := r.countReplies([]models.Comment{*comment})
+
+	if err != nil {
+		return apperr.Internal(err, "could not get comment")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "comment fetched",
+		"success": true,
+		"data":    dto.NewComments([]models.Comment{*comment}, counts)[0],
+	})
+	return nil
+}
+
+// post to the class stream or reply to a comment
+func (r *Repository) CreateComment(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	classroom, _ := classAccess(context)
+
+	incoming := comingComment{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	content := strings.TrimSpace(incoming.Content)
+
+	if incoming.ParentID != nil {
+		parent := models.Comment{}
+
+		err = r.DB.Where("id = ? AND class_id = ?", *incoming.ParentID, classroom.ClassId).First(&parent).Error
+
+		if err != nil {
+			return apperr.NotFound("parent comment not found")
+		}
+	}
+
+	comment := models.Comment{
+		Content:  &content,
+		AuthorID: user.Uuid,
+		ClassID:  classroom.ClassId,
+		ParentID: incoming.ParentID,
+	}
+
+	err = r.DB.Create(&comment).Error
+
+	if err != nil {
+		return writeFailed(err, "database insertion failed")
+	}
+
+	comment.Author = *user
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "comment posted",
+		"success": true,
+		"data":    dto.NewComment(comment),
+	})
+	return nil
+}
+
+// edit a comment, keeping the previous content in its history
+func (r *Repository) EditComment(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+
+	comment, err := r.findClassComment(context)
+
+	if err != nil {
+		return apperr.NotFound("comment not found")
+	}
+
+	if comment.AuthorID == nil || *comment.AuthorID != *user.Uuid {
+		return apperr.Forbidden("only the author can edit this comment")
+	}
+
+	incoming := comingComment{}
+
+	err = parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	content := strings.TrimSpace(incoming.Content)
+
+	now := time.Now()
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		edit := models.CommentEdit{
+			CommentID: comment.ID,
+			Content:   comment.Content,
+			EditedBy:  user.Uuid,
+		}
+
+		if err := tx.Create(&edit).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(comment).Updates(map[string]interface{}{
+			"content":   content,
+			"edited_at": now,
+		}).Error
+	})
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	comment.Content = &content
+	comment.EditedAt = &now
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "comment edited",
+		"success": true,
+		"data":    dto.NewComment(*comment),
+	})
+	return nil
+}
+
+// soft delete a comment; authors can delete their own, teachers any in the class
+func (r *Repository) DeleteComment(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	_, role := classAccess(context)
+
+	comment, err := r.findClassComment(context)
+
+	if err != nil {
+		return apperr.NotFound("comment not found")
+	}
+
+	isAuthor := comment.AuthorID != nil && *comment.AuthorID == *user.Uuid
+
+	if !isAuthor && !services.Can(role, services.PermModerateComments) {
+		return apperr.Forbidden("you do not have permission to delete this comment")
+	}
+
+	hashes := []string{}
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		ownerId := commentOwnerId(comment.ID)
+
+		err := tx.Model(&models.Attachment{}).Where("owner_type = ? AND owner_id = ?", models.AttachmentComment, ownerId).Distinct().Pluck("hash", &hashes).Error
+		if err != nil {
+			return err
+		}
+
+		// the files go with the comment, even while it stays behind as a tombstone
+		err = tx.Where("owner_type = ? AND owner_id = ?", models.AttachmentComment, ownerId).Delete(&models.Attachment{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(comment).Error
+	})
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	for _, hash := range hashes {
+		r.releaseBlob(context.Context(), hash)
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "comment deleted",
+		"success": true,
+	})
+	return nil
+}
+
+// list the previous versions of a comment, oldest first
+func (r *Repository) GetCommentHistory(context *fiber.Ctx) error {
+	comment, err := r.findClassComment(context)
+
+	if err != nil {
+		return apperr.NotFound("comment not found")
+	}
+
+	edits := []models.CommentEdit{}
+
+	err = r.DB.Where("comment_id = ?", comment.ID).Order("created_at asc").Order("id asc").Find(&edits).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get comment history")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "comment history fetched",
+		"success": true,
+		"current": dto.NewComment(*comment),
+		"data":    dto.NewCommentEdits(edits),
+	})
+	return nil
+}
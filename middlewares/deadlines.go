@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"gorm.io/gorm"
+)
+
+// wherePublished limits an assignment query to what students can see
+func wherePublished(query *gorm.DB, now time.Time) *gorm.DB {
+	return query.Where("is_draft = ? AND (publish_at IS NULL OR publish_at <= ?)", false, now)
+}
+
+// publish a draft or scheduled assignment right away
+func (r *Repository) PublishAssignment(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+
+	err := r.Services.Assignments.Publish(assignment)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "assignment published",
+		"success": true,
+		"data":    dto.NewAssignment(*assignment),
+	})
+	return nil
+}
+
+// turn an assignment back into a draft students cannot see
+func (r *Repository) UnpublishAssignment(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+
+	err := r.Services.Assignments.Unpublish(assignment)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "assignment moved to drafts",
+		"success": true,
+		"data":    dto.NewAssignment(*assignment),
+	})
+	return nil
+}
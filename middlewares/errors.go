@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"gorm.io/gorm"
+)
+
+// the code sent for errors that did not come from apperr, by status
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            apperr.CodeBadRequest,
+	http.StatusUnauthorized:          apperr.CodeUnauthenticated,
+	http.StatusForbidden:             apperr.CodeForbidden,
+	http.StatusNotFound:              apperr.CodeNotFound,
+	http.StatusMethodNotAllowed:      apperr.CodeNotFound,
+	http.StatusConflict:              apperr.CodeConflict,
+	http.StatusGone:                  apperr.CodeGone,
+	http.StatusRequestEntityTooLarge: apperr.CodeTooLarge,
+	http.StatusUnsupportedMediaType:  apperr.CodeUnsupportedMedia,
+	http.StatusUnprocessableEntity:   apperr.CodeValidation,
+	http.StatusTooManyRequests:       apperr.CodeTooManyRequests,
+	http.StatusServiceUnavailable:    apperr.CodeServiceUnavailable,
+}
+
+const duplicateMessage = "this conflicts with an existing record"
+
+// writeFailed reports a failed insert or update, as a conflict when it broke
+// a unique constraint
+func writeFailed(err error, message string) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return apperr.Conflict(duplicateMessage)
+	}
+	return apperr.Internal(err, message)
+}
+
+// toAppError turns whatever a handler returned into an *apperr.Error
+func toAppError(err error) *apperr.Error {
+	if appErr := apperr.As(err); appErr != nil {
+		return appErr
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.NotFound("not found")
+	}
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return apperr.Conflict(duplicateMessage)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code, ok := statusCodes[fiberErr.Code]
+		if !ok {
+			code = apperr.CodeInternal
+		}
+		return apperr.New(fiberErr.Code, code, fiberErr.Message)
+	}
+
+	return apperr.Internal(err, "internal server error")
+}
+
+// ErrorHandler renders every error a handler returns as the same JSON
+// envelope, with a machine readable code and the id of the request
+func ErrorHandler(context *fiber.Ctx, err error) error {
+	appErr := toAppError(err)
+
+	requestId, _ := context.Locals("requestid").(string)
+
+	// the cause of a server error is only for the logs
+	if appErr.Status >= http.StatusInternalServerError {
+		log.Printf("request %s: %s %s: %v", requestId, context.Method(), context.Path(), appErr)
+	}
+
+	body := fiber.Map{
+		"success":    false,
+		"message":    appErr.Message,
+		"code":       appErr.Code,
+		"request_id": requestId,
+	}
+	if len(appErr.Fields) != 0 {
+		body["fields"] = appErr.Fields
+	}
+
+	return context.Status(appErr.Status).JSON(body)
+}
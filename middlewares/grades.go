@@ -0,0 +1,716 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/storage"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"gorm.io/gorm"
+)
+
+type comingCriterion struct {
+	Title       string  `json:"title" validate:"required,max=200"`
+	Description *string `json:"description" validate:"max=2000"`
+	Points      float64 `json:"points" validate:"min=0"`
+}
+
+type comingRubric struct {
+	Criteria []comingCriterion `json:"criteria" validate:"max=50"`
+}
+
+type comingRubricScore struct {
+	CriterionID uint    `json:"criterion_id" validate:"required"`
+	Points      float64 `json:"points" validate:"min=0"`
+	Comment     *string `json:"comment" validate:"max=2000"`
+}
+
+type comingGrade struct {
+	Grade        *float64            `json:"grade" validate:"min=0"`
+	Feedback     *string             `json:"feedback" validate:"max=5000"`
+	RubricScores []comingRubricScore `json:"rubric_scores"`
+}
+
+type comingGradeCategory struct {
+	Name   *string  `json:"name" validate:"min=1,max=100"`
+	Weight *float64 `json:"weight" validate:"min=0,max=100"`
+}
+
+/*------------------------------------------------ rubrics ------------------------------------------------------*/
+
+// get the rubric of an assignment
+func (r *Repository) GetRubric(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+
+	criteria := []models.RubricCriterion{}
+
+	err := r.DB.Where("assignment_id = ?", assignment.ID).Order("position asc").Find(&criteria).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get rubric")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "rubric fetched",
+		"success": true,
+		"data":    dto.NewRubric(criteria),
+	})
+	return nil
+}
+
+// replace the rubric of an assignment
+func (r *Repository) SetRubric(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+
+	incoming := comingRubric{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	criteria := make([]models.RubricCriterion, 0, len(incoming.Criteria))
+	total := 0.0
+
+	for i, c := range incoming.Criteria {
+		title := strings.TrimSpace(c.Title)
+
+		criteria = append(criteria, models.RubricCriterion{
+			AssignmentID: assignment.ID,
+			Title:        &title,
+			Description:  c.Description,
+			Points:       c.Points,
+			Position:     i,
+		})
+		total += c.Points
+	}
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		// ids handed out by an attempt that was rolled back are not kept
+		for i := range criteria {
+			criteria[i].ID = 0
+		}
+
+		if err := tx.Where("assignment_id = ?", assignment.ID).Delete(&models.RubricCriterion{}).Error; err != nil {
+			return err
+		}
+
+		if len(criteria) != 0 {
+			if err := tx.Create(&criteria).Error; err != nil {
+				return err
+			}
+		}
+
+		// an assignment without its own point value is worth its rubric
+		if assignment.Points == nil && len(criteria) != 0 {
+			return tx.Model(assignment).Update("points", total).Error
+		}
+		return nil
+	})
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "rubric saved",
+		"success": true,
+		"data":    dto.NewRubric(criteria),
+	})
+	return nil
+}
+
+/*------------------------------------------------ grading ------------------------------------------------------*/
+
+// check that a user is an active student of a classroom
+func (r *Repository) isClassStudent(classId string, userId string) (bool, error) {
+	var count int64
+
+	err := r.DB.Model(&models.ClassroomCollaborator{}).
+		Scopes(activeMembership).Where("class_id = ? AND user_id = ? AND role = ?", classId, userId, services.RoleStudent).
+		Count(&count).Error
+
+	return count != 0, err
+}
+
+// grade a student's work, optionally scored against the rubric
+func (r *Repository) GradeSubmission(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	assignment := assignmentAccess(context)
+	studentId := context.Params("student_id")
+
+	isStudent, err := r.isClassStudent(*assignment.ClassID, studentId)
+
+	if err != nil || !isStudent {
+		return apperr.NotFound("student not found in this classroom")
+	}
+
+	incoming := comingGrade{}
+
+	err = parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	criteria := []models.RubricCriterion{}
+
+	err = r.DB.Where("assignment_id = ?", assignment.ID).Find(&criteria).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get rubric")
+	}
+
+	maxPoints := map[uint]float64{}
+	for _, criterion := range criteria {
+		maxPoints[criterion.ID] = criterion.Points
+	}
+
+	scores := make([]models.RubricScore, 0, len(incoming.RubricScores))
+	scored := map[uint]bool{}
+	rubricTotal := 0.0
+
+	for i, score := range incoming.RubricScores {
+		if scored[score.CriterionID] {
+			return invalidField("rubric_scores", fmt.Sprintf("criterion %d is scored more than once", score.CriterionID))
+		}
+		scored[score.CriterionID] = true
+
+		max, ok := maxPoints[score.CriterionID]
+
+		if !ok {
+			return invalidField(fmt.Sprintf("rubric_scores[%d].criterion_id", i), "is not a criterion of this assignment's rubric")
+		}
+		if score.Points > max {
+			return invalidField(fmt.Sprintf("rubric_scores[%d].points", i), fmt.Sprintf("must be at most %g", max))
+		}
+
+		scores = append(scores, models.RubricScore{
+			CriterionID: score.CriterionID,
+			Points:      score.Points,
+			Comment:     score.Comment,
+		})
+		rubricTotal += score.Points
+	}
+
+	grade := incoming.Grade
+	if grade == nil && len(scores) != 0 {
+		grade = &rubricTotal
+	}
+
+	submission, err := r.findSubmission(*assignment.ID, studentId)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submission")
+	}
+
+	// work can be graded even if nothing was turned in
+	isNew := submission == nil
+	if isNew {
+		id, _ := utils.GenerateUUid()
+
+		submission = &models.Submission{
+			ID:           &id,
+			AssignmentID: assignment.ID,
+			StudentID:    &studentId,
+			ClassID:      assignment.ClassID,
+			State:        models.SubmissionAssigned,
+		}
+	}
+
+	now := time.Now()
+	submission.Grade = grade
+	submission.Feedback = incoming.Feedback
+	submission.GradedAt = &now
+	submission.GradedBy = user.Uuid
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		err := writeSubmission(tx, submission, isNew, map[string]interface{}{
+			"grade":     submission.Grade,
+			"feedback":  submission.Feedback,
+			"graded_at": submission.GradedAt,
+			"graded_by": submission.GradedBy,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("submission_id = ?", submission.ID).Delete(&models.RubricScore{}).Error; err != nil {
+			return err
+		}
+
+		for i := range scores {
+			scores[i].ID = 0
+			scores[i].SubmissionID = submission.ID
+		}
+
+		if len(scores) != 0 {
+			return tx.Create(&scores).Error
+		}
+		return nil
+	})
+
+	if err != nil {
+		if failed := apperr.As(err); failed != nil {
+			return failed
+		}
+		return writeFailed(err, "database update failed")
+	}
+
+	submission.RubricScores = scores
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "grade saved",
+		"success": true,
+		"data":    dto.NewAssignmentSubmission(*assignment, *submission),
+	})
+	return nil
+}
+
+/*------------------------------------------------ grade categories ------------------------------------------------------*/
+
+// sum the weights of a classroom's categories, leaving one out when it is being edited
+func (r *Repository) categoryWeightTotal(classId *string, exceptId uint) (float64, error) {
+	var total float64
+
+	err := r.DB.Model(&models.GradeCategory{}).
+		Select("COALESCE(SUM(weight), 0)").
+		Where("class_id = ? AND id <> ?", classId, exceptId).
+		Scan(&total).Error
+
+	return total, err
+}
+
+// list the grade categories of a classroom
+func (r *Repository) ListGradeCategories(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	params, err := parseListParams(context, listSpec{
+		Table:     "grade_categories",
+		IDColumn:  "id",
+		NumericID: true,
+		Sorts: map[string]string{
+			"created_at": "grade_categories.created_at",
+			"name":       "grade_categories.name",
+		},
+		DefaultSort: "created_at",
+	})
+	if err != nil {
+		return err
+	}
+
+	query := r.DB.Model(&models.GradeCategory{}).Where("class_id = ?", classroom.ClassId)
+
+	var total int64
+
+	err = query.Session(&gorm.Session{}).Count(&total).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get grade categories")
+	}
+
+	categories := []models.GradeCategory{}
+
+	err = params.apply(query).Find(&categories).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get grade categories")
+	}
+
+	hasMore := len(categories) > params.Limit
+	if hasMore {
+		categories = categories[:params.Limit]
+	}
+
+	var last models.GradeCategory
+	if len(categories) != 0 {
+		last = categories[len(categories)-1]
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "grade categories fetched",
+		"success": true,
+		"data":    dto.NewGradeCategories(categories),
+		"meta":    params.meta(total, hasMore, last.CreatedAt, strconv.FormatUint(uint64(last.ID), 10)),
+	})
+	return nil
+}
+
+// create a weighted grade category
+func (r *Repository) CreateGradeCategory(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	incoming := comingGradeCategory{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	if incoming.Name == nil {
+		return invalidField("name", "is required")
+	}
+
+	category := models.GradeCategory{
+		ClassID: classroom.ClassId,
+		Name:    incoming.Name,
+	}
+	if incoming.Weight != nil {
+		category.Weight = *incoming.Weight
+	}
+
+	err = r.checkCategoryWeight(&category)
+	if err != nil {
+		return err
+	}
+
+	err = r.DB.Create(&category).Error
+
+	if err != nil {
+		return writeFailed(err, "database insertion failed")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "grade category created",
+		"success": true,
+		"data":    dto.NewGradeCategory(category),
+	})
+	return nil
+}
+
+// rename or reweight a grade category
+func (r *Repository) EditGradeCategory(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	category := models.GradeCategory{}
+
+	err := r.DB.Where("id = ? AND class_id = ?", context.Params("category_id"), classroom.ClassId).First(&category).Error
+
+	if err != nil {
+		return apperr.NotFound("grade category not found")
+	}
+
+	incoming := comingGradeCategory{}
+
+	err = parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	if incoming.Name != nil {
+		category.Name = incoming.Name
+	}
+	if incoming.Weight != nil {
+		category.Weight = *incoming.Weight
+	}
+
+	err = r.checkCategoryWeight(&category)
+	if err != nil {
+		return err
+	}
+
+	err = r.DB.Model(&category).Updates(map[string]interface{}{
+		"name":   category.Name,
+		"weight": category.Weight,
+	}).Error
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "grade category updated",
+		"success": true,
+		"data":    dto.NewGradeCategory(category),
+	})
+	return nil
+}
+
+// delete a grade category; its assignments become uncategorized
+func (r *Repository) DeleteGradeCategory(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	result := r.DB.Where("id = ? AND class_id = ?", context.Params("category_id"), classroom.ClassId).Delete(&models.GradeCategory{})
+
+	if result.Error != nil {
+		return writeFailed(result.Error, "database update failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return apperr.NotFound("grade category not found")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "grade category deleted",
+		"success": true,
+	})
+	return nil
+}
+
+// weights are percentages and a classroom's categories can add up to at most 100
+func (r *Repository) checkCategoryWeight(category *models.GradeCategory) error {
+	if category.Weight < 0 || category.Weight > 100 {
+		return apperr.Validation("weight must be between 0 and 100")
+	}
+
+	total, err := r.categoryWeightTotal(category.ClassID, category.ID)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get grade categories")
+	}
+
+	if total+category.Weight > 100 {
+		return apperr.Validation("category weights of a classroom cannot add up to more than 100")
+	}
+	return nil
+}
+
+/*------------------------------------------------ gradebook ------------------------------------------------------*/
+
+// gradebookRow computes one student's grades and running average. Only graded
+// work on assignments with points counts. When the classroom has weighted
+// categories the average is the weighted mean of the category percentages
+// (uncategorized work is left out); otherwise it is total points earned over
+// total points possible.
+func gradebookRow(student models.Users, assignments []models.Assignments, categories []models.GradeCategory, submissions map[string]*models.Submission) dto.GradebookRow {
+	row := dto.GradebookRow{
+		Student:    dto.NewPublicUser(student),
+		Categories: map[uint]*float64{},
+		Grades:     map[string]*float64{},
+	}
+
+	earned := map[uint]float64{}
+	possible := map[uint]float64{}
+	totalEarned, totalPossible := 0.0, 0.0
+
+	for _, assignment := range assignments {
+		submission := submissions[*assignment.ID]
+
+		grade := assignment.AdjustedGrade(submission)
+		row.Grades[*assignment.ID] = grade
+
+		if grade == nil || assignment.Points == nil || *assignment.Points <= 0 {
+			continue
+		}
+
+		var categoryId uint
+		if assignment.CategoryID != nil {
+			categoryId = *assignment.CategoryID
+		}
+
+		earned[categoryId] += *grade
+		possible[categoryId] += *assignment.Points
+		totalEarned += *grade
+		totalPossible += *assignment.Points
+	}
+
+	weightedSum, weightTotal := 0.0, 0.0
+
+	for _, category := range categories {
+		if possible[category.ID] == 0 {
+			row.Categories[category.ID] = nil
+			continue
+		}
+
+		percent := roundPercent(earned[category.ID] / possible[category.ID] * 100)
+		row.Categories[category.ID] = &percent
+
+		if category.Weight > 0 {
+			weightedSum += category.Weight * percent
+			weightTotal += category.Weight
+		}
+	}
+
+	if weightTotal > 0 {
+		average := roundPercent(weightedSum / weightTotal)
+		row.Average = &average
+	} else if !hasWeights(categories) && totalPossible > 0 {
+		average := roundPercent(totalEarned / totalPossible * 100)
+		row.Average = &average
+	}
+
+	return row
+}
+
+func hasWeights(categories []models.GradeCategory) bool {
+	for _, category := range categories {
+		if category.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+type gradebook struct {
+	Columns    []dto.GradebookColumn
+	Categories []models.GradeCategory
+	Rows       []dto.GradebookRow
+}
+
+// load everything the gradebook needs for the given students of a classroom,
+// with their users preloaded; a student's view only has published assignments
+// and grades that were returned
+func (r *Repository) buildGradebook(classId *string, students []models.ClassroomCollaborator, studentView bool) (*gradebook, error) {
+	assignments := []models.Assignments{}
+
+	query := r.DB.Where("class_id = ? AND is_deleted = ?", classId, false)
+	if studentView {
+		query = wherePublished(query, time.Now())
+	}
+
+	err := query.Order("created_at asc").Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	categories := []models.GradeCategory{}
+
+	err = r.DB.Where("class_id = ?", classId).Order("id asc").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	studentIds := make([]string, 0, len(students))
+	for _, student := range students {
+		studentIds = append(studentIds, *student.UserID)
+	}
+
+	submissions := []models.Submission{}
+
+	err = r.DB.Where("class_id = ? AND student_id IN ?", classId, studentIds).Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byStudent := map[string]map[string]*models.Submission{}
+	for i := range submissions {
+		submission := &submissions[i]
+		if studentView && !submission.GradeReleased() {
+			continue
+		}
+		if byStudent[*submission.StudentID] == nil {
+			byStudent[*submission.StudentID] = map[string]*models.Submission{}
+		}
+		byStudent[*submission.StudentID][*submission.AssignmentID] = submission
+	}
+
+	columns := make([]dto.GradebookColumn, 0, len(assignments))
+	for _, assignment := range assignments {
+		column := dto.GradebookColumn{
+			AssignmentID: assignment.ID,
+			Title:        assignment.Title,
+			CategoryID:   assignment.CategoryID,
+		}
+		if assignment.Points != nil {
+			column.Points = *assignment.Points
+		}
+		columns = append(columns, column)
+	}
+
+	rows := make([]dto.GradebookRow, 0, len(students))
+	for _, student := range students {
+		rows = append(rows, gradebookRow(student.User, assignments, categories, byStudent[*student.UserID]))
+	}
+
+	return &gradebook{Columns: columns, Categories: categories, Rows: rows}, nil
+}
+
+// teacher view of every student's grades in a classroom, a page of students at a time
+func (r *Repository) GetGradebook(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	params, err := parseListParams(context, studentList)
+	if err != nil {
+		return err
+	}
+
+	query := r.classStudents(classroom.ClassId)
+
+	var total int64
+
+	err = query.Session(&gorm.Session{}).Count(&total).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not count students")
+	}
+
+	students := []models.ClassroomCollaborator{}
+
+	err = params.apply(query.Select("classroom_collaborators.*").Preload("User")).Find(&students).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get students")
+	}
+
+	hasMore := len(students) > params.Limit
+	if hasMore {
+		students = students[:params.Limit]
+	}
+
+	book, err := r.buildGradebook(classroom.ClassId, students, false)
+
+	if err != nil {
+		return apperr.Internal(err, "could not build gradebook")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":     "gradebook fetched",
+		"success":     true,
+		"assignments": book.Columns,
+		"categories":  dto.NewGradeCategories(book.Categories),
+		"data":        book.Rows,
+		"meta":        params.meta(total, hasMore, time.Time{}, ""),
+	})
+	return nil
+}
+
+// a student's own line of the gradebook
+func (r *Repository) GetMyGrades(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	classroom, _ := classAccess(context)
+
+	students := []models.ClassroomCollaborator{}
+
+	err := r.DB.Preload("User").Scopes(activeMembership).
+		Where("class_id = ? AND user_id = ? AND role = ?", classroom.ClassId, user.Uuid, services.RoleStudent).
+		Limit(1).Find(&students).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get grades")
+	}
+
+	if len(students) == 0 {
+		return apperr.NotFound("you have no grades in this classroom")
+	}
+
+	book, err := r.buildGradebook(classroom.ClassId, students, true)
+
+	if err != nil {
+		return apperr.Internal(err, "could not build grades")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":     "grades fetched",
+		"success":     true,
+		"assignments": book.Columns,
+		"categories":  dto.NewGradeCategories(book.Categories),
+		"data":        book.Rows[0],
+	})
+	return nil
+}
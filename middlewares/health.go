@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	stdcontext "context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/migrations"
+)
+
+// how long a readiness probe waits on the database
+const readinessTimeout = 2 * time.Second
+
+// liveness probe: the process is up and serving requests
+func (r *Repository) Healthz(context *fiber.Ctx) error {
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "alive",
+		"success": true,
+	})
+	return nil
+}
+
+// readiness probe: the database answers and its schema is current
+func (r *Repository) Readyz(context *fiber.Ctx) error {
+	ctx, cancel := stdcontext.WithTimeout(context.Context(), readinessTimeout)
+	defer cancel()
+
+	sqlDB, err := r.DB.DB()
+
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+
+	if err != nil {
+		return apperr.Unavailable(err, "database is unreachable")
+	}
+
+	pending, err := migrations.Pending(r.DB.WithContext(ctx))
+
+	if err != nil {
+		return apperr.Unavailable(err, "could not check migrations")
+	}
+
+	if len(pending) != 0 {
+		return apperr.Unavailable(nil, fmt.Sprintf("database schema is %d migrations behind", len(pending)))
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "ready",
+		"success": true,
+	})
+	return nil
+}
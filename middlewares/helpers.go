@@ -1,113 +1,111 @@
 package middlewares
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
 	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+	"github.com/swayanshu-2003/classroom-backend/storage"
 	"github.com/swayanshu-2003/classroom-backend/utils"
 
 	"gorm.io/gorm"
 )
 
 type Repository struct {
-	DB *gorm.DB
+	DB          *gorm.DB
+	TokenSecret []byte
+	Blobs       storage.BlobStore
+	Avatars     utils.AvatarProvider
+	Services    *services.Services
 }
 
-type picRes struct {
-	Results []Result `json:"results"`
+// servicesIn are the services working inside the transaction tx, for writes
+// that have to commit together with other work of the handler
+func (r *Repository) servicesIn(tx *gorm.DB) *services.Services {
+	return services.New(gormstore.New(tx), r.Avatars)
 }
 
-type Result struct {
-	Pictures Picture `json:"picture"`
-}
+// check if user is logged in or not by verifying the access token and its session
+func (r *Repository) IsAuthUser(context *fiber.Ctx) (bool, *models.Users) {
+	// already verified earlier in this request, e.g. by RequireClassPermission
+	if user, ok := context.Locals("user").(*models.Users); ok {
+		return true, user
+	}
 
-type Picture struct {
-	Large     string `json:"large"`
-	Medium    string `json:"medium"`
-	Thumbnail string `json:"thumbnail"`
-}
+	token := bearerToken(context)
 
-// check if user is logged in or not
-func (r *Repository) IsAuthUser(context *fiber.Ctx) (bool, *models.Users) {
-	userId := context.Get("authorization")
+	if len(token) == 0 {
+		return false, nil
+	}
+
+	claims, err := utils.ParseAccessToken(r.TokenSecret, token)
+	if err != nil {
+		return false, nil
+	}
 
-	if len(userId) == 0 {
+	session, err := r.Services.Sessions.Live(claims.SessionID, claims.Subject)
+	if err != nil || session == nil {
 		return false, nil
 	}
 
 	var user models.Users
-	err := r.DB.Where("uuid = ?", userId).First(&user).Error
+	err = r.DB.Where("uuid = ?", claims.Subject).First(&user).Error
 	if err != nil {
 		return false, nil
 	}
 
+	context.Locals("session_id", *session.ID)
+	context.Locals("user", &user)
+
 	return true, &user
 }
 
 /*------------------------------------------------ user helpers ------------------------------------------------------*/
 //register user
+type comingUserRegister struct {
+	Username *string `json:"username" validate:"required,min=3,max=32,username"`
+	Name     *string `json:"name" validate:"max=100"`
+	Password string  `json:"password" validate:"required,password"`
+}
+
 func (r *Repository) CreateUser(context *fiber.Ctx) error {
-	user := models.Users{}
+	incoming := comingUserRegister{}
 
-	err := context.BodyParser(&user)
+	err := parseBody(context, &incoming)
 
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 
-	fmt.Println(user)
-
-	uuid, _ := utils.GenerateUUid()
-
-	user.Uuid = &uuid
-
-	res, _ := http.Get("https://randomuser.me/api/?inc=picture&results=1")
-
-	defer res.Body.Close()
+	user, err := r.Services.Users.Register(context.Context(), services.NewUser(incoming))
 
-	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		log.Fatalf("Error reading response body: %v", err)
+		return err
 	}
 
-	var picRes picRes
-
-	// _ = json.NewDecoder(res.Body).Decode(&picRes)
+	tokens, err := r.issueSession(user)
 
-	err = json.Unmarshal(body, &picRes)
 	if err != nil {
-		log.Fatalf("Error unmarshalling JSON: %v", err)
-	}
-
-	user.ProfilePicture = &picRes.Results[0].Pictures.Large
-
-	fmt.Println(user)
-
-	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "request failed"})
 		return err
 	}
 
-	dbErr := r.DB.Create(&user).Error
-
-	if dbErr != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "database insertion failed"})
-		return dbErr
-	}
-
 	context.Status(http.StatusOK).JSON(
 		&fiber.Map{
 			"message":         "user created",
 			"success":         true,
-			"token":           user.Uuid,
+			"token":           tokens.AccessToken,
+			"access_token":    tokens.AccessToken,
+			"refresh_token":   tokens.RefreshToken,
+			"expires_at":      tokens.ExpiresAt,
 			"username":        user.Username,
 			"name":            user.Name,
 			"profile_picture": user.ProfilePicture,
@@ -120,60 +118,40 @@ func (r *Repository) CreateUser(context *fiber.Ctx) error {
 
 // login user
 type comingUserLogin struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 func (r *Repository) LoginUser(context *fiber.Ctx) error {
-	user := comingUserLogin{}
-	dbResUser := models.Users{}
+	incoming := comingUserLogin{}
 
-	// Log the incoming request body for debugging
-	body := context.Body()
-	log.Printf("Incoming request body: %s", body)
-
-	err := context.BodyParser(&user)
+	err := parseBody(context, &incoming)
 	if err != nil {
-		log.Printf("Error parsing body: %v", err)
-		return context.Status(http.StatusUnprocessableEntity).JSON(&fiber.Map{
-			"message": "request failed",
-			"error":   err.Error(),
-		})
+		return err
 	}
 
-	// Log the parsed user struct for debugging
-	log.Printf("Parsed user struct: %+v", user)
-	// if err != nil {
-	// 	context.Status(http.StatusUnprocessableEntity).JSON(
-	// 		&fiber.Map{"message": "request failed"})
-	// 	return err
-	// }
-
-	err = r.DB.Where("username = ?", user.Username).First(&dbResUser).Error
+	user, err := r.Services.Users.Login(incoming.Username, incoming.Password)
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "invalid username"})
 		return err
 	}
 
-	err = r.DB.Where("username = ? AND password >= ?", user.Username, user.Password).Find(&dbResUser).Error
+	tokens, err := r.issueSession(user)
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "invalid invalid password"})
 		return err
 	}
 
-	fmt.Printf("%+v", dbResUser)
-
 	context.Status(http.StatusOK).JSON(
 		&fiber.Map{
 			"success":         true,
-			"token":           dbResUser.Uuid,
-			"username":        dbResUser.Username,
-			"name":            dbResUser.Name,
-			"profile_picture": dbResUser.ProfilePicture,
+			"token":           tokens.AccessToken,
+			"access_token":    tokens.AccessToken,
+			"refresh_token":   tokens.RefreshToken,
+			"expires_at":      tokens.ExpiresAt,
+			"username":        user.Username,
+			"name":            user.Name,
+			"profile_picture": user.ProfilePicture,
 		},
 	)
 	return nil
@@ -181,318 +159,225 @@ func (r *Repository) LoginUser(context *fiber.Ctx) error {
 
 // get user data
 func (r *Repository) GetUserData(context *fiber.Ctx) error {
-	searchedUser := models.Users{}
-
-	checkLoggedInUser, _ := r.IsAuthUser(context)
+	checkLoggedInUser, user := r.IsAuthUser(context)
 
 	if !checkLoggedInUser {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "un-authorized"})
-		return nil
+		return apperr.Unauthenticated("un-authorized")
 	}
 
-	err := r.DB.Where("uuid = ?", context.Params("user_id")).First(&searchedUser).Error
+	searchedUser, err := r.Services.Users.Profile(context.Params("user_id"))
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "user not found"})
 		return err
 	}
 
+	// only the user themself gets the private view
+	if *searchedUser.Uuid == *user.Uuid {
+		context.Status(http.StatusOK).JSON(&fiber.Map{
+			"success": true,
+			"data":    dto.NewPrivateUser(*searchedUser),
+		})
+		return nil
+	}
+
 	context.Status(http.StatusOK).JSON(&fiber.Map{
 		"success": true,
-		"data":    searchedUser,
+		"data":    dto.NewPublicUser(*searchedUser),
 	})
 	return nil
 }
 
 /*------------------------------------------------ classroom helpers ------------------------------------------------------*/
-//create classroom
-func (r *Repository) CreateClassroom(context *fiber.Ctx) error {
-	classroom := models.Classroom{}
+type comingClassroom struct {
+	ClassName   *string `json:"class_name" validate:"required,max=100"`
+	Description *string `json:"description" validate:"max=2000"`
+	Shared      bool    `json:"shared"`
+}
+
+type comingClassroomEdit struct {
+	ClassName   *string `json:"class_name" validate:"min=1,max=100"`
+	Description *string `json:"description" validate:"max=2000"`
+	Shared      *bool   `json:"shared"`
+}
 
+// create classroom
+func (r *Repository) CreateClassroom(context *fiber.Ctx) error {
 	checkLoggedInUser, user := r.IsAuthUser(context)
 
 	if !checkLoggedInUser {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "un-authorized"})
-		return nil
+		return apperr.Unauthenticated("un-authorized")
 	}
 
-	err := context.BodyParser(&classroom)
+	incoming := comingClassroom{}
+
+	err := parseBody(context, &incoming)
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "request failed"})
 		return err
 	}
 
-	classroom.OwnerID = user.Uuid
-
-	classId := utils.GenerateClassroomId()
-
-	classroom.ClassId = classId
-
-	fmt.Println(classroom)
-
-	dbErr := r.DB.Create(&classroom).Error
-
-	if dbErr != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "database insertion failed"})
-		return dbErr
-	}
+	classroom, err := r.Services.Classrooms.Create(user, services.NewClassroom(incoming))
 
-	var collaborator models.ClassroomCollaborator
-
-	collaborator.ClassID = classId
-	collaborator.UserID = user.Uuid
-	collaborator.Role = "teacher"
-
-	dbErr = r.DB.Create(&collaborator).Error
-
-	if dbErr != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "database insertion failed"})
-		return dbErr
+	if err != nil {
+		return err
 	}
 
 	context.Status(http.StatusOK).JSON(&fiber.Map{
 		"message":   "classroom created",
 		"success":   true,
-		"classroom": classroom,
+		"classroom": dto.NewClassroom(*classroom),
 	})
 	return nil
 }
 
 // list all classrooms
 func (r *Repository) GetClassrooms(context *fiber.Ctx) error {
-
-	var Classrooms []models.Classroom
-
-	joinedClassroom := []models.ClassroomCollaborator{}
-	joinedTeacherClassroom := []models.ClassroomCollaborator{}
-
 	checkUserLoggedIn, user := r.IsAuthUser(context)
 
 	if !checkUserLoggedIn {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
+		return apperr.Unauthenticated("un-authorized")
 	}
 
-	err := r.DB.Preload("Collaborators").Where("owner_id = ? AND is_deleted = ?", user.Uuid, false).Find(&Classrooms).Error
-	fmt.Println(err)
-	// if err != nil {
-	// 	context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-	// 		"message": "could not get owned classrooms",
-	// 		"success": false,
-	// 	})
-	// 	return err
-	// }
-
-	err = r.DB.Preload("Classroom").Where("user_id = ? AND role = ? AND is_removed = ?", user.Uuid, "student", false).Find(&joinedClassroom).Error
-
-	// if err != nil {
-	// 	context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-	// 		"message": "could not get joined classrooms",
-	// 		"success": false,
-	// 	})
-	// 	return err
-	// }
-
-	err = r.DB.Preload("Classroom").Where("user_id = ? AND role = ? AND is_removed = ?", user.Uuid, "teacher", false).Find(&joinedTeacherClassroom).Error
-
-	// if err != nil {
-	// 	context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-	// 		"message": "could not get joined classrooms",
-	// 		"success": false,
-	// 	})
-	// 	return err
-	// }
-
-	context.Status(http.StatusOK).JSON(&fiber.Map{
-		"success":           true,
-		"own":               Classrooms,
-		"joined_as_student": joinedClassroom,
-		"joined_as_teacher": joinedTeacherClassroom,
-	})
-	return nil
-}
+	params, err := parseListParams(context, gormstore.ClassroomList)
+	if err != nil {
+		return err
+	}
 
-// list a single classrooms
-func (r *Repository) GetSingleClassroom(context *fiber.Ctx) error {
+	done, err := queryBool(context, "done")
+	if err != nil {
+		return err
+	}
 
-	var Classroom models.Classroom
+	filter := services.ClassroomFilter{Role: context.Query("role"), Done: done, Search: context.Query("q")}
 
-	checkUserLoggedIn, _ := r.IsAuthUser(context)
+	classrooms, roles, total, err := r.Services.Classrooms.List(user, filter, params.Page)
 
-	if !checkUserLoggedIn {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
+	if err != nil {
+		return err
 	}
 
-	classid := context.Params("class_id")
+	hasMore := len(classrooms) > params.Limit
+	if hasMore {
+		classrooms = classrooms[:params.Limit]
+	}
 
-	err := r.DB.Preload("Owner").Where("class_id = ? AND is_deleted = ?", classid, false).First(&Classroom).Error
-	fmt.Println(err)
+	var last models.Classroom
+	if len(classrooms) != 0 {
+		last = classrooms[len(classrooms)-1]
+	}
 
 	context.Status(http.StatusOK).JSON(&fiber.Map{
 		"success": true,
-		"data":    Classroom,
+		"data":    dto.NewMemberClassrooms(classrooms, roles),
+		"meta":    params.meta(total, hasMore, last.CreatedAt, stringValue(last.ClassId)),
 	})
 	return nil
 }
 
-// edit a class
-func (r *Repository) EditClassroom(context *fiber.Ctx) error {
-	classroom := models.Classroom{}
-
-	checkLoggedInUser, user := r.IsAuthUser(context)
+// list a single classrooms
+func (r *Repository) GetSingleClassroom(context *fiber.Ctx) error {
 
-	if !checkLoggedInUser {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{
-				"success": false,
-				"message": "Please Login"})
-		return nil
-	}
+	Classroom, role := classAccess(context)
 
-	err := context.BodyParser(&classroom)
+	err := r.Services.Classrooms.WithOwner(Classroom)
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"success": false, "message": "request failed"})
 		return err
 	}
 
-	class := models.Classroom{}
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"success": true,
+		"role":    role,
+		"data":    dto.NewClassroom(*Classroom),
+	})
+	return nil
+}
+
+// edit a class; ownership, join codes and archiving have their own endpoints
+func (r *Repository) EditClassroom(context *fiber.Ctx) error {
+	incoming := comingClassroomEdit{}
 
-	err = r.DB.Where("class_id = ?", context.Params("class_id")).First(&class).Error
+	err := parseBody(context, &incoming)
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"success": false, "message": "classroom not found"})
 		return err
 	}
 
-	if *class.OwnerID != *user.Uuid {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"success": false, "message": "un-authorized"})
-		return nil
-	}
+	class, _ := classAccess(context)
 
-	err = r.DB.Model(&class).Updates(classroom).Error
+	err = r.Services.Classrooms.Edit(class, services.ClassroomEdit(incoming))
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"success": false, "message": "database update failed"})
 		return err
 	}
 
 	context.Status(http.StatusOK).JSON(&fiber.Map{
 		"success": true,
 		"message": "classroom updated",
-		"data":    class,
+		"data":    dto.NewClassroom(*class),
 	})
 
 	return nil
 }
 
 // join classroom
+type comingJoin struct {
+	JoinCode string `json:"join_code" validate:"required,max=32"`
+}
+
 func (r *Repository) JoinClassroom(context *fiber.Ctx) error {
-	var collaborator models.ClassroomCollaborator
+	incoming := comingJoin{}
 
 	checkLoggedInUser, user := r.IsAuthUser(context)
 
 	if !checkLoggedInUser {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{
-				"success": false,
-				"message": "Please Login"})
-		return nil
+		return apperr.Unauthenticated("un-authorized")
 	}
 
-	err := context.BodyParser(&collaborator)
+	err := parseBody(context, &incoming)
 
 	if err != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"success": false, "message": "request failed"})
 		return err
 	}
-	foundData := []models.ClassroomCollaborator{}
-	err = r.DB.Where("class_id = $1 AND user_id = $2", collaborator.ClassID, user.Uuid).Find(&foundData).Error
 
-	if len(foundData) != 0 {
-		context.Status(http.StatusBadRequest).JSON(
-			&fiber.Map{"success": false, "message": "you are already a member of this classroom"})
+	collaborator, err := r.Services.Membership.Join(user, incoming.JoinCode)
+
+	if err != nil {
 		return err
 	}
 
-	collaborator.UserID = user.Uuid
-
-	dbErr := r.DB.Create(&collaborator).Error
-
-	if dbErr != nil {
-		context.Status(http.StatusUnprocessableEntity).JSON(
-			&fiber.Map{"message": "database insertion failed"})
-		return dbErr
+	if collaborator.Status == models.MembershipPending {
+		context.Status(http.StatusAccepted).JSON(&fiber.Map{
+			"message": "join request sent, a teacher has to approve it",
+			"success": true,
+			"data":    dto.NewCollaborator(*collaborator),
+		})
+		return nil
 	}
 
 	context.Status(http.StatusOK).JSON(&fiber.Map{
 		"message": "Successfully Enrolled",
 		"success": true,
-		"data":    collaborator,
+		"data":    dto.NewCollaborator(*collaborator),
 	})
 	return nil
 }
 
 // exit or remove from classroom
 func (r *Repository) ExitClassroom(context *fiber.Ctx) error {
-	checkUserLoggedIn, _ := r.IsAuthUser(context)
-
-	if !checkUserLoggedIn {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
-	}
+	_, user := r.IsAuthUser(context)
+	classroom, role := classAccess(context)
 
-	classId := context.Params("class_id")
-	userId := context.Params("user_id")
-
-	collaborator := models.ClassroomCollaborator{}
-
-	err := r.DB.Where("user_id = ? AND class_id = ?", userId, classId).First(&collaborator).Error
+	collaborator, err := r.Services.Membership.Remove(classroom, user, role, context.Params("user_id"))
 
 	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "member not found",
-			"success": false,
-		})
-		return err
-	}
-
-	err = r.DB.Model(&collaborator).Where("user_id = ? AND class_id = ?", userId, classId).Update("is_removed", true).Error
-
-	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "database update failed",
-			"success": false,
-		})
 		return err
 	}
 
 	context.Status(fiber.StatusOK).JSON(&fiber.Map{
 		"message": "Successfully Exited",
 		"success": true,
-		"data":    collaborator,
+		"data":    dto.NewCollaborator(*collaborator),
 	})
 
 	return nil
@@ -500,39 +385,32 @@ func (r *Repository) ExitClassroom(context *fiber.Ctx) error {
 
 // list allstudents
 func (r *Repository) ListAllMembers(context *fiber.Ctx) error {
-	clasroomStudents := []models.ClassroomCollaborator{}
-	clasroomTeachers := []models.ClassroomCollaborator{}
-	classDetails := models.Classroom{}
+	classDetails, _ := classAccess(context)
 
-	checkUserLoggedIn, _ := r.IsAuthUser(context)
-
-	if !checkUserLoggedIn {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
+	params, err := parseListParams(context, gormstore.MemberList)
+	if err != nil {
+		return err
 	}
 
-	err := r.DB.Where("class_id = ? ", context.Params("class_id")).First(&classDetails).Error
+	filter := services.MemberFilter{Role: context.Query("role"), Search: context.Query("q")}
+
+	members, total, err := r.Services.Membership.Members(classDetails, filter, params.Page)
 
 	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "could not get classroom",
-			"success": false,
-		})
 		return err
 	}
-	err = r.DB.Preload("User").Where("class_id = ? AND role = ? AND is_removed = ?", context.Params("class_id"), "student", false).Find(&clasroomStudents).Error
-	fmt.Println(err)
-	err = r.DB.Preload("User").Where("class_id = ? AND role = ? AND is_removed = ?", context.Params("class_id"), "teacher", false).Find(&clasroomTeachers).Error
+
+	hasMore := len(members) > params.Limit
+	if hasMore {
+		members = members[:params.Limit]
+	}
 
 	context.Status(fiber.StatusOK).JSON(&fiber.Map{
 		"owner_id": classDetails.OwnerID,
-		"message":  "students fetched",
+		"message":  "members fetched",
 		"success":  true,
-		"students": clasroomStudents,
-		"teachers": clasroomTeachers,
+		"data":     dto.NewCollaborators(members),
+		"meta":     params.meta(total, hasMore, time.Time{}, ""),
 	})
 
 	return nil
@@ -540,103 +418,125 @@ func (r *Repository) ListAllMembers(context *fiber.Ctx) error {
 
 /*-----------------------------------------------------assignment section----------------------------------------------------------------*/
 
+type comingAssignment struct {
+	ClassID     *string    `json:"class_id" validate:"required"`
+	Title       *string    `json:"title" validate:"required,max=200"`
+	Type        *string    `json:"type" validate:"oneof=assignment quiz question material"`
+	Description *string    `json:"description" validate:"max=10000"`
+	Link        *string    `json:"link" validate:"max=2048,url"`
+	Points      *float64   `json:"points" validate:"min=0"`
+	CategoryID  *uint      `json:"category_id"`
+	DueAt       *time.Time `json:"due_at"`
+	DueTimezone *string    `json:"due_timezone"`
+	CloseAt     *time.Time `json:"close_at"`
+	LatePenalty *float64   `json:"late_penalty"`
+	MaxPenalty  *float64   `json:"max_penalty"`
+	IsDraft     bool       `json:"is_draft"`
+	PublishAt   *time.Time `json:"publish_at"`
+}
+
+type comingAssignmentEdit struct {
+	Title       *string    `json:"title" validate:"min=1,max=200"`
+	Type        *string    `json:"type" validate:"oneof=assignment quiz question material"`
+	Description *string    `json:"description" validate:"max=10000"`
+	Link        *string    `json:"link" validate:"max=2048,url"`
+	Points      *float64   `json:"points" validate:"min=0"`
+	CategoryID  *uint      `json:"category_id"`
+	DueAt       *time.Time `json:"due_at"`
+	DueTimezone *string    `json:"due_timezone"`
+	CloseAt     *time.Time `json:"close_at"`
+	LatePenalty *float64   `json:"late_penalty"`
+	MaxPenalty  *float64   `json:"max_penalty"`
+	PublishAt   *time.Time `json:"publish_at"`
+}
+
 func (r *Repository) CreateAssignment(context *fiber.Ctx) error {
 	checkUserLoggedIn, user := r.IsAuthUser(context)
 
 	if !checkUserLoggedIn {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
+		return apperr.Unauthenticated("un-authorized")
 	}
 
-	incomingAssignment := models.Assignments{}
+	incoming := comingAssignment{}
+	headers := []*multipart.FileHeader{}
+
+	// a multipart request carries the assignment as JSON in the "assignment"
+	// field and its materials in "file", saved together with it
+	if isMultipart(context) {
+		err := parseFormJSON(context, "assignment", &incoming)
+
+		if err != nil {
+			return err
+		}
+
+		form, err := context.MultipartForm()
 
-	err := context.BodyParser(&incomingAssignment)
+		if err != nil {
+			return apperr.BadRequest("request body could not be parsed")
+		}
+
+		headers = form.File["file"]
+	} else {
+		err := parseBody(context, &incoming)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	attachments, err := r.inspectUploads(context, headers)
 
 	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "request failed",
-			"success": false,
-		})
 		return err
 	}
 
-	id, _ := utils.GenerateUUid()
+	var assignment *models.Assignments
+
+	err = storage.Transaction(r.DB, func(tx *gorm.DB) error {
+		var err error
 
-	incomingAssignment.ID = &id
-	incomingAssignment.AutherId = user.Uuid
+		// assignments go into classrooms the caller teaches that are not
+		// archived, which is checked before anything is stored
+		assignment, err = r.servicesIn(tx).Assignments.Create(user, services.NewAssignment(incoming), attachments)
+		if err != nil || len(attachments) == 0 {
+			return err
+		}
 
-	err = r.DB.Create(&incomingAssignment).Error
+		return r.storeBlobs(context, tx, headers, attachments)
+	})
 
 	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "database insertion failed",
-			"success": false,
-		})
-		return err
+		r.releaseUploads(context.Context(), attachments)
+
+		if failed := apperr.As(err); failed != nil {
+			return failed
+		}
+		return writeFailed(err, "database insertion failed")
 	}
 
 	context.Status(fiber.StatusOK).JSON(&fiber.Map{
 		"message": "assignment created",
 		"success": true,
-		"data":    incomingAssignment,
+		"data":    dto.NewAssignment(*assignment),
 	})
 
 	return nil
 }
 func (r *Repository) EditAssignment(context *fiber.Ctx) error {
-	checkUserLoggedIn, user := r.IsAuthUser(context)
+	_, user := r.IsAuthUser(context)
+	assignment := assignmentAccess(context)
 
-	if !checkUserLoggedIn {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
-	}
-
-	incomingAssignment := models.Assignments{}
+	incoming := comingAssignmentEdit{}
 
-	err := context.BodyParser(&incomingAssignment)
-
-	incomingAssignmentId := context.Params("id")
+	err := parseBody(context, &incoming)
 
 	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "request failed",
-			"success": false,
-		})
 		return err
 	}
 
-	dbResAssignment := models.Assignments{}
-
-	err = r.DB.Where("id = ?", incomingAssignmentId).First(&dbResAssignment).Error
-
-	if dbResAssignment.ID == nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "assignment not found",
-			"success": false,
-		})
-		return nil
-	}
-
-	if *dbResAssignment.AutherId != *user.Uuid {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
-	}
-	err = r.DB.Model(&dbResAssignment).Updates(&incomingAssignment).Error
+	err = r.Services.Assignments.Edit(assignment, user, services.AssignmentEdit(incoming))
 
 	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "database update failed",
-			"success": false,
-		})
 		return err
 	}
 
@@ -649,33 +549,51 @@ func (r *Repository) EditAssignment(context *fiber.Ctx) error {
 }
 
 func (r *Repository) GetAllAssignments(context *fiber.Ctx) error {
-	isUserLoggedIn, _ := r.IsAuthUser(context)
+	classroom, role := classAccess(context)
 
-	if !isUserLoggedIn {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "un-authorized",
-			"success": false,
-		})
-		return nil
+	params, err := parseListParams(context, gormstore.AssignmentList)
+	if err != nil {
+		return err
 	}
 
-	incomingClassId := context.Params("class_id")
+	draft, err := queryBool(context, "draft")
+	if err != nil {
+		return err
+	}
 
-	allAssignments := []models.Assignments{}
+	filter := services.AssignmentFilter{Draft: draft, Type: context.Query("type")}
 
-	err := r.DB.Preload("Classroom").Preload("CreatedBy").Where("class_id = ? AND is_deleted = ?", incomingClassId, false).Find(&allAssignments).Error
+	if category := context.Query("category_id"); len(category) != 0 {
+		categoryId, err := strconv.ParseUint(category, 10, 64)
+		if err != nil {
+			return apperr.BadRequest("category_id must be a number")
+		}
+		id := uint(categoryId)
+		filter.CategoryID = &id
+	}
+
+	// drafts and scheduled assignments stay hidden from students
+	allAssignments, total, err := r.Services.Assignments.List(classroom, role, filter, params.Page)
 
 	if err != nil {
-		context.Status(fiber.StatusBadRequest).JSON(&fiber.Map{
-			"message": "could not get assignments",
-			"success": false,
-		})
 		return err
 	}
+
+	hasMore := len(allAssignments) > params.Limit
+	if hasMore {
+		allAssignments = allAssignments[:params.Limit]
+	}
+
+	var last models.Assignments
+	if len(allAssignments) != 0 {
+		last = allAssignments[len(allAssignments)-1]
+	}
+
 	context.Status(fiber.StatusOK).JSON(&fiber.Map{
 		"message": "assignments fetched",
 		"success": true,
-		"data":    allAssignments,
+		"data":    dto.NewAssignments(allAssignments),
+		"meta":    params.meta(total, hasMore, last.CreatedAt, stringValue(last.ID)),
 	})
 
 	return nil
@@ -685,32 +603,8 @@ func (r *Repository) GetAllAssignments(context *fiber.Ctx) error {
 
 func (r *Repository) testMessage(context *fiber.Ctx) error {
 	userId := context.Get("authorization")
-	// userId := context.Get("test")
-	fmt.Println(userId)
-
-	res, _ := http.Get("https://randomuser.me/api/?inc=picture&results=1")
 
-	defer res.Body.Close()
-
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		log.Fatalf("Error reading response body: %v", err)
-	}
-
-	fmt.Println(string(body))
-
-	var picRes picRes
-
-	// _ = json.NewDecoder(res.Body).Decode(&picRes)
-
-	err = json.Unmarshal(body, &picRes)
-	if err != nil {
-		log.Fatalf("Error unmarshalling JSON: %v", err)
-	}
-
-	fmt.Println(picRes)
-
-	context.Status(http.StatusOK).JSON(&fiber.Map{"message": "test message", "user-id": userId, "data": picRes})
+	context.Status(http.StatusOK).JSON(&fiber.Map{"message": "test message", "user-id": userId})
 
 	return nil
 }
@@ -718,6 +612,9 @@ func (r *Repository) testMessage(context *fiber.Ctx) error {
 func (r *Repository) SetupRoutes(app *fiber.App) {
 	api := app.Group("/api/v1")
 
+	// every response carries an X-Request-ID, which errors repeat in their body
+	app.Use(requestid.New())
+
 	// Default middleware config allows all origins
 	app.Use(cors.New())
 
@@ -729,25 +626,102 @@ func (r *Repository) SetupRoutes(app *fiber.App) {
 		AllowCredentials: false, // Do not allow credentials
 	}))
 
+	/*---------------------probe routes----------------------*/
+	app.Get("/healthz", r.Healthz)
+	app.Get("/readyz", r.Readyz)
+
 	/*---------------------user routes----------------------*/
 	api.Post("/user/create", r.CreateUser)
 	api.Post("/user/login", r.LoginUser)
+	api.Post("/user/token/refresh", r.RefreshSession)
+	api.Post("/user/logout", r.Logout)
+	api.Post("/user/logout/all", r.LogoutAll)
+	api.Get("/user/sessions", r.ListSessions)
+	api.Delete("/user/sessions/:session_id", r.RevokeSession)
+	api.Post("/user/avatar", r.UploadProfilePicture)
+	api.Delete("/user/avatar", r.RemoveProfilePicture)
+	api.Get("/user/:user_id/avatar", r.GetProfilePicture)
 	api.Post("/user/:user_id", r.GetUserData)
 
 	/*---------------------classroom routes----------------------*/
 	api.Post("/classroom/create", r.CreateClassroom)
 	api.Get("/classrooms", r.GetClassrooms)
-	api.Get("/classroom/:class_id", r.GetSingleClassroom)
-	api.Patch("/classroom/edit/:class_id", r.EditClassroom)
+	api.Get("/classroom/:class_id", r.RequireClassPermission(services.PermViewClassroom), r.GetSingleClassroom)
+	api.Patch("/classroom/edit/:class_id", r.RequireClassPermission(services.PermEditClassroom), r.EditClassroom)
 	api.Post("/classroom/join", r.JoinClassroom)
-	api.Patch("/classroom/exit/:class_id/:user_id", r.ExitClassroom)
-	api.Get("/classroom/members/:class_id", r.ListAllMembers)
+	api.Get("/classroom/:class_id/invitations", r.RequireClassPermission(services.PermInviteStudent), r.ListClassInvitations)
+	api.Post("/classroom/:class_id/invitations", r.RequireClassPermission(services.PermInviteStudent), r.CreateInvitation)
+	api.Delete("/classroom/:class_id/invitations/:invitation_id", r.RequireClassPermission(services.PermInviteStudent), r.RevokeInvitation)
+	api.Get("/invitations", r.ListMyInvitations)
+	api.Get("/invitations/:token", r.GetInvitation)
+	api.Post("/invitations/:token/accept", r.AcceptInvitation)
+	api.Post("/invitations/:token/decline", r.DeclineInvitation)
+	api.Patch("/classroom/:class_id/join-mode", r.RequireClassPermission(services.PermEditClassroom), r.SetJoinMode)
+	api.Get("/classroom/:class_id/join-requests", r.RequireClassPermission(services.PermApproveMembers), r.ListJoinRequests)
+	api.Post("/classroom/:class_id/join-requests/approve", r.RequireClassPermission(services.PermApproveMembers), r.ApproveJoinRequests)
+	api.Post("/classroom/:class_id/join-requests/reject", r.RequireClassPermission(services.PermApproveMembers), r.RejectJoinRequests)
+	api.Get("/notifications", r.ListNotifications)
+	api.Post("/notifications/read", r.MarkAllNotificationsRead)
+	api.Post("/notifications/:notification_id/read", r.MarkNotificationRead)
+	api.Patch("/classroom/:class_id/join-code", r.RequireClassPermission(services.PermManageJoinCode), r.UpdateJoinCode)
+	api.Post("/classroom/:class_id/join-code/regenerate", r.RequireClassPermission(services.PermManageJoinCode), r.RegenerateJoinCode)
+	api.Patch("/classroom/exit/:class_id/:user_id", r.RequireClassPermission(services.PermExitClassroom), r.ExitClassroom)
+	api.Get("/classroom/members/:class_id", r.RequireClassPermission(services.PermViewMembers), r.ListAllMembers)
+	api.Post("/classroom/:class_id/archive", r.RequireClassPermission(services.PermArchiveClassroom), r.ArchiveClassroom)
+	api.Post("/classroom/:class_id/unarchive", r.RequireClassPermission(services.PermArchiveClassroom), r.UnarchiveClassroom)
+	api.Delete("/classroom/:class_id", r.RequireClassPermission(services.PermDeleteClassroom), r.DeleteClassroom)
+	api.Post("/classroom/:class_id/restore", r.RestoreClassroom)
+	api.Get("/classrooms/trash", r.ListTrash)
+	api.Post("/classroom/:class_id/transfer", r.RequireClassPermission(services.PermTransferOwnership), r.TransferOwnership)
+	api.Patch("/classroom/:class_id/members/:user_id/role", r.RequireClassPermission(services.PermChangeRoles), r.ChangeMemberRole)
+
+	/*-----------------------comment routes----------------------*/
+	comments := api.Group("/classroom/:class_id/comments")
+	comments.Get("/", r.RequireClassPermission(services.PermViewComments), r.ListComments)
+	comments.Post("/", r.RequireClassPermission(services.PermPostComment), r.CreateComment)
+	comments.Get("/:comment_id", r.RequireClassPermission(services.PermViewComments), r.GetComment)
+	comments.Get("/:comment_id/replies", r.RequireClassPermission(services.PermViewComments), r.ListCommentReplies)
+	comments.Get("/:comment_id/history", r.RequireClassPermission(services.PermViewComments), r.GetCommentHistory)
+	comments.Patch("/:comment_id", r.RequireClassPermission(services.PermPostComment), r.EditComment)
+	comments.Delete("/:comment_id", r.RequireClassPermission(services.PermPostComment), r.DeleteComment)
+	comments.Get("/:comment_id/attachments", r.RequireClassPermission(services.PermViewComments), r.ListCommentAttachments)
+	comments.Post("/:comment_id/attachments", r.RequireClassPermission(services.PermPostComment), r.UploadCommentAttachments)
 
 	/*-----------------------assignment routes----------------------*/
 
 	api.Post("/assignment/create", r.CreateAssignment)
-	api.Patch("/assignment/:id/edit", r.EditAssignment)
-	api.Get("/assignments/:class_id", r.GetAllAssignments)
+	api.Patch("/assignment/:id/edit", r.RequireAssignmentPermission(services.PermManageAssignments), r.EditAssignment)
+	api.Get("/assignments/:class_id", r.RequireClassPermission(services.PermViewAssignments), r.GetAllAssignments)
+	api.Post("/assignment/:id/publish", r.RequireAssignmentPermission(services.PermManageAssignments), r.PublishAssignment)
+	api.Post("/assignment/:id/unpublish", r.RequireAssignmentPermission(services.PermManageAssignments), r.UnpublishAssignment)
+
+	/*-----------------------submission routes----------------------*/
+	api.Post("/assignment/:id/turn-in", r.RequireAssignmentPermission(services.PermSubmitWork), r.TurnInSubmission)
+	api.Post("/assignment/:id/unsubmit", r.RequireAssignmentPermission(services.PermSubmitWork), r.UnsubmitSubmission)
+	api.Get("/assignment/:id/submission", r.RequireAssignmentPermission(services.PermSubmitWork), r.GetMySubmission)
+	api.Get("/assignment/:id/submissions", r.RequireAssignmentPermission(services.PermReviewWork), r.ListSubmissions)
+	api.Post("/assignment/:id/submissions/:student_id/return", r.RequireAssignmentPermission(services.PermReviewWork), r.ReturnSubmission)
+
+	/*-----------------------grading routes----------------------*/
+	api.Get("/assignment/:id/rubric", r.RequireAssignmentPermission(services.PermViewAssignments), r.GetRubric)
+	api.Put("/assignment/:id/rubric", r.RequireAssignmentPermission(services.PermManageAssignments), r.SetRubric)
+	api.Put("/assignment/:id/submissions/:student_id/grade", r.RequireAssignmentPermission(services.PermReviewWork), r.GradeSubmission)
+	api.Get("/classroom/:class_id/grade-categories", r.RequireClassPermission(services.PermViewClassroom), r.ListGradeCategories)
+	api.Post("/classroom/:class_id/grade-categories", r.RequireClassPermission(services.PermManageGrades), r.CreateGradeCategory)
+	api.Patch("/classroom/:class_id/grade-categories/:category_id", r.RequireClassPermission(services.PermManageGrades), r.EditGradeCategory)
+	api.Delete("/classroom/:class_id/grade-categories/:category_id", r.RequireClassPermission(services.PermManageGrades), r.DeleteGradeCategory)
+	api.Get("/classroom/:class_id/gradebook", r.RequireClassPermission(services.PermReviewWork), r.GetGradebook)
+	api.Get("/classroom/:class_id/grades", r.RequireClassPermission(services.PermSubmitWork), r.GetMyGrades)
+
+	/*-----------------------attachment routes----------------------*/
+	api.Get("/assignment/:id/attachments", r.RequireAssignmentPermission(services.PermViewAssignments), r.ListAssignmentAttachments)
+	api.Post("/assignment/:id/attachments", r.RequireAssignmentPermission(services.PermManageAssignments), r.UploadAssignmentAttachments)
+	api.Get("/assignment/:id/submission/attachments", r.RequireAssignmentPermission(services.PermSubmitWork), r.ListMySubmissionAttachments)
+	api.Post("/assignment/:id/submission/attachments", r.RequireAssignmentPermission(services.PermSubmitWork), r.UploadSubmissionAttachments)
+	api.Get("/assignment/:id/submissions/:student_id/attachments", r.RequireAssignmentPermission(services.PermReviewWork), r.ListStudentSubmissionAttachments)
+	api.Get("/attachments/:attachment_id/url", r.GetAttachmentURL)
+	api.Delete("/attachments/:attachment_id", r.DeleteAttachment)
+	api.Get("/files/*", r.DownloadFile)
 
 	api.Get("/test", r.testMessage)
 }
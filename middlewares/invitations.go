@@ -0,0 +1,205 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+)
+
+type comingInvitation struct {
+	Role      string     `json:"role" validate:"oneof=student teacher"`
+	Username  *string    `json:"username" validate:"min=1,max=32"`
+	MaxUses   *int       `json:"max_uses" validate:"min=1,max=1000"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// respond with a page of invitations
+func pageInvitations(context *fiber.Ctx, params *listParams, invitations []models.Invitation, total int64) error {
+	hasMore := len(invitations) > params.Limit
+	if hasMore {
+		invitations = invitations[:params.Limit]
+	}
+
+	var last models.Invitation
+	if len(invitations) != 0 {
+		last = invitations[len(invitations)-1]
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "invitations fetched",
+		"success": true,
+		"data":    dto.NewInvitations(invitations),
+		"meta":    params.meta(total, hasMore, last.CreatedAt, stringValue(last.ID)),
+	})
+	return nil
+}
+
+/*------------------------------------------------ teacher side ------------------------------------------------------*/
+
+// invite people into a classroom, either personally by username or with a shareable link
+func (r *Repository) CreateInvitation(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	classroom, role := classAccess(context)
+
+	incoming := comingInvitation{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	invitation, err := r.Services.Invitations.Create(classroom, user, role, services.NewInvitation(incoming))
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "invitation created",
+		"success": true,
+		"data":    dto.NewInvitation(*invitation),
+	})
+	return nil
+}
+
+// list the invitations of a classroom that have not been revoked, newest first by default
+func (r *Repository) ListClassInvitations(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	params, err := parseListParams(context, gormstore.InvitationList)
+	if err != nil {
+		return err
+	}
+
+	invitations, total, err := r.Services.Invitations.ForClassroom(classroom, params.Page)
+
+	if err != nil {
+		return err
+	}
+
+	return pageInvitations(context, params, invitations, total)
+}
+
+// revoke an invitation so it can no longer be accepted
+func (r *Repository) RevokeInvitation(context *fiber.Ctx) error {
+	classroom, role := classAccess(context)
+
+	invitation, err := r.Services.Invitations.Revoke(classroom, role, context.Params("invitation_id"))
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "invitation revoked",
+		"success": true,
+		"data":    dto.NewInvitation(*invitation),
+	})
+	return nil
+}
+
+/*------------------------------------------------ invitee side ------------------------------------------------------*/
+
+// the personal invitations still waiting on the caller
+func (r *Repository) ListMyInvitations(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	params, err := parseListParams(context, gormstore.InvitationList)
+	if err != nil {
+		return err
+	}
+
+	invitations, total, err := r.Services.Invitations.Pending(user, params.Page)
+
+	if err != nil {
+		return err
+	}
+
+	return pageInvitations(context, params, invitations, total)
+}
+
+// look at an invitation before accepting it
+func (r *Repository) GetInvitation(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	invitation, err := r.Services.Invitations.Find(user, context.Params("token"))
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "invitation fetched",
+		"success": true,
+		"data":    dto.NewInvitation(*invitation),
+	})
+	return nil
+}
+
+// join the invitation's classroom with the invitation's role
+func (r *Repository) AcceptInvitation(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	invitation, err := r.Services.Invitations.Find(user, context.Params("token"))
+	if err != nil {
+		return err
+	}
+
+	collaborator, err := r.Services.Membership.AcceptInvitation(user, invitation)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "Successfully Enrolled",
+		"success": true,
+		"data":    dto.NewCollaborator(*collaborator),
+	})
+	return nil
+}
+
+// turn down a personal invitation
+func (r *Repository) DeclineInvitation(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	invitation, err := r.Services.Invitations.Find(user, context.Params("token"))
+	if err != nil {
+		return err
+	}
+
+	err = r.Services.Invitations.Decline(invitation)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "invitation declined",
+		"success": true,
+		"data":    dto.NewInvitation(*invitation),
+	})
+	return nil
+}
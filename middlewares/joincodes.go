@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+type comingJoinCode struct {
+	Enabled   *bool      `json:"enabled"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	NoExpiry  bool       `json:"no_expiry"`
+}
+
+// turn a classroom's join code on or off, or change when it expires
+func (r *Repository) UpdateJoinCode(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	incoming := comingJoinCode{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	err = r.Services.Classrooms.EditJoinCode(classroom, services.JoinCodeEdit(incoming))
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "join code updated",
+		"success": true,
+		"data":    dto.NewClassroom(*classroom),
+	})
+	return nil
+}
+
+// replace a classroom's join code, so the old one stops working
+func (r *Repository) RegenerateJoinCode(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	err := r.Services.Classrooms.RegenerateJoinCode(classroom)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "join code regenerated",
+		"success": true,
+		"data":    dto.NewClassroom(*classroom),
+	})
+	return nil
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+)
+
+type comingJoinMode struct {
+	JoinMode string `json:"join_mode" validate:"required,oneof=open approval invite_only closed"`
+}
+
+type comingJoinDecision struct {
+	UserIDs []string `json:"user_ids" validate:"max=500"`
+	All     bool     `json:"all"`
+}
+
+// choose how people get into a classroom
+func (r *Repository) SetJoinMode(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	incoming := comingJoinMode{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	err = r.Services.Classrooms.SetJoinMode(classroom, incoming.JoinMode)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "join mode updated",
+		"success": true,
+		"data":    dto.NewClassroom(*classroom),
+	})
+	return nil
+}
+
+// list the requests waiting on a teacher, oldest first by default
+func (r *Repository) ListJoinRequests(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	params, err := parseListParams(context, gormstore.JoinRequestList)
+	if err != nil {
+		return err
+	}
+
+	requests, total, err := r.Services.Membership.JoinRequests(classroom, params.Page)
+
+	if err != nil {
+		return err
+	}
+
+	hasMore := len(requests) > params.Limit
+	if hasMore {
+		requests = requests[:params.Limit]
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "join requests fetched",
+		"success": true,
+		"data":    dto.NewCollaborators(requests),
+		"meta":    params.meta(total, hasMore, time.Time{}, ""),
+	})
+	return nil
+}
+
+// approve pending join requests, either the listed ones or all of them
+func (r *Repository) ApproveJoinRequests(context *fiber.Ctx) error {
+	return r.decideJoinRequests(context, true)
+}
+
+// reject pending join requests, either the listed ones or all of them
+func (r *Repository) RejectJoinRequests(context *fiber.Ctx) error {
+	return r.decideJoinRequests(context, false)
+}
+
+func (r *Repository) decideJoinRequests(context *fiber.Ctx, approve bool) error {
+	_, user := r.IsAuthUser(context)
+	classroom, _ := classAccess(context)
+
+	incoming := comingJoinDecision{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	if !incoming.All && len(incoming.UserIDs) == 0 {
+		return invalidField("user_ids", "is required unless all is true")
+	}
+
+	userIds := incoming.UserIDs
+	if incoming.All {
+		userIds = nil
+	}
+
+	decided, err := r.Services.Membership.DecideJoinRequests(classroom, user, userIds, approve)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "join requests updated",
+		"success": true,
+		"updated": len(decided),
+		"data":    dto.NewCollaborators(decided),
+	})
+	return nil
+}
@@ -0,0 +1,164 @@
+package middlewares
+
+import (
+	stdcontext "context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+)
+
+// archive a classroom; it stays visible but read-only
+func (r *Repository) ArchiveClassroom(context *fiber.Ctx) error {
+	return r.setArchived(context, true)
+}
+
+// make an archived classroom editable again
+func (r *Repository) UnarchiveClassroom(context *fiber.Ctx) error {
+	return r.setArchived(context, false)
+}
+
+func (r *Repository) setArchived(context *fiber.Ctx, archived bool) error {
+	classroom, _ := classAccess(context)
+
+	err := r.Services.Classrooms.SetArchived(classroom, archived)
+
+	if err != nil {
+		return err
+	}
+
+	message := "classroom unarchived"
+	if archived {
+		message = "classroom archived"
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": message,
+		"success": true,
+		"data":    dto.NewClassroom(*classroom),
+	})
+	return nil
+}
+
+// move a classroom to the owner's trash; it is purged after the retention period
+func (r *Repository) DeleteClassroom(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	err := r.Services.Classrooms.Trash(classroom)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":  "classroom moved to trash",
+		"success":  true,
+		"purge_at": classroom.TrashedAt.Add(services.TrashRetention),
+		"data":     dto.NewClassroom(*classroom),
+	})
+	return nil
+}
+
+// list the caller's classrooms in the trash
+func (r *Repository) ListTrash(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	params, err := parseListParams(context, gormstore.TrashList)
+	if err != nil {
+		return err
+	}
+
+	classrooms, total, err := r.Services.Classrooms.ListTrash(user, params.Page)
+
+	if err != nil {
+		return err
+	}
+
+	hasMore := len(classrooms) > params.Limit
+	if hasMore {
+		classrooms = classrooms[:params.Limit]
+	}
+
+	var last models.Classroom
+	if len(classrooms) != 0 {
+		last = classrooms[len(classrooms)-1]
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":        "trash fetched",
+		"success":        true,
+		"retention_days": int(services.TrashRetention.Hours() / 24),
+		"data":           dto.NewClassrooms(classrooms),
+		"meta":           params.meta(total, hasMore, last.CreatedAt, stringValue(last.ClassId)),
+	})
+	return nil
+}
+
+// take a classroom back out of the trash
+func (r *Repository) RestoreClassroom(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	classroom, err := r.Services.Classrooms.Restore(user, context.Params("class_id"))
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "classroom restored",
+		"success": true,
+		"data":    dto.NewClassroom(*classroom),
+	})
+	return nil
+}
+
+// PurgeTrash permanently deletes classrooms that have been in the trash for
+// longer than the retention period, with everything that belongs to them.
+// It returns how many classrooms were purged.
+func (r *Repository) PurgeTrash(ctx stdcontext.Context, now time.Time) (int, error) {
+	purged, hashes, err := r.servicesIn(r.DB.WithContext(ctx)).Classrooms.PurgeTrash(now)
+
+	// what was purged before a failure still leaves its blobs behind
+	if r.Blobs != nil {
+		for _, hash := range hashes {
+			r.releaseBlob(ctx, hash)
+		}
+	}
+
+	return purged, err
+}
+
+// PurgeTrashEvery runs PurgeTrash on an interval until ctx is cancelled
+func (r *Repository) PurgeTrashEvery(ctx stdcontext.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := r.PurgeTrash(ctx, time.Now())
+		if err != nil {
+			log.Printf("could not purge trashed classrooms: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d trashed classrooms", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+)
+
+// list the caller's notifications, newest first by default; ?unread=true leaves out read ones
+func (r *Repository) ListNotifications(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	params, err := parseListParams(context, gormstore.NotificationList)
+	if err != nil {
+		return err
+	}
+
+	notifications, total, unread, err := r.Services.Notifications.List(user, context.QueryBool("unread"), params.Page)
+
+	if err != nil {
+		return err
+	}
+
+	hasMore := len(notifications) > params.Limit
+	if hasMore {
+		notifications = notifications[:params.Limit]
+	}
+
+	var last models.Notification
+	if len(notifications) != 0 {
+		last = notifications[len(notifications)-1]
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "notifications fetched",
+		"success": true,
+		"unread":  unread,
+		"data":    dto.NewNotifications(notifications),
+		"meta":    params.meta(total, hasMore, last.CreatedAt, strconv.FormatUint(uint64(last.ID), 10)),
+	})
+	return nil
+}
+
+// mark one of the caller's notifications as read
+func (r *Repository) MarkNotificationRead(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	err := r.Services.Notifications.MarkRead(user, context.Params("notification_id"))
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "notification marked as read",
+		"success": true,
+	})
+	return nil
+}
+
+// mark all of the caller's notifications as read
+func (r *Repository) MarkAllNotificationsRead(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	updated, err := r.Services.Notifications.MarkAllRead(user)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "notifications marked as read",
+		"success": true,
+		"updated": updated,
+	})
+	return nil
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+)
+
+type comingTransfer struct {
+	UserID string `json:"user_id" validate:"required"`
+}
+
+type comingRole struct {
+	Role string `json:"role" validate:"required,oneof=student teacher"`
+}
+
+// hand the classroom to another teacher; the old owner stays on as a teacher
+func (r *Repository) TransferOwnership(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	classroom, _ := classAccess(context)
+
+	incoming := comingTransfer{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	err = r.Services.Membership.TransferOwnership(classroom, user, incoming.UserID)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "ownership transferred",
+		"success": true,
+		"data":    dto.NewClassroom(*classroom),
+	})
+	return nil
+}
+
+// promote a student to teacher or demote a teacher to student
+func (r *Repository) ChangeMemberRole(context *fiber.Ctx) error {
+	classroom, _ := classAccess(context)
+
+	incoming := comingRole{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	member, err := r.Services.Membership.ChangeRole(classroom, context.Params("user_id"), incoming.Role)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "role updated",
+		"success": true,
+		"data":    dto.NewCollaborator(*member),
+	})
+	return nil
+}
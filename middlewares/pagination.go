@@ -0,0 +1,173 @@
+package middlewares
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageLimit reads ?limit= clamped to a sane range
+func pageLimit(context *fiber.Ctx) int {
+	limit := context.QueryInt("limit", defaultPageLimit)
+
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+// encodeCursor builds the opaque cursor for the row a page ended on
+func encodeCursor(createdAt time.Time, id string) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(cursor string) (*services.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &services.Cursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: parts[1]}, nil
+}
+
+// listSpec describes what a list endpoint can be sorted by
+type listSpec = gormstore.ListSpec
+
+// listParams is the shared query string convention of list endpoints:
+// ?limit= with either ?page= or ?cursor=, and ?sort= naming a field, with a
+// leading "-" for descending order. Filters are read by each endpoint.
+type listParams struct {
+	services.Page
+	spec   listSpec
+	Number int
+}
+
+// parseListParams reads the list convention, failing with a bad request when
+// it does not fit the endpoint
+func parseListParams(context *fiber.Ctx, spec listSpec) (*listParams, error) {
+	params := &listParams{
+		spec:   spec,
+		Number: context.QueryInt("page", 1),
+	}
+	params.Limit = pageLimit(context)
+
+	if params.Number < 1 {
+		params.Number = 1
+	}
+
+	sortName := context.Query("sort", spec.DefaultSort)
+	if strings.HasPrefix(sortName, "-") {
+		params.Desc = true
+		sortName = sortName[1:]
+	}
+
+	if _, ok := spec.Sorts[sortName]; !ok {
+		names := make([]string, 0, len(spec.Sorts))
+		for name := range spec.Sorts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return nil, apperr.BadRequest("sort must be one of " + strings.Join(names, ", ") + ", optionally prefixed with -")
+	}
+	params.Sort = sortName
+
+	if raw := context.Query("cursor"); len(raw) != 0 {
+		cursor, err := decodeCursor(raw)
+		if err == nil && spec.NumericID {
+			_, err = strconv.ParseUint(cursor.ID, 10, 64)
+		}
+
+		// cursors walk (created_at, id), so they only make sense in that order
+		if err != nil || sortName != "created_at" {
+			return nil, apperr.BadRequest("invalid cursor, cursors need sort=created_at or sort=-created_at")
+		}
+		params.After = cursor
+	} else {
+		params.Offset = (params.Number - 1) * params.Limit
+	}
+
+	return params, nil
+}
+
+// apply orders and limits the query to the requested page, fetching one row
+// past the limit so the caller can tell whether another page exists
+func (p *listParams) apply(query *gorm.DB) *gorm.DB {
+	return gormstore.Paginate(query, p.spec, p.Page)
+}
+
+// meta is the pagination part of a list response. createdAt and id describe
+// the last row returned and are only used to build the next cursor.
+func (p *listParams) meta(total int64, hasMore bool, createdAt time.Time, id string) fiber.Map {
+	meta := fiber.Map{
+		"total":       total,
+		"limit":       p.Limit,
+		"has_more":    hasMore,
+		"next_cursor": nil,
+		"next_page":   nil,
+	}
+
+	if p.After == nil {
+		meta["page"] = p.Number
+		if hasMore {
+			meta["next_page"] = p.Number + 1
+		}
+	}
+
+	if hasMore && p.Sort == "created_at" {
+		meta["next_cursor"] = encodeCursor(createdAt, id)
+	}
+
+	return meta
+}
+
+// queryBool reads an optional true/false filter, nil when it is not given
+func queryBool(context *fiber.Ctx, name string) (*bool, error) {
+	raw := context.Query(name)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, apperr.BadRequest(name + " must be true or false")
+	}
+	return &value, nil
+}
+
+// stringValue dereferences an optional id, empty when it is nil
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,195 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services/gormstore"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+const accessTokenTTL = 15 * time.Minute
+
+type sessionTokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type comingRefreshToken struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// bearerToken extracts the token from the authorization header, with or without the Bearer prefix
+func bearerToken(context *fiber.Ctx) string {
+	header := strings.TrimSpace(context.Get("authorization"))
+
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
+
+// create a new session for the user and sign its first token pair
+func (r *Repository) issueSession(user *models.Users) (*sessionTokens, error) {
+	session, refreshToken, err := r.Services.Sessions.Open(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.signSession(session, refreshToken)
+}
+
+// sign an access token for a session and pair it with the session's refresh token
+func (r *Repository) signSession(session *models.Session, refreshToken string) (*sessionTokens, error) {
+	accessToken, expiresAt, err := utils.GenerateAccessToken(r.TokenSecret, *session.UserID, *session.ID, accessTokenTTL)
+	if err != nil {
+		return nil, apperr.Internal(err, "could not sign access token")
+	}
+
+	return &sessionTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// exchange a refresh token for a new token pair, rotating the refresh token
+func (r *Repository) RefreshSession(context *fiber.Ctx) error {
+	incoming := comingRefreshToken{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	session, refreshToken, err := r.Services.Sessions.Refresh(incoming.RefreshToken)
+
+	if err != nil {
+		return err
+	}
+
+	tokens, err := r.signSession(session, refreshToken)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"success":       true,
+		"token":         tokens.AccessToken,
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+	return nil
+}
+
+// revoke the session the caller is authenticated with
+func (r *Repository) Logout(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	sessionId, _ := context.Locals("session_id").(string)
+
+	err := r.Services.Sessions.Revoke(user, sessionId)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"success": true,
+		"message": "logged out",
+	})
+	return nil
+}
+
+// revoke every session of the caller, e.g. after a token leak
+func (r *Repository) LogoutAll(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	err := r.Services.Sessions.RevokeAll(user)
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"success": true,
+		"message": "logged out of all sessions",
+	})
+	return nil
+}
+
+// list the caller's live sessions
+func (r *Repository) ListSessions(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	params, err := parseListParams(context, gormstore.SessionList)
+	if err != nil {
+		return err
+	}
+
+	sessions, total, err := r.Services.Sessions.List(user, params.Page)
+
+	if err != nil {
+		return err
+	}
+
+	hasMore := len(sessions) > params.Limit
+	if hasMore {
+		sessions = sessions[:params.Limit]
+	}
+
+	var last models.Session
+	if len(sessions) != 0 {
+		last = sessions[len(sessions)-1]
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"success": true,
+		"current": context.Locals("session_id"),
+		"data":    dto.NewSessions(sessions),
+		"meta":    params.meta(total, hasMore, last.CreatedAt, stringValue(last.ID)),
+	})
+	return nil
+}
+
+// revoke one of the caller's sessions by id
+func (r *Repository) RevokeSession(context *fiber.Ctx) error {
+	checkLoggedInUser, user := r.IsAuthUser(context)
+
+	if !checkLoggedInUser {
+		return apperr.Unauthenticated("un-authorized")
+	}
+
+	err := r.Services.Sessions.Revoke(user, context.Params("session_id"))
+
+	if err != nil {
+		return err
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"success": true,
+		"message": "session revoked",
+	})
+	return nil
+}
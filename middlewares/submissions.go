@@ -0,0 +1,373 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/dto"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"gorm.io/gorm"
+)
+
+type comingSubmission struct {
+	Link *string `json:"link" validate:"max=2048,url"`
+	Note *string `json:"note" validate:"max=5000"`
+}
+
+// isTurnedIn reports whether the work is currently handed in
+func isTurnedIn(state string) bool {
+	return state == models.SubmissionTurnedIn || state == models.SubmissionLate
+}
+
+// find a student's submission for an assignment, nil when there is none yet
+func (r *Repository) findSubmission(assignmentId string, studentId string) (*models.Submission, error) {
+	submission := models.Submission{}
+
+	err := r.DB.Where("assignment_id = ? AND student_id = ?", assignmentId, studentId).First(&submission).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// write the columns an action owns to a submission, creating it when it is
+// new; an existing one is only changed while it is still in one of the given
+// states, so an action racing another one fails instead of overwriting it
+func writeSubmission(tx *gorm.DB, submission *models.Submission, isNew bool, columns map[string]interface{}, states ...string) error {
+	if isNew {
+		return tx.Omit("RubricScores").Create(submission).Error
+	}
+
+	query := tx.Model(&models.Submission{}).Where("id = ?", submission.ID)
+	if len(states) != 0 {
+		query = query.Where("state IN ?", states)
+	}
+
+	result := query.Updates(columns)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperr.Conflict("work was changed meanwhile, reload it and try again")
+	}
+	return nil
+}
+
+// turn in work for an assignment
+func (r *Repository) TurnInSubmission(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	assignment := assignmentAccess(context)
+
+	incoming := comingSubmission{}
+
+	err := parseBody(context, &incoming)
+
+	if err != nil {
+		return err
+	}
+
+	submission, err := r.findSubmission(*assignment.ID, *user.Uuid)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submission")
+	}
+
+	now := time.Now()
+
+	if assignment.IsClosed(now) {
+		return apperr.Forbidden("this assignment no longer accepts work")
+	}
+
+	if isTurnedIn(assignment.SubmissionState(submission, now)) {
+		return apperr.Conflict("work is already turned in, unsubmit it first")
+	}
+
+	isNew := submission == nil
+	if isNew {
+		id, _ := utils.GenerateUUid()
+
+		submission = &models.Submission{
+			ID:           &id,
+			AssignmentID: assignment.ID,
+			StudentID:    user.Uuid,
+			ClassID:      assignment.ClassID,
+		}
+	}
+
+	previous := submission.State
+	submission.State = assignment.TurnInState(now)
+	submission.Link = incoming.Link
+	submission.Note = incoming.Note
+	submission.TurnedInAt = &now
+
+	err = writeSubmission(r.DB, submission, isNew, map[string]interface{}{
+		"state":        submission.State,
+		"link":         submission.Link,
+		"note":         submission.Note,
+		"turned_in_at": submission.TurnedInAt,
+	}, previous)
+
+	if err != nil {
+		if failed := apperr.As(err); failed != nil {
+			return failed
+		}
+		return writeFailed(err, "database update failed")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "work turned in",
+		"success": true,
+		"data":    dto.NewStudentSubmission(*assignment, *submission),
+	})
+	return nil
+}
+
+// take back turned in work so it can be changed
+func (r *Repository) UnsubmitSubmission(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	assignment := assignmentAccess(context)
+
+	submission, err := r.findSubmission(*assignment.ID, *user.Uuid)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submission")
+	}
+
+	now := time.Now()
+
+	if !isTurnedIn(assignment.SubmissionState(submission, now)) {
+		return apperr.Conflict("work is not turned in")
+	}
+
+	// work taken back after the cutoff could never be turned in again
+	if assignment.IsClosed(now) {
+		return apperr.Forbidden("this assignment no longer accepts work")
+	}
+
+	err = writeSubmission(r.DB, submission, false, map[string]interface{}{
+		"state":        models.SubmissionAssigned,
+		"turned_in_at": nil,
+	}, models.SubmissionTurnedIn, models.SubmissionLate)
+
+	if err != nil {
+		if failed := apperr.As(err); failed != nil {
+			return failed
+		}
+		return writeFailed(err, "database update failed")
+	}
+
+	submission.State = models.SubmissionAssigned
+	submission.TurnedInAt = nil
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "work unsubmitted",
+		"success": true,
+		"data":    dto.NewStudentSubmission(*assignment, *submission),
+	})
+	return nil
+}
+
+// get the caller's own submission for an assignment
+func (r *Repository) GetMySubmission(context *fiber.Ctx) error {
+	_, user := r.IsAuthUser(context)
+	assignment := assignmentAccess(context)
+
+	submission, err := r.findSubmission(*assignment.ID, *user.Uuid)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submission")
+	}
+
+	var data *dto.Submission
+	if submission != nil {
+		res := dto.NewStudentSubmission(*assignment, *submission)
+		data = &res
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "submission fetched",
+		"success": true,
+		"state":   assignment.SubmissionState(submission, time.Now()),
+		"data":    data,
+	})
+	return nil
+}
+
+// studentList pages the students of a classroom
+var studentList = listSpec{
+	Table:    "classroom_collaborators",
+	IDColumn: "user_id",
+	Sorts: map[string]string{
+		"name":     "users.name",
+		"username": "users.username",
+	},
+	DefaultSort: "name",
+}
+
+// classStudents queries the active students of a classroom, joined with their
+// users so they can be sorted by studentList
+func (r *Repository) classStudents(classId *string) *gorm.DB {
+	return r.DB.Model(&models.ClassroomCollaborator{}).
+		Joins("JOIN users ON users.uuid = classroom_collaborators.user_id").
+		Where("classroom_collaborators.is_removed = ? AND classroom_collaborators.status = ?", false, models.MembershipActive).
+		Where("classroom_collaborators.class_id = ? AND classroom_collaborators.role = ?", classId, services.RoleStudent)
+}
+
+// teacher view: every student in the class with the state of their work
+func (r *Repository) ListSubmissions(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+
+	params, err := parseListParams(context, studentList)
+	if err != nil {
+		return err
+	}
+
+	query := r.classStudents(assignment.ClassID)
+
+	// the summary covers every student, not just the page
+	studentIds := []string{}
+
+	err = query.Session(&gorm.Session{}).Pluck("classroom_collaborators.user_id", &studentIds).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get students")
+	}
+
+	submissions := []models.Submission{}
+
+	err = r.DB.Where("assignment_id = ?", assignment.ID).Find(&submissions).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submissions")
+	}
+
+	byStudent := map[string]*models.Submission{}
+	for i := range submissions {
+		byStudent[*submissions[i].StudentID] = &submissions[i]
+	}
+
+	summary := map[string]int{
+		models.SubmissionAssigned: 0,
+		models.SubmissionTurnedIn: 0,
+		models.SubmissionReturned: 0,
+		models.SubmissionLate:     0,
+		models.SubmissionMissing:  0,
+	}
+
+	now := time.Now()
+
+	for _, studentId := range studentIds {
+		summary[assignment.SubmissionState(byStudent[studentId], now)]++
+	}
+
+	students := []models.ClassroomCollaborator{}
+
+	err = params.apply(query.Select("classroom_collaborators.*").Preload("User")).Find(&students).Error
+
+	if err != nil {
+		return apperr.Internal(err, "could not get students")
+	}
+
+	hasMore := len(students) > params.Limit
+	if hasMore {
+		students = students[:params.Limit]
+	}
+
+	rows := make([]dto.StudentSubmission, 0, len(students))
+
+	for _, student := range students {
+		submission := byStudent[*student.UserID]
+
+		row := dto.StudentSubmission{
+			Student: dto.NewPublicUser(student.User),
+			State:   assignment.SubmissionState(submission, now),
+		}
+		if submission != nil {
+			res := dto.NewAssignmentSubmission(*assignment, *submission)
+			row.Submission = &res
+		}
+
+		rows = append(rows, row)
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "submissions fetched",
+		"success": true,
+		"summary": summary,
+		"data":    rows,
+		"meta":    params.meta(int64(len(studentIds)), hasMore, time.Time{}, ""),
+	})
+	return nil
+}
+
+// hand a student's work back to them
+func (r *Repository) ReturnSubmission(context *fiber.Ctx) error {
+	assignment := assignmentAccess(context)
+	studentId := context.Params("student_id")
+
+	student := []models.ClassroomCollaborator{}
+
+	err := r.DB.Scopes(activeMembership).Where("class_id = ? AND user_id = ? AND role = ?", assignment.ClassID, studentId, services.RoleStudent).Limit(1).Find(&student).Error
+
+	if err != nil || len(student) == 0 {
+		return apperr.NotFound("student not found in this classroom")
+	}
+
+	submission, err := r.findSubmission(*assignment.ID, studentId)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get submission")
+	}
+
+	// returned work that was graded again can be returned again to release the new grade
+	if assignment.SubmissionState(submission, time.Now()) == models.SubmissionReturned && submission.GradeReleased() {
+		return apperr.Conflict("work is already returned")
+	}
+
+	// returning work that was never turned in is allowed, e.g. to hand back a grade
+	isNew := submission == nil
+	if isNew {
+		id, _ := utils.GenerateUUid()
+
+		submission = &models.Submission{
+			ID:           &id,
+			AssignmentID: assignment.ID,
+			StudentID:    &studentId,
+			ClassID:      assignment.ClassID,
+		}
+	}
+
+	now := time.Now()
+	previous := submission.State
+	submission.State = models.SubmissionReturned
+	submission.ReturnedAt = &now
+
+	err = writeSubmission(r.DB, submission, isNew, map[string]interface{}{
+		"state":       submission.State,
+		"returned_at": submission.ReturnedAt,
+	}, previous)
+
+	if err != nil {
+		if failed := apperr.As(err); failed != nil {
+			return failed
+		}
+		return writeFailed(err, "database update failed")
+	}
+
+	context.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "work returned",
+		"success": true,
+		"data":    dto.NewAssignmentSubmission(*assignment, *submission),
+	})
+	return nil
+}
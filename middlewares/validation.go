@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/validate"
+)
+
+// parseBody reads the request body into incoming and checks its validate
+// tags, failing with the messages of every invalid field
+func parseBody(context *fiber.Ctx, incoming interface{}) error {
+	err := context.BodyParser(incoming)
+
+	if err != nil {
+		return apperr.BadRequest("request body could not be parsed")
+	}
+
+	if fields := validate.Struct(incoming); fields != nil {
+		return apperr.Validation("request is invalid").WithFields(fields)
+	}
+	return nil
+}
+
+// parseFormJSON reads the JSON held by a multipart form field into incoming
+// and checks it like parseBody
+func parseFormJSON(context *fiber.Ctx, field string, incoming interface{}) error {
+	err := json.Unmarshal([]byte(context.FormValue(field)), incoming)
+
+	if err != nil {
+		return apperr.BadRequest("the form field \"" + field + "\" must hold a JSON object")
+	}
+
+	if fields := validate.Struct(incoming); fields != nil {
+		return apperr.Validation("request is invalid").WithFields(fields)
+	}
+	return nil
+}
+
+// isMultipart reports whether the request body is a multipart form
+func isMultipart(context *fiber.Ctx) bool {
+	return strings.HasPrefix(string(context.Request().Header.ContentType()), fiber.MIMEMultipartForm)
+}
+
+// invalidField fails with a message for a single field
+func invalidField(field string, message string) error {
+	return apperr.InvalidField(field, message)
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/swayanshu-2003/classroom-backend/validate"
+)
+
+// requests holds every request struct, so a broken validate tag fails here
+// rather than on the first request that reaches it
+var requests = []interface{}{
+	comingComment{},
+	comingCriterion{},
+	comingRubric{},
+	comingRubricScore{},
+	comingGrade{},
+	comingGradeCategory{},
+	comingUserRegister{},
+	comingUserLogin{},
+	comingClassroom{},
+	comingClassroomEdit{},
+	comingJoin{},
+	comingAssignment{},
+	comingAssignmentEdit{},
+	comingInvitation{},
+	comingJoinCode{},
+	comingJoinMode{},
+	comingJoinDecision{},
+	comingTransfer{},
+	comingRole{},
+	comingRefreshToken{},
+	comingSubmission{},
+}
+
+func TestRequestTags(t *testing.T) {
+	for _, request := range requests {
+		if err := validate.Tags(request); err != nil {
+			t.Errorf("%T: %v", request, err)
+		}
+	}
+}
+
+func TestRequestsListsEveryRequest(t *testing.T) {
+	listed := map[string]bool{}
+	for _, request := range requests {
+		listed[reflect.TypeOf(request).Name()] = true
+	}
+
+	packages, err := parser.ParseDir(token.NewFileSet(), ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range packages["middlewares"].Files {
+		for _, decl := range file.Decls {
+			general, ok := decl.(*ast.GenDecl)
+			if !ok || general.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range general.Specs {
+				name := spec.(*ast.TypeSpec).Name.Name
+				if strings.HasPrefix(name, "coming") && !listed[name] {
+					t.Errorf("%s is missing from requests", name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/swayanshu-2003/classroom-backend/migrations"
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `usage: classroom-backend migrate <command>
+
+commands:
+  up              apply every pending migration
+  down [n]        revert the last n applied migrations, 1 by default
+  status          list migrations and when they were applied
+  create <name>   add empty up and down scripts to the migrations directory`
+
+// runMigrateCreate handles "migrate create", which needs no database
+func runMigrateCreate(args []string) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", "migrations", "directory holding the migration scripts")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("migrate create needs a name\n\n%s", migrateUsage)
+	}
+
+	up, down, err := migrations.Create(*dir, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("created", up)
+	fmt.Println("created", down)
+	return nil
+}
+
+// runMigrate handles the migrate subcommands that work on the database
+func runMigrate(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrations.Up(db)
+		for _, migration := range done {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down takes a positive number of migrations to revert")
+			}
+			steps = n
+		}
+
+		done, err := migrations.Down(db, steps)
+		for _, migration := range done {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		states, err := migrations.Status(db)
+		if err != nil {
+			return err
+		}
+
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", state.Version, state.Name, applied)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], migrateUsage)
+}
+
+// checkSchema refuses to serve against a database that still has pending migrations
+func checkSchema(db *gorm.DB) error {
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) != 0 {
+		return fmt.Errorf("database schema is %d migrations behind, run `%s migrate up` first", len(pending), os.Args[0])
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS rubric_scores;
+DROP TABLE IF EXISTS submissions;
+DROP TABLE IF EXISTS rubric_criterions;
+DROP TABLE IF EXISTS assignments;
+DROP TABLE IF EXISTS grade_categories;
+DROP TABLE IF EXISTS comment_edits;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS classroom_collaborators;
+DROP TABLE IF EXISTS classrooms;
+DROP TABLE IF EXISTS users;
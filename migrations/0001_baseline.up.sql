@@ -0,0 +1,378 @@
+-- the schema as AutoMigrate left it, so databases created before versioned
+-- migrations can be brought under them. Tables that already exist get the
+-- columns they were created without, whichever version of the code created
+-- them, before any index or key on those columns is built.
+
+CREATE TABLE IF NOT EXISTS users (
+	uuid text PRIMARY KEY,
+	username text,
+	name text,
+	password text,
+	profile_picture text,
+	avatar_hash text,
+	avatar_name text
+);
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS username text,
+	ADD COLUMN IF NOT EXISTS name text,
+	ADD COLUMN IF NOT EXISTS password text,
+	ADD COLUMN IF NOT EXISTS profile_picture text,
+	ADD COLUMN IF NOT EXISTS avatar_hash text,
+	ADD COLUMN IF NOT EXISTS avatar_name text;
+-- the unique index cannot be built while usernames differ only in case, so
+-- name them rather than fail on the index
+DO $$
+DECLARE
+	duplicates text;
+BEGIN
+	SELECT string_agg(name, ', ' ORDER BY name) INTO duplicates FROM (
+		SELECT lower(username) AS name FROM users
+		WHERE username IS NOT NULL
+		GROUP BY lower(username) HAVING count(*) > 1
+	) taken;
+
+	IF duplicates IS NOT NULL THEN
+		RAISE EXCEPTION 'usernames must be unique ignoring case, rename these before migrating: %', duplicates;
+	END IF;
+END
+$$;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username));
+
+CREATE TABLE IF NOT EXISTS classrooms (
+	class_id text PRIMARY KEY,
+	class_name text,
+	description text,
+	done boolean DEFAULT false,
+	owner_id text CONSTRAINT fk_users_classroom REFERENCES users (uuid) ON DELETE CASCADE,
+	is_deleted boolean DEFAULT false,
+	archived_at timestamptz,
+	trashed_at timestamptz,
+	shared boolean DEFAULT false,
+	join_code text,
+	join_enabled boolean DEFAULT true,
+	join_expires_at timestamptz,
+	join_mode text DEFAULT 'open',
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE classrooms
+	ADD COLUMN IF NOT EXISTS class_name text,
+	ADD COLUMN IF NOT EXISTS description text,
+	ADD COLUMN IF NOT EXISTS done boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS owner_id text CONSTRAINT fk_users_classroom REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS is_deleted boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS archived_at timestamptz,
+	ADD COLUMN IF NOT EXISTS trashed_at timestamptz,
+	ADD COLUMN IF NOT EXISTS shared boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS join_code text,
+	ADD COLUMN IF NOT EXISTS join_enabled boolean DEFAULT true,
+	ADD COLUMN IF NOT EXISTS join_expires_at timestamptz,
+	ADD COLUMN IF NOT EXISTS join_mode text DEFAULT 'open',
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+CREATE UNIQUE INDEX IF NOT EXISTS idx_classrooms_join_code ON classrooms (join_code);
+CREATE INDEX IF NOT EXISTS idx_classrooms_owner_id ON classrooms (owner_id);
+CREATE INDEX IF NOT EXISTS idx_classrooms_trashed_at ON classrooms (trashed_at);
+
+CREATE TABLE IF NOT EXISTS classroom_collaborators (
+	class_id text NOT NULL CONSTRAINT fk_classrooms_collaborators REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	user_id text NOT NULL CONSTRAINT fk_users_collaborations REFERENCES users (uuid) ON DELETE CASCADE,
+	role text,
+	is_removed boolean DEFAULT false,
+	status text DEFAULT 'active',
+	requested_at timestamptz,
+	decided_at timestamptz,
+	decided_by text,
+	PRIMARY KEY (class_id, user_id)
+);
+ALTER TABLE classroom_collaborators
+	ADD COLUMN IF NOT EXISTS class_id text CONSTRAINT fk_classrooms_collaborators REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS user_id text CONSTRAINT fk_users_collaborations REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS role text,
+	ADD COLUMN IF NOT EXISTS is_removed boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS status text DEFAULT 'active',
+	ADD COLUMN IF NOT EXISTS requested_at timestamptz,
+	ADD COLUMN IF NOT EXISTS decided_at timestamptz,
+	ADD COLUMN IF NOT EXISTS decided_by text;
+CREATE INDEX IF NOT EXISTS idx_classroom_collaborators_user_id ON classroom_collaborators (user_id);
+CREATE INDEX IF NOT EXISTS idx_classroom_collaborators_status ON classroom_collaborators (status);
+
+-- older tables had no key: drop orphaned and duplicate memberships, keeping
+-- the active one, then add it
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.table_constraints
+		WHERE table_schema = current_schema() AND table_name = 'classroom_collaborators' AND constraint_type = 'PRIMARY KEY'
+	) THEN
+		DELETE FROM classroom_collaborators WHERE class_id IS NULL OR user_id IS NULL;
+
+		DELETE FROM classroom_collaborators WHERE ctid IN (
+			SELECT ctid FROM (
+				SELECT ctid, row_number() OVER (
+					PARTITION BY class_id, user_id
+					ORDER BY is_removed ASC, status = 'active' DESC, ctid DESC
+				) AS n FROM classroom_collaborators
+			) ranked WHERE n > 1
+		);
+
+		ALTER TABLE classroom_collaborators ADD PRIMARY KEY (class_id, user_id);
+	END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS comments (
+	id bigserial PRIMARY KEY,
+	content text,
+	author_id text CONSTRAINT fk_users_comments REFERENCES users (uuid) ON DELETE CASCADE,
+	class_id text CONSTRAINT fk_classrooms_comments REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	parent_id bigint CONSTRAINT fk_comments_replies REFERENCES comments (id) ON DELETE CASCADE,
+	edited_at timestamptz,
+	created_at timestamptz DEFAULT now(),
+	updated_at timestamptz,
+	deleted_at timestamptz
+);
+ALTER TABLE comments
+	ADD COLUMN IF NOT EXISTS content text,
+	ADD COLUMN IF NOT EXISTS author_id text CONSTRAINT fk_users_comments REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS class_id text CONSTRAINT fk_classrooms_comments REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS parent_id bigint CONSTRAINT fk_comments_replies REFERENCES comments (id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS edited_at timestamptz,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now(),
+	ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+	ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_comments_author_id ON comments (author_id);
+CREATE INDEX IF NOT EXISTS idx_comments_class_created ON comments (class_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at);
+
+CREATE TABLE IF NOT EXISTS comment_edits (
+	id bigserial PRIMARY KEY,
+	comment_id bigint CONSTRAINT fk_comments_edits REFERENCES comments (id) ON DELETE CASCADE,
+	content text,
+	edited_by text,
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE comment_edits
+	ADD COLUMN IF NOT EXISTS comment_id bigint CONSTRAINT fk_comments_edits REFERENCES comments (id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS content text,
+	ADD COLUMN IF NOT EXISTS edited_by text,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_comment_edits_comment_id ON comment_edits (comment_id);
+
+CREATE TABLE IF NOT EXISTS grade_categories (
+	id bigserial PRIMARY KEY,
+	class_id text CONSTRAINT fk_grade_categories_classroom REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	name text,
+	weight decimal DEFAULT 0,
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE grade_categories
+	ADD COLUMN IF NOT EXISTS class_id text CONSTRAINT fk_grade_categories_classroom REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS name text,
+	ADD COLUMN IF NOT EXISTS weight decimal DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_grade_categories_class_id ON grade_categories (class_id);
+
+CREATE TABLE IF NOT EXISTS assignments (
+	is_deleted boolean DEFAULT false,
+	id text PRIMARY KEY,
+	title text,
+	type text,
+	description text,
+	link text,
+	class_id text CONSTRAINT fk_classrooms_assignments REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	auther_id text CONSTRAINT fk_users_assignments REFERENCES users (uuid) ON DELETE CASCADE,
+	points decimal,
+	category_id bigint CONSTRAINT fk_assignments_category REFERENCES grade_categories (id) ON DELETE SET NULL,
+	due_at timestamptz,
+	due_timezone text,
+	close_at timestamptz,
+	late_penalty decimal DEFAULT 0,
+	max_penalty decimal DEFAULT 100,
+	is_draft boolean DEFAULT false,
+	publish_at timestamptz,
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE assignments
+	ADD COLUMN IF NOT EXISTS is_deleted boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS title text,
+	ADD COLUMN IF NOT EXISTS type text,
+	ADD COLUMN IF NOT EXISTS description text,
+	ADD COLUMN IF NOT EXISTS link text,
+	ADD COLUMN IF NOT EXISTS class_id text CONSTRAINT fk_classrooms_assignments REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS auther_id text CONSTRAINT fk_users_assignments REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS points decimal,
+	ADD COLUMN IF NOT EXISTS category_id bigint CONSTRAINT fk_assignments_category REFERENCES grade_categories (id) ON DELETE SET NULL,
+	ADD COLUMN IF NOT EXISTS due_at timestamptz,
+	ADD COLUMN IF NOT EXISTS due_timezone text,
+	ADD COLUMN IF NOT EXISTS close_at timestamptz,
+	ADD COLUMN IF NOT EXISTS late_penalty decimal DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS max_penalty decimal DEFAULT 100,
+	ADD COLUMN IF NOT EXISTS is_draft boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS publish_at timestamptz,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_assignments_class_created ON assignments (class_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_assignments_auther_id ON assignments (auther_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_category_id ON assignments (category_id);
+
+CREATE TABLE IF NOT EXISTS rubric_criterions (
+	id bigserial PRIMARY KEY,
+	assignment_id text CONSTRAINT fk_assignments_rubric REFERENCES assignments (id) ON DELETE CASCADE,
+	title text,
+	description text,
+	points decimal,
+	position bigint
+);
+ALTER TABLE rubric_criterions
+	ADD COLUMN IF NOT EXISTS assignment_id text CONSTRAINT fk_assignments_rubric REFERENCES assignments (id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS title text,
+	ADD COLUMN IF NOT EXISTS description text,
+	ADD COLUMN IF NOT EXISTS points decimal,
+	ADD COLUMN IF NOT EXISTS position bigint;
+CREATE INDEX IF NOT EXISTS idx_rubric_criterions_assignment_id ON rubric_criterions (assignment_id);
+
+CREATE TABLE IF NOT EXISTS submissions (
+	id text PRIMARY KEY,
+	assignment_id text CONSTRAINT fk_submissions_assignment REFERENCES assignments (id) ON DELETE CASCADE,
+	student_id text CONSTRAINT fk_submissions_student REFERENCES users (uuid) ON DELETE CASCADE,
+	class_id text,
+	state text DEFAULT 'assigned',
+	link text,
+	note text,
+	turned_in_at timestamptz,
+	returned_at timestamptz,
+	grade decimal,
+	feedback text,
+	graded_at timestamptz,
+	graded_by text,
+	created_at timestamptz DEFAULT now(),
+	updated_at timestamptz
+);
+ALTER TABLE submissions
+	ADD COLUMN IF NOT EXISTS assignment_id text CONSTRAINT fk_submissions_assignment REFERENCES assignments (id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS student_id text CONSTRAINT fk_submissions_student REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS class_id text,
+	ADD COLUMN IF NOT EXISTS state text DEFAULT 'assigned',
+	ADD COLUMN IF NOT EXISTS link text,
+	ADD COLUMN IF NOT EXISTS note text,
+	ADD COLUMN IF NOT EXISTS turned_in_at timestamptz,
+	ADD COLUMN IF NOT EXISTS returned_at timestamptz,
+	ADD COLUMN IF NOT EXISTS grade decimal,
+	ADD COLUMN IF NOT EXISTS feedback text,
+	ADD COLUMN IF NOT EXISTS graded_at timestamptz,
+	ADD COLUMN IF NOT EXISTS graded_by text,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now(),
+	ADD COLUMN IF NOT EXISTS updated_at timestamptz;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_submissions_assignment_student ON submissions (assignment_id, student_id);
+CREATE INDEX IF NOT EXISTS idx_submissions_class_id ON submissions (class_id);
+
+CREATE TABLE IF NOT EXISTS rubric_scores (
+	id bigserial PRIMARY KEY,
+	submission_id text CONSTRAINT fk_submissions_rubric_scores REFERENCES submissions (id) ON DELETE CASCADE,
+	criterion_id bigint CONSTRAINT fk_rubric_scores_criterion REFERENCES rubric_criterions (id) ON DELETE CASCADE,
+	points decimal,
+	comment text
+);
+ALTER TABLE rubric_scores
+	ADD COLUMN IF NOT EXISTS submission_id text CONSTRAINT fk_submissions_rubric_scores REFERENCES submissions (id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS criterion_id bigint CONSTRAINT fk_rubric_scores_criterion REFERENCES rubric_criterions (id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS points decimal,
+	ADD COLUMN IF NOT EXISTS comment text;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rubric_scores_submission_criterion ON rubric_scores (submission_id, criterion_id);
+
+CREATE TABLE IF NOT EXISTS attachments (
+	id text PRIMARY KEY,
+	class_id text,
+	owner_type text,
+	owner_id text,
+	uploaded_by text,
+	filename text,
+	content_type text,
+	size bigint,
+	hash text,
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE attachments
+	ADD COLUMN IF NOT EXISTS class_id text,
+	ADD COLUMN IF NOT EXISTS owner_type text,
+	ADD COLUMN IF NOT EXISTS owner_id text,
+	ADD COLUMN IF NOT EXISTS uploaded_by text,
+	ADD COLUMN IF NOT EXISTS filename text,
+	ADD COLUMN IF NOT EXISTS content_type text,
+	ADD COLUMN IF NOT EXISTS size bigint,
+	ADD COLUMN IF NOT EXISTS hash text,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_attachments_class_id ON attachments (class_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_owner ON attachments (owner_type, owner_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_hash ON attachments (hash);
+
+CREATE TABLE IF NOT EXISTS invitations (
+	id text PRIMARY KEY,
+	class_id text CONSTRAINT fk_invitations_classroom REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	token text,
+	role text,
+	invitee_id text CONSTRAINT fk_invitations_invitee REFERENCES users (uuid) ON DELETE CASCADE,
+	max_uses bigint,
+	uses bigint DEFAULT 0,
+	expires_at timestamptz,
+	revoked_at timestamptz,
+	declined_at timestamptz,
+	created_by text,
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE invitations
+	ADD COLUMN IF NOT EXISTS class_id text CONSTRAINT fk_invitations_classroom REFERENCES classrooms (class_id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS token text,
+	ADD COLUMN IF NOT EXISTS role text,
+	ADD COLUMN IF NOT EXISTS invitee_id text CONSTRAINT fk_invitations_invitee REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS max_uses bigint,
+	ADD COLUMN IF NOT EXISTS uses bigint DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS expires_at timestamptz,
+	ADD COLUMN IF NOT EXISTS revoked_at timestamptz,
+	ADD COLUMN IF NOT EXISTS declined_at timestamptz,
+	ADD COLUMN IF NOT EXISTS created_by text,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_invitations_class_id ON invitations (class_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token ON invitations (token);
+CREATE INDEX IF NOT EXISTS idx_invitations_invitee_id ON invitations (invitee_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id bigserial PRIMARY KEY,
+	user_id text CONSTRAINT fk_notifications_user REFERENCES users (uuid) ON DELETE CASCADE,
+	class_id text,
+	type text,
+	message text,
+	read_at timestamptz,
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE notifications
+	ADD COLUMN IF NOT EXISTS user_id text CONSTRAINT fk_notifications_user REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS class_id text,
+	ADD COLUMN IF NOT EXISTS type text,
+	ADD COLUMN IF NOT EXISTS message text,
+	ADD COLUMN IF NOT EXISTS read_at timestamptz,
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+DROP INDEX IF EXISTS idx_notifications_user_id;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_class_id ON notifications (class_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id text PRIMARY KEY,
+	user_id text CONSTRAINT fk_sessions_user REFERENCES users (uuid) ON DELETE CASCADE,
+	refresh_token_hash text,
+	previous_token_hash text,
+	expires_at timestamptz,
+	revoked_at timestamptz,
+	last_used_at timestamptz DEFAULT now(),
+	created_at timestamptz DEFAULT now()
+);
+ALTER TABLE sessions
+	ADD COLUMN IF NOT EXISTS user_id text CONSTRAINT fk_sessions_user REFERENCES users (uuid) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS refresh_token_hash text,
+	ADD COLUMN IF NOT EXISTS previous_token_hash text,
+	ADD COLUMN IF NOT EXISTS expires_at timestamptz,
+	ADD COLUMN IF NOT EXISTS revoked_at timestamptz,
+	ADD COLUMN IF NOT EXISTS last_used_at timestamptz DEFAULT now(),
+	ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token_hash ON sessions (previous_token_hash);
@@ -0,0 +1,19 @@
+package migrations
+
+import "github.com/swayanshu-2003/classroom-backend/models"
+
+// backfills that need application code, such as bcrypt, and so cannot be SQL.
+// Neither can be undone, so they have no down step.
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "hash_plaintext_passwords",
+		Up:      models.MigratePlaintextPasswords,
+	})
+
+	register(Migration{
+		Version: 3,
+		Name:    "backfill_join_codes",
+		Up:      models.MigrateJoinCodes,
+	})
+}
@@ -0,0 +1,323 @@
+// Package migrations versions the database schema. Schema changes are SQL
+// files named <version>_<name>.up.sql with a matching .down.sql, embedded in
+// the binary; data backfills that need application code are registered in
+// backfills.go. Applied versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockKey serializes migration runs across processes through a postgres advisory lock
+const lockKey = 72_616_130
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one schema version. Down may be nil when there is nothing to undo.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// State is a migration with when it was applied, nil when it is pending
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time `gorm:"default:now()"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// registered holds the migrations written in Go
+var registered []Migration
+
+func register(migration Migration) {
+	registered = append(registered, migration)
+}
+
+// All returns every known migration ordered by version
+func All() ([]Migration, error) {
+	byVersion := map[int64]*Migration{}
+
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(parts[1], 10, 64)
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, parts[2])
+		}
+
+		body, err := files.ReadFile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		run := execSQL(string(body))
+		if parts[3] == "up" {
+			migration.Up = run
+		} else {
+			migration.Down = run
+		}
+	}
+
+	for i := range registered {
+		if _, ok := byVersion[registered[i].Version]; ok {
+			return nil, fmt.Errorf("migration %d is defined twice", registered[i].Version)
+		}
+		byVersion[registered[i].Version] = &registered[i]
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		all = append(all, *migration)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all, nil
+}
+
+func execSQL(body string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if len(strings.TrimSpace(body)) == 0 {
+			return nil
+		}
+		return tx.Exec(body).Error
+	}
+}
+
+// applied returns when each applied version was applied
+func applied(db *gorm.DB) (map[int64]time.Time, error) {
+	err := db.AutoMigrate(&schemaMigration{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []schemaMigration{}
+
+	err = db.Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		versions[row.Version] = row.AppliedAt
+	}
+	return versions, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func Status(db *gorm.DB) ([]State, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]State, 0, len(all))
+	for _, migration := range all {
+		state := State{Migration: migration}
+		if at, ok := versions[migration.Version]; ok {
+			state.AppliedAt = &at
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func Pending(db *gorm.DB) ([]Migration, error) {
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			pending = append(pending, state.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the ones it applied
+func Up(db *gorm.DB) ([]Migration, error) {
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+
+	for _, migration := range pending {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			ran, err := lockAndCheck(tx, migration.Version)
+			if err != nil || ran {
+				return err
+			}
+
+			err = migration.Up(tx)
+			if err != nil {
+				return err
+			}
+
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+
+	for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := states[i].Migration
+		if states[i].AppliedAt == nil {
+			continue
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			ran, err := lockAndCheck(tx, migration.Version)
+			if err != nil || !ran {
+				return err
+			}
+
+			if migration.Down != nil {
+				err = migration.Down(tx)
+				if err != nil {
+					return err
+				}
+			}
+
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// lockAndCheck takes the migration lock for the rest of the transaction and
+// reports whether version has been applied, as another process may have run
+// it while this one waited
+func lockAndCheck(tx *gorm.DB, version int64) (bool, error) {
+	err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	err = tx.Model(&schemaMigration{}).Where("version = ?", version).Count(&count).Error
+	return count != 0, err
+}
+
+// Create writes an empty up and down script for a new migration into dir,
+// numbered after the highest version already there, and returns their paths
+func Create(dir string, name string) (string, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+
+	if len(name) == 0 {
+		return "", "", fmt.Errorf("a migration needs a name")
+	}
+
+	all, err := All()
+	if err != nil {
+		return "", "", err
+	}
+
+	next := int64(1)
+	if len(all) != 0 {
+		next = all[len(all)-1].Version + 1
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for _, entry := range entries {
+		if parts := fileName.FindStringSubmatch(entry.Name()); parts != nil {
+			version, _ := strconv.ParseInt(parts[1], 10, 64)
+			if version >= next {
+				next = version + 1
+			}
+		}
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", next, name))
+	up, down := base+".up.sql", base+".down.sql"
+
+	err = os.WriteFile(up, []byte("-- "+name+"\n"), 0o644)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = os.WriteFile(down, []byte("-- revert "+name+"\n"), 0o644)
+	if err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// IsPublished reports whether students can see the assignment: it is not a
+// draft and its scheduled publish time, if any, has passed
+func (a *Assignments) IsPublished(now time.Time) bool {
+	if a.IsDraft {
+		return false
+	}
+	return a.PublishAt == nil || !now.Before(*a.PublishAt)
+}
+
+// IsOverdue reports whether the due date has passed
+func (a *Assignments) IsOverdue(now time.Time) bool {
+	return a.DueAt != nil && now.After(*a.DueAt)
+}
+
+// IsClosed reports whether the assignment stopped accepting work
+func (a *Assignments) IsClosed(now time.Time) bool {
+	return a.CloseAt != nil && now.After(*a.CloseAt)
+}
+
+// TurnInState is the state work turned in at the given time gets
+func (a *Assignments) TurnInState(at time.Time) string {
+	if a.IsOverdue(at) {
+		return SubmissionLate
+	}
+	return SubmissionTurnedIn
+}
+
+// SubmissionState is where a student's work stands. Work that was never
+// turned in is missing once the assignment is overdue.
+func (a *Assignments) SubmissionState(submission *Submission, now time.Time) string {
+	state := SubmissionAssigned
+	if submission != nil && len(submission.State) != 0 {
+		state = submission.State
+	}
+
+	if state == SubmissionAssigned && a.IsOverdue(now) {
+		return SubmissionMissing
+	}
+	return state
+}
+
+// PenaltyPercent is the late penalty earned by a submission: LatePenalty
+// percent for each started day past the due date, capped at MaxPenalty
+func (a *Assignments) PenaltyPercent(submission *Submission) float64 {
+	if submission == nil || submission.TurnedInAt == nil || a.DueAt == nil || a.LatePenalty <= 0 {
+		return 0
+	}
+
+	late := submission.TurnedInAt.Sub(*a.DueAt)
+	if late <= 0 {
+		return 0
+	}
+
+	days := math.Ceil(late.Hours() / 24)
+	return math.Min(days*a.LatePenalty, a.MaxPenalty)
+}
+
+// AdjustedGrade is the submission's grade after the late penalty, nil while ungraded
+func (a *Assignments) AdjustedGrade(submission *Submission) *float64 {
+	if submission == nil || submission.Grade == nil {
+		return nil
+	}
+
+	grade := *submission.Grade * (1 - a.PenaltyPercent(submission)/100)
+	grade = math.Round(grade*100) / 100
+	return &grade
+}
+
+// LocalDueAt formats the due date in the assignment's time zone
+func (a *Assignments) LocalDueAt() *string {
+	if a.DueAt == nil {
+		return nil
+	}
+
+	due := *a.DueAt
+	if a.DueTimezone != nil {
+		if location, err := time.LoadLocation(*a.DueTimezone); err == nil {
+			due = due.In(location)
+		}
+	}
+
+	formatted := due.Format(time.RFC3339)
+	return &formatted
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"gorm.io/gorm"
+)
+
+// UnusedJoinCode draws join codes until one is not taken by another
+// classroom. The unique index on join_code still guards concurrent writers.
+func UnusedJoinCode(db *gorm.DB) (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := utils.GenerateJoinCode()
+		if err != nil {
+			return "", err
+		}
+
+		var count int64
+		err = db.Model(&Classroom{}).Where("join_code = ?", code).Count(&count).Error
+		if err != nil {
+			return "", err
+		}
+
+		if count == 0 {
+			return code, nil
+		}
+	}
+
+	return "", errors.New("could not find an unused join code")
+}
+
+// MigrateJoinCodes gives a join code to every classroom created before
+// classrooms had one. It is safe to run repeatedly.
+func MigrateJoinCodes(db *gorm.DB) error {
+	var classrooms []Classroom
+
+	err := db.Select("class_id").Where("join_code IS NULL").Find(&classrooms).Error
+	if err != nil {
+		return err
+	}
+
+	for _, classroom := range classrooms {
+		code, err := UnusedJoinCode(db)
+		if err != nil {
+			return err
+		}
+
+		err = db.Model(&Classroom{}).Where("class_id = ?", classroom.ClassId).Update("join_code", code).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
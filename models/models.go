@@ -10,10 +10,12 @@ import (
 type Users struct {
 	// ID             int                     `gorm:"primaryKey;autoIncrement" json:"id"`
 	Uuid           *string                 `gorm:"primaryKey" json:"uuid"`
-	Username       *string                 `json:"username"`
+	Username       *string                 `gorm:"uniqueIndex:idx_users_username_lower,expression:lower(username)" json:"username"`
 	Name           *string                 `json:"name"`
-	Password       *string                 `json:"password"`
+	Password       *string                 `json:"-"`
 	ProfilePicture *string                 `json:"profile_picture"`
+	AvatarHash     *string                 `json:"-"`
+	AvatarName     *string                 `json:"-"`
 	Classroom      []Classroom             `gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE" json:"classroom"`
 	Collaborations []ClassroomCollaborator `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"collaborations"`
 	Comments       []Comment               `gorm:"foreignKey:AuthorID;constraint:OnDelete:CASCADE" json:"comments"`
@@ -27,9 +29,15 @@ type Classroom struct {
 	ClassName     *string                 `json:"class_name"`
 	Description   *string                 `json:"description"`
 	Done          bool                    `gorm:"default:false" json:"done"`
-	OwnerID       *string                 `json:"owner_id"`
+	OwnerID       *string                 `gorm:"index" json:"owner_id"`
 	IsDeleted     bool                    `gorm:"default:false" json:"is_deleted"`
+	ArchivedAt    *time.Time              `json:"archived_at"`
+	TrashedAt     *time.Time              `gorm:"index" json:"trashed_at"`
 	Shared        bool                    `gorm:"default:false" json:"shared"`
+	JoinCode      *string                 `gorm:"uniqueIndex" json:"join_code"`
+	JoinEnabled   bool                    `gorm:"default:true" json:"join_enabled"`
+	JoinExpiresAt *time.Time              `json:"join_expires_at"`
+	JoinMode      string                  `gorm:"default:open" json:"join_mode"`
 	Owner         Users                   `gorm:"foreignKey:OwnerID;references:Uuid" json:"owner"`
 	Collaborators []ClassroomCollaborator `gorm:"foreignKey:ClassID;constraint:OnDelete:CASCADE" json:"collaborators"`
 	Comments      []Comment               `gorm:"foreignKey:ClassID;constraint:OnDelete:CASCADE" json:"comments"`
@@ -37,46 +45,241 @@ type Classroom struct {
 	Assignments   []Assignments           `gorm:"foreignKey:ClassID;constraint:OnDelete:CASCADE" json:"assignments"`
 }
 
-// ClassroomCollaborator represents the classroom collaborator model
+// classroom join modes
+const (
+	JoinOpen       = "open"
+	JoinApproval   = "approval"
+	JoinInviteOnly = "invite_only"
+	JoinClosed     = "closed"
+)
+
+// membership states; pending and rejected rows are join requests, not members
+const (
+	MembershipPending  = "pending"
+	MembershipActive   = "active"
+	MembershipRejected = "rejected"
+)
+
+// ClassroomCollaborator represents the classroom collaborator model, one row
+// per user and classroom
 type ClassroomCollaborator struct {
-	UserID    *string `json:"user_id"`
-	ClassID   *string `json:"class_id"`
-	Role      string  `json:"role"`
-	IsRemoved bool    `gorm:"default:false" json:"is_removed"`
+	ClassID     *string    `gorm:"primaryKey" json:"class_id"`
+	UserID      *string    `gorm:"primaryKey;index" json:"user_id"`
+	Role        string     `json:"role"`
+	IsRemoved   bool       `gorm:"default:false" json:"is_removed"`
+	Status      string     `gorm:"default:active;index" json:"status"`
+	RequestedAt *time.Time `json:"requested_at"`
+	DecidedAt   *time.Time `json:"decided_at"`
+	DecidedBy   *string    `json:"decided_by"`
 	// User      Users     `gorm:"-" json:"-"`
 	// Classroom Classroom `gorm:"-" json:"-"`
 	User      Users     `gorm:"foreignKey:UserID;references:Uuid" json:"users"`
 	Classroom Classroom `gorm:"foreignKey:ClassID;references:ClassId" json:"classroom"`
 }
 
-// Comment represents the comment model
+// Comment represents the comment model, a class stream post or a reply to one
 type Comment struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Content   *string        `json:"content"`
+	AuthorID  *string        `gorm:"index" json:"author_id"`
+	ClassID   *string        `gorm:"index:idx_comments_class_created" json:"class_id"`
+	ParentID  *uint          `gorm:"index" json:"parent_id"`
+	EditedAt  *time.Time     `json:"edited_at"`
+	CreatedAt time.Time      `gorm:"default:now();index:idx_comments_class_created" json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	Author    Users          `gorm:"foreignKey:AuthorID;references:Uuid" json:"author"`
+	Classroom Classroom      `gorm:"foreignKey:ClassID;references:ClassId" json:"classroom"`
+	Replies   []Comment      `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"replies"`
+	Edits     []CommentEdit  `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE" json:"edits"`
+}
+
+// CommentEdit keeps the previous content of a comment each time it is edited
+type CommentEdit struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"index" json:"comment_id"`
 	Content   *string   `json:"content"`
-	AuthorID  *string   `json:"author_id"`
-	ClassID   *string   `json:"class_id"`
+	EditedBy  *string   `json:"edited_by"`
 	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
-	Author    Users     `gorm:"foreignKey:AuthorID;references:Uuid" json:"author"`
-	Classroom Classroom `gorm:"foreignKey:ClassID;references:ClassId" json:"classroom"`
 }
 
+// assignment types
+const (
+	AssignmentTask     = "assignment"
+	AssignmentQuiz     = "quiz"
+	AssignmentQuestion = "question"
+	AssignmentMaterial = "material"
+)
+
 // assignments
 type Assignments struct {
-	IsDeleted   bool      `gorm:"default:false" json:"is_deleted"`
+	IsDeleted   bool              `gorm:"default:false" json:"is_deleted"`
+	ID          *string           `gorm:"primaryKey" json:"id"`
+	Title       *string           `json:"title"`
+	Type        *string           `json:"type"`
+	Description *string           `json:"description"`
+	Link        *string           `json:"link"`
+	ClassID     *string           `gorm:"index:idx_assignments_class_created" json:"class_id"`
+	AutherId    *string           `gorm:"index" json:"auther_id"`
+	Points      *float64          `json:"points"`
+	CategoryID  *uint             `gorm:"index" json:"category_id"`
+	DueAt       *time.Time        `json:"due_at"`
+	DueTimezone *string           `json:"due_timezone"`
+	CloseAt     *time.Time        `json:"close_at"`
+	LatePenalty float64           `json:"late_penalty"`
+	MaxPenalty  float64           `json:"max_penalty"`
+	IsDraft     bool              `json:"is_draft"`
+	PublishAt   *time.Time        `json:"publish_at"`
+	CreatedAt   time.Time         `gorm:"default:now();index:idx_assignments_class_created" json:"created_at"`
+	Classroom   Classroom         `gorm:"foreignKey:ClassID;references:ClassId" json:"classroom"`
+	CreatedBy   Users             `gorm:"foreignKey:AutherId;references:Uuid" json:"created_by"`
+	Category    *GradeCategory    `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL" json:"category"`
+	Rubric      []RubricCriterion `gorm:"foreignKey:AssignmentID;constraint:OnDelete:CASCADE" json:"rubric"`
+}
+
+// GradeCategory groups assignments of a classroom under a weight, in percent
+type GradeCategory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ClassID   *string   `gorm:"index" json:"class_id"`
+	Name      *string   `json:"name"`
+	Weight    float64   `gorm:"default:0" json:"weight"`
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+	Classroom Classroom `gorm:"foreignKey:ClassID;references:ClassId;constraint:OnDelete:CASCADE" json:"classroom"`
+}
+
+// RubricCriterion is one scored line of an assignment's rubric
+type RubricCriterion struct {
+	ID           uint    `gorm:"primaryKey" json:"id"`
+	AssignmentID *string `gorm:"index" json:"assignment_id"`
+	Title        *string `json:"title"`
+	Description  *string `json:"description"`
+	Points       float64 `json:"points"`
+	Position     int     `json:"position"`
+}
+
+// RubricScore is the points a submission earned on one rubric criterion
+type RubricScore struct {
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	SubmissionID *string         `gorm:"uniqueIndex:idx_rubric_scores_submission_criterion" json:"submission_id"`
+	CriterionID  uint            `gorm:"uniqueIndex:idx_rubric_scores_submission_criterion" json:"criterion_id"`
+	Points       float64         `json:"points"`
+	Comment      *string         `json:"comment"`
+	Criterion    RubricCriterion `gorm:"foreignKey:CriterionID;constraint:OnDelete:CASCADE" json:"criterion"`
+}
+
+// submission states
+const (
+	SubmissionAssigned = "assigned"
+	SubmissionTurnedIn = "turned_in"
+	SubmissionReturned = "returned"
+	SubmissionLate     = "late"
+	SubmissionMissing  = "missing"
+)
+
+// Submission represents a student's work on an assignment
+type Submission struct {
+	ID           *string       `gorm:"primaryKey" json:"id"`
+	AssignmentID *string       `gorm:"uniqueIndex:idx_submissions_assignment_student" json:"assignment_id"`
+	StudentID    *string       `gorm:"uniqueIndex:idx_submissions_assignment_student" json:"student_id"`
+	ClassID      *string       `gorm:"index" json:"class_id"`
+	State        string        `gorm:"default:assigned" json:"state"`
+	Link         *string       `json:"link"`
+	Note         *string       `json:"note"`
+	TurnedInAt   *time.Time    `json:"turned_in_at"`
+	ReturnedAt   *time.Time    `json:"returned_at"`
+	Grade        *float64      `json:"grade"`
+	Feedback     *string       `json:"feedback"`
+	GradedAt     *time.Time    `json:"graded_at"`
+	GradedBy     *string       `json:"graded_by"`
+	CreatedAt    time.Time     `gorm:"default:now()" json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Assignment   Assignments   `gorm:"foreignKey:AssignmentID;references:ID;constraint:OnDelete:CASCADE" json:"assignment"`
+	Student      Users         `gorm:"foreignKey:StudentID;references:Uuid;constraint:OnDelete:CASCADE" json:"student"`
+	RubricScores []RubricScore `gorm:"foreignKey:SubmissionID;constraint:OnDelete:CASCADE" json:"rubric_scores"`
+}
+
+// GradeReleased reports whether the student may see the grade and feedback,
+// which stay private until the work is returned after it was last graded
+func (s *Submission) GradeReleased() bool {
+	return s.ReturnedAt != nil && (s.GradedAt == nil || !s.GradedAt.After(*s.ReturnedAt))
+}
+
+// attachment owners
+const (
+	AttachmentAssignment = "assignment"
+	AttachmentSubmission = "submission"
+	AttachmentComment    = "comment"
+)
+
+// Attachment is an uploaded file attached to an assignment, submission or
+// comment. The content lives in blob storage keyed by its sha256, so the
+// same file uploaded twice is stored once.
+type Attachment struct {
 	ID          *string   `gorm:"primaryKey" json:"id"`
-	Title       *string   `json:"title"`
-	Type        *string   `json:"type"`
-	Description *string   `json:"description"`
-	Link        *string   `json:"link"`
-	ClassID     *string   `json:"class_id"`
-	AutherId    *string   `json:"auther_id"`
+	ClassID     *string   `gorm:"index" json:"class_id"`
+	OwnerType   string    `gorm:"index:idx_attachments_owner" json:"owner_type"`
+	OwnerID     string    `gorm:"index:idx_attachments_owner" json:"owner_id"`
+	UploadedBy  *string   `json:"uploaded_by"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Hash        string    `gorm:"index" json:"hash"`
 	CreatedAt   time.Time `gorm:"default:now()" json:"created_at"`
-	Classroom   Classroom `gorm:"foreignKey:ClassID;references:ClassId" json:"classroom"`
-	CreatedBy   Users     `gorm:"foreignKey:AutherId;references:Uuid" json:"created_by"`
 }
 
-// MigrateUser migrates the user and related models
-func MigrateUser(db *gorm.DB) error {
-	err := db.AutoMigrate(&Users{}, &Classroom{}, &ClassroomCollaborator{}, &Comment{}, &Assignments{})
-	return err
+// Invitation lets people join a classroom with a given role. Without an
+// invitee it is a shareable link anyone can use until it runs out of uses,
+// expires or is revoked; with one it is a personal, single use invitation.
+type Invitation struct {
+	ID         *string    `gorm:"primaryKey" json:"id"`
+	ClassID    *string    `gorm:"index" json:"class_id"`
+	Token      string     `gorm:"uniqueIndex" json:"token"`
+	Role       string     `json:"role"`
+	InviteeID  *string    `gorm:"index" json:"invitee_id"`
+	MaxUses    *int       `json:"max_uses"`
+	Uses       int        `gorm:"default:0" json:"uses"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	DeclinedAt *time.Time `json:"declined_at"`
+	CreatedBy  *string    `json:"created_by"`
+	CreatedAt  time.Time  `gorm:"default:now()" json:"created_at"`
+	Classroom  Classroom  `gorm:"foreignKey:ClassID;references:ClassId;constraint:OnDelete:CASCADE" json:"classroom"`
+	Invitee    Users      `gorm:"foreignKey:InviteeID;references:Uuid;constraint:OnDelete:CASCADE" json:"invitee"`
+}
+
+// Usable reports whether the invitation can still be accepted
+func (i *Invitation) Usable(now time.Time) bool {
+	if i.RevokedAt != nil || i.DeclinedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == nil || i.Uses < *i.MaxUses
+}
+
+// Notification tells a user about something that happened to them, such as
+// the outcome of a join request
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    *string    `gorm:"index:idx_notifications_user_created" json:"user_id"`
+	ClassID   *string    `gorm:"index" json:"class_id"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"default:now();index:idx_notifications_user_created" json:"created_at"`
+	User      Users      `gorm:"foreignKey:UserID;references:Uuid;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Session represents a login session backing the issued access and refresh tokens
+type Session struct {
+	ID                *string    `gorm:"primaryKey" json:"id"`
+	UserID            *string    `gorm:"index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"index" json:"-"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	LastUsedAt        time.Time  `gorm:"default:now()" json:"last_used_at"`
+	CreatedAt         time.Time  `gorm:"default:now()" json:"created_at"`
+	User              Users      `gorm:"foreignKey:UserID;references:Uuid;constraint:OnDelete:CASCADE" json:"-"`
 }
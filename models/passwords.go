@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"gorm.io/gorm"
+)
+
+// MigratePlaintextPasswords hashes every password still stored in plaintext.
+// It is safe to run repeatedly; rows that already hold a hash are skipped.
+func MigratePlaintextPasswords(db *gorm.DB) error {
+	var users []Users
+
+	return db.Select("uuid", "password").
+		Where("password IS NOT NULL AND password NOT LIKE ?", "$2_$%").
+		FindInBatches(&users, 100, func(tx *gorm.DB, batch int) error {
+			for _, user := range users {
+				if user.Password == nil || utils.IsPasswordHash(*user.Password) {
+					continue
+				}
+
+				hash, err := utils.HashPassword(*user.Password)
+				if err != nil {
+					return err
+				}
+
+				err = tx.Model(&Users{}).Where("uuid = ? AND password = ?", user.Uuid, *user.Password).
+					Update("password", hash).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+// NewAssignment is what an assignment is created with
+type NewAssignment struct {
+	ClassID     *string
+	Title       *string
+	Type        *string
+	Description *string
+	Link        *string
+	Points      *float64
+	CategoryID  *uint
+	DueAt       *time.Time
+	DueTimezone *string
+	CloseAt     *time.Time
+	LatePenalty *float64
+	MaxPenalty  *float64
+	IsDraft     bool
+	PublishAt   *time.Time
+}
+
+// AssignmentEdit changes the fields that are set; publishing has its own operations
+type AssignmentEdit struct {
+	Title       *string
+	Type        *string
+	Description *string
+	Link        *string
+	Points      *float64
+	CategoryID  *uint
+	DueAt       *time.Time
+	DueTimezone *string
+	CloseAt     *time.Time
+	LatePenalty *float64
+	MaxPenalty  *float64
+	PublishAt   *time.Time
+}
+
+type AssignmentService struct {
+	assignments AssignmentStore
+	membership  *MembershipService
+	work        UnitOfWork
+}
+
+// checkSchedule validates the deadline fields of an assignment, returning a
+// validation error for the first one that does not make sense
+func checkSchedule(assignment *models.Assignments) error {
+	switch {
+	case assignment.DueTimezone != nil && !validTimezone(*assignment.DueTimezone):
+		return apperr.InvalidField("due_timezone", "must be an IANA time zone such as Asia/Kolkata")
+	case assignment.CloseAt != nil && assignment.DueAt != nil && assignment.CloseAt.Before(*assignment.DueAt):
+		return apperr.InvalidField("close_at", "cannot be before due_at")
+	case assignment.LatePenalty < 0 || assignment.LatePenalty > 100:
+		return apperr.InvalidField("late_penalty", "must be between 0 and 100")
+	case assignment.MaxPenalty < 0 || assignment.MaxPenalty > 100:
+		return apperr.InvalidField("max_penalty", "must be between 0 and 100")
+	}
+	return nil
+}
+
+func validTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil && len(name) != 0
+}
+
+// checkCategory makes sure a grade category belongs to the assignment's classroom
+func (s *AssignmentService) checkCategory(classId string, categoryId *uint) error {
+	if categoryId == nil {
+		return nil
+	}
+
+	found, err := s.assignments.CategoryInClassroom(classId, *categoryId)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get grade categories")
+	}
+
+	if !found {
+		return apperr.InvalidField("category_id", "is not a grade category of this classroom")
+	}
+	return nil
+}
+
+// Authorize loads an assignment and checks the permission against its
+// classroom, like MembershipService.Authorize. Unpublished assignments do not
+// exist for students.
+func (s *AssignmentService) Authorize(id string, user *models.Users, permission string, readOnly bool) (*models.Assignments, *models.Classroom, string, error) {
+	assignment, err := s.assignments.Assignment(id)
+
+	if err != nil || assignment == nil || assignment.ClassID == nil {
+		return nil, nil, "", apperr.NotFound("assignment not found")
+	}
+
+	classroom, role, err := s.membership.Authorize(*assignment.ClassID, user, permission, readOnly)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if role == RoleStudent && !assignment.IsPublished(time.Now()) {
+		return nil, nil, "", apperr.NotFound("assignment not found")
+	}
+
+	return assignment, classroom, role, nil
+}
+
+// List pages through the assignments of a classroom; students only see the
+// published ones, whatever the filter asks for
+func (s *AssignmentService) List(classroom *models.Classroom, role string, filter AssignmentFilter, page Page) ([]models.Assignments, int64, error) {
+	if role == RoleStudent {
+		now := time.Now()
+		filter.PublishedBy = &now
+		filter.Draft = nil
+	}
+
+	assignments, total, err := s.assignments.ListAssignments(*classroom.ClassId, filter, page)
+
+	if err != nil {
+		return nil, 0, apperr.Internal(err, "could not get assignments")
+	}
+	return assignments, total, nil
+}
+
+// Create adds an assignment to a classroom the author teaches that is not
+// archived, together with attachments whose content is already stored
+func (s *AssignmentService) Create(author *models.Users, incoming NewAssignment, attachments []models.Attachment) (*models.Assignments, error) {
+	_, _, err := s.membership.Authorize(*incoming.ClassID, author, PermManageAssignments, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkCategory(*incoming.ClassID, incoming.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := models.AssignmentTask
+	if incoming.Type != nil {
+		kind = *incoming.Type
+	}
+
+	id, _ := utils.GenerateUUid()
+
+	assignment := models.Assignments{
+		ID:          &id,
+		ClassID:     incoming.ClassID,
+		AutherId:    author.Uuid,
+		Title:       incoming.Title,
+		Type:        &kind,
+		Description: incoming.Description,
+		Link:        incoming.Link,
+		Points:      incoming.Points,
+		CategoryID:  incoming.CategoryID,
+		DueAt:       incoming.DueAt,
+		DueTimezone: incoming.DueTimezone,
+		CloseAt:     incoming.CloseAt,
+		IsDraft:     incoming.IsDraft,
+		PublishAt:   incoming.PublishAt,
+		MaxPenalty:  100,
+	}
+	if incoming.LatePenalty != nil {
+		assignment.LatePenalty = *incoming.LatePenalty
+	}
+	// the 100 default lives here rather than on the column, since 0 is a real cap
+	if incoming.MaxPenalty != nil {
+		assignment.MaxPenalty = *incoming.MaxPenalty
+	}
+
+	err = checkSchedule(&assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		attachments[i].ClassID = assignment.ClassID
+		attachments[i].OwnerType = models.AttachmentAssignment
+		attachments[i].OwnerID = id
+	}
+
+	err = s.work.Do(func(stores Stores) error {
+		err := stores.Assignments.CreateAssignment(&assignment)
+		if err != nil || len(attachments) == 0 {
+			return err
+		}
+		return stores.Assignments.CreateAttachments(attachments)
+	})
+
+	if err != nil {
+		return nil, writeFailed(err, "database insertion failed")
+	}
+	return &assignment, nil
+}
+
+// Edit applies the fields of incoming that are set; only the author may edit
+// an assignment, and the schedule is checked as it will be after the change
+func (s *AssignmentService) Edit(assignment *models.Assignments, editor *models.Users, incoming AssignmentEdit) error {
+	if assignment.AutherId == nil || *assignment.AutherId != *editor.Uuid {
+		return apperr.Forbidden("un-authorized")
+	}
+
+	err := s.checkCategory(*assignment.ClassID, incoming.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	edited := *assignment
+	columns := []string{}
+
+	if incoming.Title != nil {
+		edited.Title = incoming.Title
+		columns = append(columns, "title")
+	}
+	if incoming.Type != nil {
+		edited.Type = incoming.Type
+		columns = append(columns, "type")
+	}
+	if incoming.Description != nil {
+		edited.Description = incoming.Description
+		columns = append(columns, "description")
+	}
+	if incoming.Link != nil {
+		edited.Link = incoming.Link
+		columns = append(columns, "link")
+	}
+	if incoming.Points != nil {
+		edited.Points = incoming.Points
+		columns = append(columns, "points")
+	}
+	if incoming.CategoryID != nil {
+		edited.CategoryID = incoming.CategoryID
+		columns = append(columns, "category_id")
+	}
+	if incoming.DueAt != nil {
+		edited.DueAt = incoming.DueAt
+		columns = append(columns, "due_at")
+	}
+	if incoming.DueTimezone != nil {
+		edited.DueTimezone = incoming.DueTimezone
+		columns = append(columns, "due_timezone")
+	}
+	if incoming.CloseAt != nil {
+		edited.CloseAt = incoming.CloseAt
+		columns = append(columns, "close_at")
+	}
+	if incoming.LatePenalty != nil {
+		edited.LatePenalty = *incoming.LatePenalty
+		columns = append(columns, "late_penalty")
+	}
+	if incoming.MaxPenalty != nil {
+		edited.MaxPenalty = *incoming.MaxPenalty
+		columns = append(columns, "max_penalty")
+	}
+	if incoming.PublishAt != nil {
+		edited.PublishAt = incoming.PublishAt
+		columns = append(columns, "publish_at")
+	}
+
+	if len(columns) == 0 {
+		return apperr.Validation("nothing to update")
+	}
+
+	err = checkSchedule(&edited)
+	if err != nil {
+		return err
+	}
+
+	err = s.assignments.UpdateAssignment(&edited, columns...)
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*assignment = edited
+	return nil
+}
+
+// Publish makes a draft or scheduled assignment visible right away
+func (s *AssignmentService) Publish(assignment *models.Assignments) error {
+	now := time.Now()
+
+	published := *assignment
+	published.IsDraft = false
+	published.PublishAt = &now
+
+	err := s.assignments.UpdateAssignment(&published, "is_draft", "publish_at")
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*assignment = published
+	return nil
+}
+
+// Unpublish turns an assignment back into a draft students cannot see
+func (s *AssignmentService) Unpublish(assignment *models.Assignments) error {
+	drafted := *assignment
+	drafted.IsDraft = true
+
+	err := s.assignments.UpdateAssignment(&drafted, "is_draft")
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*assignment = drafted
+	return nil
+}
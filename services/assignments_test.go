@@ -0,0 +1,190 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+func createAssignment(t *testing.T, svc *services.Services, author *models.Users, incoming services.NewAssignment) *models.Assignments {
+	t.Helper()
+
+	assignment, err := svc.Assignments.Create(author, incoming, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return assignment
+}
+
+func TestCreateAssignment(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, teacher)
+	join(t, svc, classroom, student)
+
+	_, err := svc.Assignments.Create(student, services.NewAssignment{ClassID: classroom.ClassId, Title: str("essay")}, nil)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	attachments := []models.Attachment{{ID: str("attachment")}}
+
+	assignment, err := svc.Assignments.Create(teacher, services.NewAssignment{ClassID: classroom.ClassId, Title: str("essay")}, attachments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *assignment.Type != models.AssignmentTask || assignment.MaxPenalty != 100 {
+		t.Fatalf("expected the defaults to be filled in, got %+v", assignment)
+	}
+	if attachments[0].OwnerType != models.AttachmentAssignment || attachments[0].OwnerID != *assignment.ID {
+		t.Fatalf("expected the attachment to belong to the assignment, got %+v", attachments[0])
+	}
+
+	// an attachment that is already stored conflicts
+	_, err = svc.Assignments.Create(teacher, services.NewAssignment{ClassID: classroom.ClassId, Title: str("copy")}, attachments)
+	expectCode(t, err, apperr.CodeConflict)
+}
+
+func TestCreateAssignmentChecks(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	classroom := createClassroom(t, svc, teacher)
+	other := createClassroom(t, svc, teacher)
+
+	due := time.Now().Add(24 * time.Hour)
+	before := due.Add(-time.Hour)
+	penalty := 150.0
+	foreign := store.AddCategory(*other.ClassId)
+
+	for _, tc := range []struct {
+		name     string
+		incoming services.NewAssignment
+		field    string
+	}{
+		{"timezone", services.NewAssignment{DueTimezone: str("Mars/Olympus")}, "due_timezone"},
+		{"close before due", services.NewAssignment{DueAt: &due, CloseAt: &before}, "close_at"},
+		{"late penalty", services.NewAssignment{LatePenalty: &penalty}, "late_penalty"},
+		{"max penalty", services.NewAssignment{MaxPenalty: &penalty}, "max_penalty"},
+		{"category of another classroom", services.NewAssignment{CategoryID: &foreign}, "category_id"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.incoming.ClassID = classroom.ClassId
+			tc.incoming.Title = str("essay")
+
+			_, err := svc.Assignments.Create(teacher, tc.incoming, nil)
+			expectCode(t, err, apperr.CodeValidation)
+
+			if _, ok := apperr.As(err).Fields[tc.field]; !ok {
+				t.Fatalf("expected %s to be reported, got %v", tc.field, apperr.As(err).Fields)
+			}
+		})
+	}
+
+	category := store.AddCategory(*classroom.ClassId)
+	createAssignment(t, svc, teacher, services.NewAssignment{ClassID: classroom.ClassId, Title: str("essay"), CategoryID: &category})
+}
+
+func TestDraftAssignmentsAreHiddenFromStudents(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, teacher)
+	join(t, svc, classroom, student)
+
+	assignment := createAssignment(t, svc, teacher, services.NewAssignment{ClassID: classroom.ClassId, Title: str("essay"), IsDraft: true})
+
+	_, _, _, err := svc.Assignments.Authorize(*assignment.ID, student, services.PermViewAssignments, true)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	_, _, _, err = svc.Assignments.Authorize(*assignment.ID, teacher, services.PermViewAssignments, true)
+	if err != nil {
+		t.Fatalf("expected the teacher to see drafts, got %v", err)
+	}
+
+	err = svc.Assignments.Publish(assignment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = svc.Assignments.Authorize(*assignment.ID, student, services.PermViewAssignments, true)
+	if err != nil {
+		t.Fatalf("expected a published assignment to be visible, got %v", err)
+	}
+
+	err = svc.Assignments.Unpublish(assignment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = svc.Assignments.Authorize(*assignment.ID, student, services.PermViewAssignments, true)
+	expectCode(t, err, apperr.CodeNotFound)
+}
+
+func TestEditAssignment(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	colleague := addUser(store, "colleague")
+	classroom := createClassroom(t, svc, teacher)
+
+	due := time.Now().Add(24 * time.Hour)
+	assignment := createAssignment(t, svc, teacher, services.NewAssignment{ClassID: classroom.ClassId, Title: str("essay"), DueAt: &due})
+
+	// only the author edits
+	err := svc.Assignments.Edit(assignment, colleague, services.AssignmentEdit{Title: str("renamed")})
+	expectCode(t, err, apperr.CodeForbidden)
+
+	err = svc.Assignments.Edit(assignment, teacher, services.AssignmentEdit{})
+	expectCode(t, err, apperr.CodeValidation)
+
+	// the schedule is checked as it will be after the change
+	before := due.Add(-time.Hour)
+	err = svc.Assignments.Edit(assignment, teacher, services.AssignmentEdit{CloseAt: &before})
+	expectCode(t, err, apperr.CodeValidation)
+
+	if assignment.CloseAt != nil {
+		t.Fatal("expected a refused edit to leave the assignment alone")
+	}
+
+	err = svc.Assignments.Edit(assignment, teacher, services.AssignmentEdit{Title: str("renamed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := store.Assignment(*assignment.ID)
+	if *stored.Title != "renamed" || *assignment.Title != "renamed" {
+		t.Fatalf("expected the title to change, got %q", *stored.Title)
+	}
+}
+
+func TestListAssignments(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	classroom := createClassroom(t, svc, teacher)
+
+	createAssignment(t, svc, teacher, services.NewAssignment{ClassID: classroom.ClassId, Title: str("b essay")})
+	createAssignment(t, svc, teacher, services.NewAssignment{ClassID: classroom.ClassId, Title: str("a draft"), IsDraft: true})
+
+	page := services.Page{Limit: 10, Sort: "title"}
+
+	assignments, total, err := svc.Assignments.List(classroom, services.RoleTeacher, services.AssignmentFilter{}, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || *assignments[0].Title != "a draft" {
+		t.Fatalf("expected both assignments by title, got %+v", assignments)
+	}
+
+	// students never see drafts, even when asking for them
+	draft := true
+	assignments, total, _ = svc.Assignments.List(classroom, services.RoleStudent, services.AssignmentFilter{Draft: &draft}, page)
+	if total != 1 || *assignments[0].Title != "b essay" {
+		t.Fatalf("expected only the published assignment, got %+v", assignments)
+	}
+}
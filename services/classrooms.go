@@ -0,0 +1,242 @@
+package services
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+// NewClassroom is what a classroom is created with
+type NewClassroom struct {
+	ClassName   *string
+	Description *string
+	Shared      bool
+}
+
+// ClassroomEdit changes the fields that are set; ownership, join codes and
+// archiving have their own operations
+type ClassroomEdit struct {
+	ClassName   *string
+	Description *string
+	Shared      *bool
+}
+
+// JoinCodeEdit turns a classroom's join code on or off or changes when it
+// expires; NoExpiry makes it last until it is turned off
+type JoinCodeEdit struct {
+	Enabled   *bool
+	ExpiresAt *time.Time
+	NoExpiry  bool
+}
+
+type ClassroomService struct {
+	classrooms ClassroomStore
+	members    MembershipStore
+	work       UnitOfWork
+}
+
+// Create makes a classroom owned by owner, open to join with a fresh code.
+// The owner is also recorded as a teacher of it, in the same transaction so
+// a classroom never exists without its teacher.
+func (s *ClassroomService) Create(owner *models.Users, incoming NewClassroom) (*models.Classroom, error) {
+	joinCode, err := s.classrooms.UnusedJoinCode()
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not generate a join code")
+	}
+
+	classroom := models.Classroom{
+		ClassId:     utils.GenerateClassroomId(),
+		ClassName:   incoming.ClassName,
+		Description: incoming.Description,
+		Shared:      incoming.Shared,
+		OwnerID:     owner.Uuid,
+		JoinCode:    &joinCode,
+		JoinEnabled: true,
+		JoinMode:    models.JoinOpen,
+	}
+
+	collaborator := models.ClassroomCollaborator{
+		ClassID: classroom.ClassId,
+		UserID:  owner.Uuid,
+		Role:    RoleTeacher,
+		Status:  models.MembershipActive,
+	}
+
+	err = s.work.Do(func(stores Stores) error {
+		err := stores.Classrooms.CreateClassroom(&classroom)
+		if err != nil {
+			return err
+		}
+		return stores.Members.CreateMembership(&collaborator)
+	})
+
+	if err != nil {
+		return nil, writeFailed(err, "database insertion failed")
+	}
+	return &classroom, nil
+}
+
+// WithOwner fills in the owner of classroom
+func (s *ClassroomService) WithOwner(classroom *models.Classroom) error {
+	err := s.classrooms.LoadOwner(classroom)
+
+	if err != nil {
+		return apperr.Internal(err, "could not get classroom")
+	}
+	return nil
+}
+
+// Edit applies the fields of incoming that are set to classroom
+func (s *ClassroomService) Edit(classroom *models.Classroom, incoming ClassroomEdit) error {
+	columns := []string{}
+
+	if incoming.ClassName != nil {
+		classroom.ClassName = incoming.ClassName
+		columns = append(columns, "class_name")
+	}
+	if incoming.Description != nil {
+		classroom.Description = incoming.Description
+		columns = append(columns, "description")
+	}
+	if incoming.Shared != nil {
+		classroom.Shared = *incoming.Shared
+		columns = append(columns, "shared")
+	}
+
+	if len(columns) == 0 {
+		return apperr.Validation("nothing to update")
+	}
+
+	err := s.classrooms.UpdateClassroom(classroom, columns...)
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+	return nil
+}
+
+// SetJoinMode chooses how people get into a classroom
+func (s *ClassroomService) SetJoinMode(classroom *models.Classroom, mode string) error {
+	changed := *classroom
+	changed.JoinMode = mode
+
+	err := s.classrooms.UpdateClassroom(&changed, "join_mode")
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*classroom = changed
+	return nil
+}
+
+// EditJoinCode applies the fields of incoming that are set to classroom's join code
+func (s *ClassroomService) EditJoinCode(classroom *models.Classroom, incoming JoinCodeEdit) error {
+	changed := *classroom
+	columns := []string{}
+
+	if incoming.Enabled != nil {
+		changed.JoinEnabled = *incoming.Enabled
+		columns = append(columns, "join_enabled")
+	}
+
+	if incoming.NoExpiry {
+		changed.JoinExpiresAt = nil
+		columns = append(columns, "join_expires_at")
+	} else if incoming.ExpiresAt != nil {
+		if !incoming.ExpiresAt.After(time.Now()) {
+			return apperr.InvalidField("expires_at", "must be in the future")
+		}
+
+		changed.JoinExpiresAt = incoming.ExpiresAt
+		columns = append(columns, "join_expires_at")
+	}
+
+	if len(columns) == 0 {
+		return apperr.Validation("nothing to update")
+	}
+
+	err := s.classrooms.UpdateClassroom(&changed, columns...)
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*classroom = changed
+	return nil
+}
+
+// RegenerateJoinCode replaces a classroom's join code, so the old one stops working
+func (s *ClassroomService) RegenerateJoinCode(classroom *models.Classroom) error {
+	code, err := s.classrooms.UnusedJoinCode()
+
+	if err != nil {
+		return apperr.Internal(err, "could not generate a join code")
+	}
+
+	changed := *classroom
+	changed.JoinCode = &code
+
+	err = s.classrooms.UpdateClassroom(&changed, "join_code")
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*classroom = changed
+	return nil
+}
+
+// List pages through the live classrooms a user owns or is an active member
+// of, with the role they hold in each by classroom id
+func (s *ClassroomService) List(user *models.Users, filter ClassroomFilter, page Page) ([]models.Classroom, map[string]string, int64, error) {
+	switch filter.Role {
+	case "", RoleOwner, RoleTeacher, RoleStudent:
+	default:
+		return nil, nil, 0, apperr.BadRequest("role must be owner, teacher or student")
+	}
+
+	classrooms, total, err := s.classrooms.ListClassrooms(*user.Uuid, filter, page)
+
+	if err != nil {
+		return nil, nil, 0, apperr.Internal(err, "could not get classrooms")
+	}
+
+	roles, err := s.roles(user, classrooms)
+
+	if err != nil {
+		return nil, nil, 0, apperr.Internal(err, "could not get classrooms")
+	}
+	return classrooms, roles, total, nil
+}
+
+// roles resolves the user's role in each of a page of classrooms at once
+func (s *ClassroomService) roles(user *models.Users, classrooms []models.Classroom) (map[string]string, error) {
+	roles := map[string]string{}
+	classIds := []string{}
+
+	for _, classroom := range classrooms {
+		if classroom.OwnerID != nil && *classroom.OwnerID == *user.Uuid {
+			roles[*classroom.ClassId] = RoleOwner
+		} else {
+			classIds = append(classIds, *classroom.ClassId)
+		}
+	}
+
+	if len(classIds) == 0 {
+		return roles, nil
+	}
+
+	memberships, err := s.members.ActiveMemberships(*user.Uuid, classIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, membership := range memberships {
+		roles[*membership.ClassID] = membership.Role
+	}
+	return roles, nil
+}
@@ -0,0 +1,173 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+func TestCreateClassroom(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	classroom := createClassroom(t, svc, owner)
+
+	if classroom.JoinCode == nil || !classroom.JoinEnabled || classroom.JoinMode != models.JoinOpen {
+		t.Fatalf("expected an open classroom with a join code, got %+v", classroom)
+	}
+
+	// the owner is recorded as a teacher too
+	membership, _ := store.Membership(*classroom.ClassId, *owner.Uuid)
+	if membership == nil || membership.Role != services.RoleTeacher || membership.Status != models.MembershipActive {
+		t.Fatalf("expected the owner to be an active teacher, got %+v", membership)
+	}
+
+	role, err := svc.Membership.Role(classroom, owner)
+	if err != nil || role != services.RoleOwner {
+		t.Fatalf("expected the creator to be the owner, got %q, %v", role, err)
+	}
+}
+
+func TestEditClassroom(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	classroom := createClassroom(t, svc, owner)
+
+	err := svc.Classrooms.Edit(classroom, services.ClassroomEdit{})
+	expectCode(t, err, apperr.CodeValidation)
+
+	err = svc.Classrooms.Edit(classroom, services.ClassroomEdit{ClassName: str("physics")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := store.Classroom(*classroom.ClassId)
+	if *stored.ClassName != "physics" || *stored.JoinCode != *classroom.JoinCode {
+		t.Fatalf("expected only the name to change, got %+v", stored)
+	}
+
+	err = svc.Classrooms.WithOwner(stored)
+	if err != nil || stored.Owner.Uuid == nil || *stored.Owner.Uuid != *owner.Uuid {
+		t.Fatalf("expected the owner to be loaded, got %+v, %v", stored.Owner, err)
+	}
+}
+
+func TestListClassrooms(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	student := addUser(store, "student")
+
+	owned := createClassroom(t, svc, owner)
+	joined := createClassroom(t, svc, student)
+	join(t, svc, joined, owner)
+	left := createClassroom(t, svc, student)
+	join(t, svc, left, owner)
+	createClassroom(t, svc, student)
+
+	_, err := svc.Membership.Remove(left, owner, services.RoleStudent, *owner.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := services.Page{Limit: 10, Sort: "created_at"}
+
+	classrooms, roles, total, err := svc.Classrooms.List(owner, services.ClassroomFilter{}, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || roles[*owned.ClassId] != services.RoleOwner || roles[*joined.ClassId] != services.RoleStudent {
+		t.Fatalf("expected the owned and joined classrooms, got %d, %v", len(classrooms), roles)
+	}
+
+	classrooms, _, _, _ = svc.Classrooms.List(owner, services.ClassroomFilter{Role: services.RoleStudent}, page)
+	if len(classrooms) != 1 || *classrooms[0].ClassId != *joined.ClassId {
+		t.Fatalf("expected only the joined classroom, got %+v", classrooms)
+	}
+
+	// a cursor continues after the row it names
+	page.Limit = 1
+	classrooms, _, _, _ = svc.Classrooms.List(owner, services.ClassroomFilter{}, page)
+	page.After = &services.Cursor{CreatedAt: classrooms[0].CreatedAt, ID: *classrooms[0].ClassId}
+
+	next, _, _, _ := svc.Classrooms.List(owner, services.ClassroomFilter{}, page)
+	if len(next) != 1 || *next[0].ClassId == *classrooms[0].ClassId {
+		t.Fatalf("expected the other classroom after the cursor, got %+v", next)
+	}
+
+	_, _, _, err = svc.Classrooms.List(owner, services.ClassroomFilter{Role: "admin"}, page)
+	expectCode(t, err, apperr.CodeBadRequest)
+}
+
+func TestSetJoinMode(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	classroom := createClassroom(t, svc, owner)
+
+	err := svc.Classrooms.SetJoinMode(classroom, models.JoinApproval)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := store.Classroom(*classroom.ClassId)
+	if stored.JoinMode != models.JoinApproval || classroom.JoinMode != models.JoinApproval {
+		t.Fatalf("expected the join mode to change, got %q", stored.JoinMode)
+	}
+}
+
+func TestEditJoinCode(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	classroom := createClassroom(t, svc, owner)
+
+	err := svc.Classrooms.EditJoinCode(classroom, services.JoinCodeEdit{})
+	expectCode(t, err, apperr.CodeValidation)
+
+	past := time.Now().Add(-time.Hour)
+	err = svc.Classrooms.EditJoinCode(classroom, services.JoinCodeEdit{ExpiresAt: &past})
+	expectCode(t, err, apperr.CodeValidation)
+
+	off, later := false, time.Now().Add(time.Hour)
+	if err = svc.Classrooms.EditJoinCode(classroom, services.JoinCodeEdit{Enabled: &off, ExpiresAt: &later}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := store.Classroom(*classroom.ClassId)
+	if stored.JoinEnabled || stored.JoinExpiresAt == nil || !stored.JoinExpiresAt.Equal(later) {
+		t.Fatalf("expected a disabled join code expiring later, got %+v", stored)
+	}
+
+	if err = svc.Classrooms.EditJoinCode(classroom, services.JoinCodeEdit{NoExpiry: true, ExpiresAt: &later}); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ = store.Classroom(*classroom.ClassId); stored.JoinExpiresAt != nil || stored.JoinEnabled {
+		t.Fatalf("expected only the expiry to be cleared, got %+v", stored)
+	}
+}
+
+func TestRegenerateJoinCode(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, owner)
+	old := *classroom.JoinCode
+
+	if err := svc.Classrooms.RegenerateJoinCode(classroom); err != nil {
+		t.Fatal(err)
+	}
+	if *classroom.JoinCode == old {
+		t.Fatal("expected a new join code")
+	}
+
+	_, err := svc.Membership.Join(student, old)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	join(t, svc, classroom, student)
+}
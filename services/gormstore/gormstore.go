@@ -0,0 +1,614 @@
+// Package gormstore implements the service stores on Postgres through GORM
+package gormstore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/storage"
+	"gorm.io/gorm"
+)
+
+// Store implements every services store on one database handle
+type Store struct {
+	db *gorm.DB
+}
+
+// New returns the stores backed by db, which should translate errors so
+// unique violations can be told apart
+func New(db *gorm.DB) services.Stores {
+	store := &Store{db: db}
+
+	return services.Stores{
+		Users:         store,
+		Sessions:      store,
+		Classrooms:    store,
+		Members:       store,
+		Assignments:   store,
+		Notifications: store,
+		Invitations:   store,
+		Work:          store,
+	}
+}
+
+// Do runs fn on stores bound to a transaction, retried on serialization failures
+func (s *Store) Do(fn func(stores services.Stores) error) error {
+	return storage.Transaction(s.db, func(tx *gorm.DB) error {
+		return fn(New(tx))
+	})
+}
+
+// translate turns unique violations into services.ErrDuplicate
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return fmt.Errorf("%w: %v", services.ErrDuplicate, err)
+	}
+	return err
+}
+
+// count counts every row of the query, ignoring any order or limit on it
+func count(query *gorm.DB) (int64, error) {
+	var total int64
+
+	err := query.Session(&gorm.Session{}).Count(&total).Error
+	return total, err
+}
+
+// activeMembership limits a collaborator query to people who are in the
+// classroom right now
+func activeMembership(query *gorm.DB) *gorm.DB {
+	return query.Where("classroom_collaborators.is_removed = ? AND classroom_collaborators.status = ?", false, models.MembershipActive)
+}
+
+// first loads the first row matching the query into dest, reporting false
+// when there is none
+func first(query *gorm.DB, dest interface{}) (bool, error) {
+	result := query.Limit(1).Find(dest)
+	return result.RowsAffected != 0, result.Error
+}
+
+/*------------------------------------------------ users ------------------------------------------------------*/
+
+func (s *Store) UsernameTaken(username string) (bool, error) {
+	var count int64
+
+	err := s.db.Model(&models.Users{}).Where("lower(username) = lower(?)", username).Count(&count).Error
+	return count != 0, err
+}
+
+func (s *Store) UserByUsername(username string) (*models.Users, error) {
+	user := models.Users{}
+
+	found, err := first(s.db.Where("lower(username) = lower(?)", username), &user)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *Store) UserByID(userId string) (*models.Users, error) {
+	user := models.Users{}
+
+	query := s.db.Preload("Collaborations", "is_removed = ? AND status = ?", false, models.MembershipActive).Where("uuid = ?", userId)
+
+	found, err := first(query, &user)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *Store) CreateUser(user *models.Users) error {
+	return translate(s.db.Create(user).Error)
+}
+
+func (s *Store) SetPassword(userId string, passwordHash string) error {
+	return s.db.Model(&models.Users{}).Where("uuid = ?", userId).Update("password", passwordHash).Error
+}
+
+/*------------------------------------------------ sessions ------------------------------------------------------*/
+
+func (s *Store) CreateSession(session *models.Session) error {
+	return translate(s.db.Create(session).Error)
+}
+
+func (s *Store) LiveSession(id string, userId string, now time.Time) (*models.Session, error) {
+	session := models.Session{}
+
+	found, err := first(s.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userId, now), &session)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *Store) SessionByToken(tokenHash string) (*models.Session, error) {
+	session := models.Session{}
+
+	found, err := first(s.db.Where("refresh_token_hash = ?", tokenHash), &session)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *Store) SessionByPreviousToken(tokenHash string) (*models.Session, error) {
+	session := models.Session{}
+
+	found, err := first(s.db.Where("previous_token_hash = ?", tokenHash), &session)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *Store) RotateToken(id string, from string, to string, at time.Time) (bool, error) {
+	res := s.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", id, from).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  to,
+			"previous_token_hash": from,
+			"last_used_at":        at,
+		})
+	return res.RowsAffected != 0, translate(res.Error)
+}
+
+func (s *Store) RevokeSessions(userId string, id string, at time.Time) (int64, error) {
+	query := s.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userId)
+	if len(id) != 0 {
+		query = query.Where("id = ?", id)
+	}
+
+	res := query.Update("revoked_at", at)
+	return res.RowsAffected, res.Error
+}
+
+func (s *Store) ListSessions(userId string, now time.Time, page services.Page) ([]models.Session, int64, error) {
+	query := s.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, now)
+
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sessions := []models.Session{}
+
+	err = Paginate(query, SessionList, page).Find(&sessions).Error
+	return sessions, total, err
+}
+
+/*------------------------------------------------ classrooms ------------------------------------------------------*/
+
+func (s *Store) Classroom(classId string) (*models.Classroom, error) {
+	classroom := models.Classroom{}
+
+	found, err := first(s.db.Where("class_id = ? AND is_deleted = ?", classId, false), &classroom)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &classroom, nil
+}
+
+func (s *Store) LoadOwner(classroom *models.Classroom) error {
+	if classroom.OwnerID == nil {
+		return nil
+	}
+	return s.db.Where("uuid = ?", classroom.OwnerID).First(&classroom.Owner).Error
+}
+
+func (s *Store) ClassroomByJoinCode(code string) (*models.Classroom, error) {
+	classroom := models.Classroom{}
+
+	found, err := first(s.db.Where("join_code = ? AND is_deleted = ?", code, false), &classroom)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &classroom, nil
+}
+
+func (s *Store) UnusedJoinCode() (string, error) {
+	return models.UnusedJoinCode(s.db)
+}
+
+func (s *Store) ListClassrooms(userId string, filter services.ClassroomFilter, page services.Page) ([]models.Classroom, int64, error) {
+	// every live classroom the user owns or is an active member of
+	query := s.db.Model(&models.Classroom{}).
+		Joins("LEFT JOIN classroom_collaborators AS memberships ON memberships.class_id = classrooms.class_id AND memberships.user_id = ? AND memberships.is_removed = ? AND memberships.status = ?", userId, false, models.MembershipActive).
+		Where("classrooms.is_deleted = ? AND (classrooms.owner_id = ? OR memberships.user_id IS NOT NULL)", false, userId)
+
+	switch filter.Role {
+	case "":
+	case services.RoleOwner:
+		query = query.Where("classrooms.owner_id = ?", userId)
+	default:
+		query = query.Where("classrooms.owner_id <> ? AND memberships.role = ?", userId, filter.Role)
+	}
+
+	if filter.Done != nil {
+		query = query.Where("classrooms.done = ?", *filter.Done)
+	}
+
+	if len(filter.Search) != 0 {
+		query = query.Where("classrooms.class_name ILIKE ?", "%"+filter.Search+"%")
+	}
+
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	classrooms := []models.Classroom{}
+
+	err = Paginate(query.Select("classrooms.*").Preload("Owner"), ClassroomList, page).Find(&classrooms).Error
+	return classrooms, total, err
+}
+
+func (s *Store) CreateClassroom(classroom *models.Classroom) error {
+	return translate(s.db.Create(classroom).Error)
+}
+
+func (s *Store) UpdateClassroom(classroom *models.Classroom, columns ...string) error {
+	return translate(s.db.Model(classroom).Select(columns).Updates(classroom).Error)
+}
+
+func (s *Store) ChangeOwner(classId string, from string, to string) (bool, error) {
+	res := s.db.Model(&models.Classroom{}).
+		Where("class_id = ? AND owner_id = ?", classId, from).
+		Update("owner_id", to)
+	return res.RowsAffected != 0, res.Error
+}
+
+func (s *Store) TrashedClassroom(classId string, ownerId string) (*models.Classroom, error) {
+	classroom := models.Classroom{}
+
+	found, err := first(s.db.Where("class_id = ? AND owner_id = ? AND is_deleted = ?", classId, ownerId, true), &classroom)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &classroom, nil
+}
+
+func (s *Store) ListTrash(ownerId string, page services.Page) ([]models.Classroom, int64, error) {
+	query := s.db.Model(&models.Classroom{}).Where("owner_id = ? AND is_deleted = ?", ownerId, true)
+
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	classrooms := []models.Classroom{}
+
+	err = Paginate(query, TrashList, page).Find(&classrooms).Error
+	return classrooms, total, err
+}
+
+func (s *Store) ExpiredTrash(cutoff time.Time) ([]string, error) {
+	classIds := []string{}
+
+	err := s.db.Model(&models.Classroom{}).
+		Where("is_deleted = ? AND trashed_at < ?", true, cutoff).
+		Pluck("class_id", &classIds).Error
+	return classIds, err
+}
+
+func (s *Store) PurgeClassroom(classId string, cutoff time.Time) (bool, []string, error) {
+	hashes := []string{}
+
+	err := s.db.Model(&models.Attachment{}).Where("class_id = ?", classId).Distinct().Pluck("hash", &hashes).Error
+	if err != nil {
+		return false, nil, err
+	}
+
+	res := s.db.Where("class_id = ? AND is_deleted = ? AND trashed_at < ?", classId, true, cutoff).Delete(&models.Classroom{})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, nil, res.Error
+	}
+
+	// attachments only point at their owners loosely, so they do not cascade
+	err = s.db.Where("class_id = ?", classId).Delete(&models.Attachment{}).Error
+	if err != nil {
+		return false, nil, err
+	}
+
+	// neither do notifications, which belong to their users
+	err = s.db.Where("class_id = ?", classId).Delete(&models.Notification{}).Error
+	if err != nil {
+		return false, nil, err
+	}
+	return true, hashes, nil
+}
+
+/*------------------------------------------------ membership ------------------------------------------------------*/
+
+func (s *Store) Membership(classId string, userId string) (*models.ClassroomCollaborator, error) {
+	membership := models.ClassroomCollaborator{}
+
+	found, err := first(s.db.Where("class_id = ? AND user_id = ?", classId, userId), &membership)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (s *Store) ActiveMember(classId string, userId string) (*models.ClassroomCollaborator, error) {
+	membership := models.ClassroomCollaborator{}
+
+	query := s.db.Preload("User").Scopes(activeMembership).Where("class_id = ? AND user_id = ?", classId, userId)
+
+	found, err := first(query, &membership)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (s *Store) ActiveMemberships(userId string, classIds []string) ([]models.ClassroomCollaborator, error) {
+	memberships := []models.ClassroomCollaborator{}
+
+	err := s.db.Scopes(activeMembership).Where("user_id = ? AND class_id IN ?", userId, classIds).Find(&memberships).Error
+	return memberships, err
+}
+
+func (s *Store) ListMembers(classId string, filter services.MemberFilter, page services.Page) ([]models.ClassroomCollaborator, int64, error) {
+	query := s.db.Model(&models.ClassroomCollaborator{}).
+		Joins("JOIN users ON users.uuid = classroom_collaborators.user_id").
+		Scopes(activeMembership).
+		Where("classroom_collaborators.class_id = ?", classId)
+
+	if len(filter.Role) != 0 {
+		query = query.Where("classroom_collaborators.role = ?", filter.Role)
+	}
+
+	if len(filter.Search) != 0 {
+		query = query.Where("(users.name ILIKE ? OR users.username ILIKE ?)", "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	members := []models.ClassroomCollaborator{}
+
+	err = Paginate(query.Select("classroom_collaborators.*").Preload("User"), MemberList, page).Find(&members).Error
+	return members, total, err
+}
+
+func (s *Store) ListJoinRequests(classId string, page services.Page) ([]models.ClassroomCollaborator, int64, error) {
+	query := s.db.Model(&models.ClassroomCollaborator{}).
+		Joins("JOIN users ON users.uuid = classroom_collaborators.user_id").
+		Where("classroom_collaborators.class_id = ? AND classroom_collaborators.status = ?", classId, models.MembershipPending)
+
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	requests := []models.ClassroomCollaborator{}
+
+	err = Paginate(query.Select("classroom_collaborators.*").Preload("User"), JoinRequestList, page).Find(&requests).Error
+	return requests, total, err
+}
+
+func (s *Store) JoinRequests(classId string, userIds []string) ([]models.ClassroomCollaborator, error) {
+	query := s.db.Preload("User").Where("class_id = ? AND status = ?", classId, models.MembershipPending)
+	if userIds != nil {
+		query = query.Where("user_id IN ?", userIds)
+	}
+
+	requests := []models.ClassroomCollaborator{}
+
+	err := query.Order("requested_at asc").Find(&requests).Error
+	return requests, err
+}
+
+func (s *Store) DecideJoinRequests(classId string, userIds []string, status string, decidedBy string, decidedAt time.Time) error {
+	return s.db.Model(&models.ClassroomCollaborator{}).
+		Where("class_id = ? AND status = ? AND user_id IN ?", classId, models.MembershipPending, userIds).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"decided_at": decidedAt,
+			"decided_by": decidedBy,
+		}).Error
+}
+
+func (s *Store) CreateMembership(membership *models.ClassroomCollaborator) error {
+	return translate(s.db.Create(membership).Error)
+}
+
+func (s *Store) UpdateMembership(membership *models.ClassroomCollaborator, columns ...string) error {
+	return translate(s.db.Model(membership).Select(columns).Updates(membership).Error)
+}
+
+/*------------------------------------------------ assignments ------------------------------------------------------*/
+
+func (s *Store) Assignment(id string) (*models.Assignments, error) {
+	assignment := models.Assignments{}
+
+	found, err := first(s.db.Where("id = ? AND is_deleted = ?", id, false), &assignment)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (s *Store) ListAssignments(classId string, filter services.AssignmentFilter, page services.Page) ([]models.Assignments, int64, error) {
+	query := s.db.Model(&models.Assignments{}).Where("class_id = ? AND is_deleted = ?", classId, false)
+
+	if filter.PublishedBy != nil {
+		query = query.Where("is_draft = ? AND (publish_at IS NULL OR publish_at <= ?)", false, *filter.PublishedBy)
+	}
+
+	if filter.Draft != nil {
+		query = query.Where("is_draft = ?", *filter.Draft)
+	}
+
+	if len(filter.Type) != 0 {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	if filter.CategoryID != nil {
+		query = query.Where("category_id = ?", *filter.CategoryID)
+	}
+
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	assignments := []models.Assignments{}
+
+	err = Paginate(query.Preload("Classroom").Preload("CreatedBy"), AssignmentList, page).Find(&assignments).Error
+	return assignments, total, err
+}
+
+func (s *Store) CategoryInClassroom(classId string, categoryId uint) (bool, error) {
+	var count int64
+
+	err := s.db.Model(&models.GradeCategory{}).Where("id = ? AND class_id = ?", categoryId, classId).Count(&count).Error
+	return count != 0, err
+}
+
+func (s *Store) CreateAssignment(assignment *models.Assignments) error {
+	return translate(s.db.Create(assignment).Error)
+}
+
+func (s *Store) UpdateAssignment(assignment *models.Assignments, columns ...string) error {
+	return translate(s.db.Model(assignment).Select(columns).Updates(assignment).Error)
+}
+
+func (s *Store) CreateAttachments(attachments []models.Attachment) error {
+	return translate(s.db.Create(&attachments).Error)
+}
+
+/*------------------------------------------------ notifications ------------------------------------------------------*/
+
+func (s *Store) Notify(userIds []string, classId *string, kind string, message string) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	notifications := make([]models.Notification, 0, len(userIds))
+	for i := range userIds {
+		notifications = append(notifications, models.Notification{
+			UserID:  &userIds[i],
+			ClassID: classId,
+			Type:    kind,
+			Message: message,
+		})
+	}
+
+	return s.db.Create(&notifications).Error
+}
+
+func (s *Store) ListNotifications(userId string, unread bool, page services.Page) ([]models.Notification, int64, error) {
+	query := s.db.Model(&models.Notification{}).Where("user_id = ?", userId)
+
+	if unread {
+		query = query.Where("read_at IS NULL")
+	}
+
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notifications := []models.Notification{}
+
+	err = Paginate(query, NotificationList, page).Find(&notifications).Error
+	return notifications, total, err
+}
+
+func (s *Store) CountUnread(userId string) (int64, error) {
+	return count(s.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userId))
+}
+
+func (s *Store) MarkRead(userId string, id uint, at time.Time) (bool, error) {
+	res := s.db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userId).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", at))
+	return res.RowsAffected != 0, res.Error
+}
+
+func (s *Store) MarkAllRead(userId string, at time.Time) (int64, error) {
+	res := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userId).
+		Update("read_at", at)
+	return res.RowsAffected, res.Error
+}
+
+/*------------------------------------------------ invitations ------------------------------------------------------*/
+
+func (s *Store) ClassInvitation(classId string, id string) (*models.Invitation, error) {
+	invitation := models.Invitation{}
+
+	found, err := first(s.db.Where("id = ? AND class_id = ?", id, classId), &invitation)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (s *Store) InvitationByToken(token string) (*models.Invitation, error) {
+	invitation := models.Invitation{}
+
+	found, err := first(s.db.Preload("Classroom").Where("token = ?", token), &invitation)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// pageInvitations counts the invitations of a query and loads a page of them
+func pageInvitations(query *gorm.DB, page services.Page) ([]models.Invitation, int64, error) {
+	total, err := count(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	invitations := []models.Invitation{}
+
+	err = Paginate(query.Select("invitations.*"), InvitationList, page).Find(&invitations).Error
+	return invitations, total, err
+}
+
+func (s *Store) ListInvitations(classId string, page services.Page) ([]models.Invitation, int64, error) {
+	query := s.db.Model(&models.Invitation{}).Preload("Invitee").
+		Where("class_id = ? AND revoked_at IS NULL", classId)
+
+	return pageInvitations(query, page)
+}
+
+func (s *Store) ListPendingInvitations(userId string, now time.Time, page services.Page) ([]models.Invitation, int64, error) {
+	query := s.db.Model(&models.Invitation{}).Preload("Classroom").
+		Joins("JOIN classrooms ON classrooms.class_id = invitations.class_id AND classrooms.is_deleted = ?", false).
+		Where("invitations.invitee_id = ? AND invitations.revoked_at IS NULL AND invitations.declined_at IS NULL", userId).
+		Where("invitations.uses = 0 AND (invitations.expires_at IS NULL OR invitations.expires_at > ?)", now)
+
+	return pageInvitations(query, page)
+}
+
+func (s *Store) CreateInvitation(invitation *models.Invitation) error {
+	return translate(s.db.Omit("Classroom", "Invitee").Create(invitation).Error)
+}
+
+func (s *Store) UpdateInvitation(invitation *models.Invitation, columns ...string) error {
+	return translate(s.db.Model(invitation).Select(columns).Updates(invitation).Error)
+}
+
+func (s *Store) UseInvitation(id string) (bool, error) {
+	res := s.db.Model(&models.Invitation{}).
+		Where("id = ? AND revoked_at IS NULL AND declined_at IS NULL AND (max_uses IS NULL OR uses < max_uses)", id).
+		Update("uses", gorm.Expr("uses + 1"))
+	return res.RowsAffected != 0, res.Error
+}
+
+func (s *Store) DeclineInvitation(id string, at time.Time) (bool, error) {
+	res := s.db.Model(&models.Invitation{}).
+		Where("id = ? AND revoked_at IS NULL AND declined_at IS NULL AND uses = 0", id).
+		Update("declined_at", at)
+	return res.RowsAffected != 0, res.Error
+}
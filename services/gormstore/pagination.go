@@ -0,0 +1,126 @@
+package gormstore
+
+import (
+	"strconv"
+
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"gorm.io/gorm"
+)
+
+// ListSpec describes how a list pages through its table: the columns it can
+// be sorted by, keyed by the field name clients use, and the id column that
+// breaks ties between equal sort values
+type ListSpec struct {
+	Table       string
+	IDColumn    string
+	NumericID   bool
+	Sorts       map[string]string
+	DefaultSort string
+}
+
+// the lists the stores page through
+var (
+	ClassroomList = ListSpec{
+		Table:    "classrooms",
+		IDColumn: "class_id",
+		Sorts: map[string]string{
+			"created_at": "classrooms.created_at",
+			"class_name": "classrooms.class_name",
+		},
+		DefaultSort: "-created_at",
+	}
+	MemberList = ListSpec{
+		Table:    "classroom_collaborators",
+		IDColumn: "user_id",
+		Sorts: map[string]string{
+			"name":     "users.name",
+			"username": "users.username",
+			"role":     "classroom_collaborators.role",
+		},
+		DefaultSort: "name",
+	}
+	JoinRequestList = ListSpec{
+		Table:    "classroom_collaborators",
+		IDColumn: "user_id",
+		Sorts: map[string]string{
+			"requested_at": "classroom_collaborators.requested_at",
+			"name":         "users.name",
+			"username":     "users.username",
+		},
+		DefaultSort: "requested_at",
+	}
+	AssignmentList = ListSpec{
+		Table:    "assignments",
+		IDColumn: "id",
+		Sorts: map[string]string{
+			"created_at": "assignments.created_at",
+			"due_at":     "assignments.due_at",
+			"title":      "assignments.title",
+		},
+		DefaultSort: "-created_at",
+	}
+	SessionList = ListSpec{
+		Table:    "sessions",
+		IDColumn: "id",
+		Sorts: map[string]string{
+			"last_used_at": "sessions.last_used_at",
+			"created_at":   "sessions.created_at",
+		},
+		DefaultSort: "-last_used_at",
+	}
+	TrashList = ListSpec{
+		Table:    "classrooms",
+		IDColumn: "class_id",
+		Sorts: map[string]string{
+			"trashed_at": "classrooms.trashed_at",
+			"created_at": "classrooms.created_at",
+			"class_name": "classrooms.class_name",
+		},
+		DefaultSort: "-trashed_at",
+	}
+	InvitationList = ListSpec{
+		Table:    "invitations",
+		IDColumn: "id",
+		Sorts: map[string]string{
+			"created_at": "invitations.created_at",
+			"expires_at": "invitations.expires_at",
+		},
+		DefaultSort: "-created_at",
+	}
+	NotificationList = ListSpec{
+		Table:       "notifications",
+		IDColumn:    "id",
+		NumericID:   true,
+		Sorts:       map[string]string{"created_at": "notifications.created_at"},
+		DefaultSort: "-created_at",
+	}
+)
+
+// Paginate orders and limits the query to the requested page, fetching one
+// row past the limit so the caller can tell whether another page exists. A
+// cursor continues after the (created_at, id) it names.
+func Paginate(query *gorm.DB, spec ListSpec, page services.Page) *gorm.DB {
+	column := spec.Sorts[page.Sort]
+	id := spec.Table + "." + spec.IDColumn
+
+	direction, comparison := " asc", ">"
+	if page.Desc {
+		direction, comparison = " desc", "<"
+	}
+
+	if page.After != nil {
+		var cursorID interface{} = page.After.ID
+		if spec.NumericID {
+			cursorID, _ = strconv.ParseUint(page.After.ID, 10, 64)
+		}
+
+		query = query.Where("("+column+", "+id+") "+comparison+" (?, ?)", page.After.CreatedAt, cursorID)
+	} else {
+		query = query.Offset(page.Offset)
+	}
+
+	return query.
+		Order(column + direction).
+		Order(id + direction).
+		Limit(page.Limit + 1)
+}
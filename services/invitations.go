@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+// how long an invitation lasts when it is created without an expiry
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+var errInvitationUsed = errors.New("invitation is no longer valid")
+
+// NewInvitation is what an invitation is created with. Naming a user makes
+// it personal and single use; without one it is a shareable link.
+type NewInvitation struct {
+	Role      string
+	Username  *string
+	MaxUses   *int
+	ExpiresAt *time.Time
+}
+
+// InvitationService creates and manages invitations into classrooms;
+// accepting one is part of MembershipService
+type InvitationService struct {
+	invitations InvitationStore
+	users       UserStore
+}
+
+/*------------------------------------------------ teacher side ------------------------------------------------------*/
+
+// Create invites people into a classroom on behalf of inviter, who holds
+// role in it. The role invited defaults to student.
+func (s *InvitationService) Create(classroom *models.Classroom, inviter *models.Users, role string, incoming NewInvitation) (*models.Invitation, error) {
+	if len(incoming.Role) == 0 {
+		incoming.Role = RoleStudent
+	}
+
+	if incoming.Role == RoleTeacher && !Can(role, PermInviteTeacher) {
+		return nil, apperr.Forbidden("only the owner can invite teachers")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultInvitationTTL)
+
+	if incoming.ExpiresAt != nil {
+		if !incoming.ExpiresAt.After(now) {
+			return nil, apperr.InvalidField("expires_at", "must be in the future")
+		}
+		expiresAt = *incoming.ExpiresAt
+	}
+
+	id, _ := utils.GenerateUUid()
+	token, err := utils.GenerateInviteToken()
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not create invitation")
+	}
+
+	invitation := models.Invitation{
+		ID:        &id,
+		ClassID:   classroom.ClassId,
+		Token:     token,
+		Role:      incoming.Role,
+		MaxUses:   incoming.MaxUses,
+		ExpiresAt: &expiresAt,
+		CreatedBy: inviter.Uuid,
+	}
+
+	if incoming.Username != nil {
+		invitee, err := s.users.UserByUsername(*incoming.Username)
+
+		if err != nil {
+			return nil, apperr.Internal(err, "could not create invitation")
+		}
+
+		if invitee == nil {
+			return nil, apperr.InvalidField("username", "does not belong to any user")
+		}
+
+		single := 1
+		invitation.InviteeID = invitee.Uuid
+		invitation.MaxUses = &single
+		invitation.Invitee = *invitee
+	}
+
+	err = s.invitations.CreateInvitation(&invitation)
+
+	if err != nil {
+		return nil, writeFailed(err, "database insertion failed")
+	}
+	return &invitation, nil
+}
+
+// ForClassroom pages through the invitations of a classroom that were not revoked
+func (s *InvitationService) ForClassroom(classroom *models.Classroom, page Page) ([]models.Invitation, int64, error) {
+	invitations, total, err := s.invitations.ListInvitations(*classroom.ClassId, page)
+
+	if err != nil {
+		return nil, 0, apperr.Internal(err, "could not get invitations")
+	}
+	return invitations, total, nil
+}
+
+// Revoke stops an invitation into a classroom from being accepted, for
+// someone holding role in the classroom. Revoking it again changes nothing.
+func (s *InvitationService) Revoke(classroom *models.Classroom, role string, invitationId string) (*models.Invitation, error) {
+	invitation, err := s.invitations.ClassInvitation(*classroom.ClassId, invitationId)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not get invitation")
+	}
+
+	if invitation == nil {
+		return nil, apperr.NotFound("invitation not found")
+	}
+
+	if invitation.Role == RoleTeacher && !Can(role, PermInviteTeacher) {
+		return nil, apperr.Forbidden("only the owner can revoke teacher invitations")
+	}
+
+	if invitation.RevokedAt != nil {
+		return invitation, nil
+	}
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+
+	err = s.invitations.UpdateInvitation(invitation, "revoked_at")
+
+	if err != nil {
+		return nil, writeFailed(err, "database update failed")
+	}
+	return invitation, nil
+}
+
+/*------------------------------------------------ invitee side ------------------------------------------------------*/
+
+// Pending pages through the personal invitations still waiting on a user
+func (s *InvitationService) Pending(user *models.Users, page Page) ([]models.Invitation, int64, error) {
+	invitations, total, err := s.invitations.ListPendingInvitations(*user.Uuid, time.Now(), page)
+
+	if err != nil {
+		return nil, 0, apperr.Internal(err, "could not get invitations")
+	}
+	return invitations, total, nil
+}
+
+// Find loads an invitation into a live classroom by its token, with the
+// classroom, for user to look at before accepting or declining it
+func (s *InvitationService) Find(user *models.Users, token string) (*models.Invitation, error) {
+	invitation, err := s.invitations.InvitationByToken(token)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not get invitation")
+	}
+
+	// personal invitations do not exist for anyone but the invitee
+	if invitation == nil || invitation.Classroom.IsDeleted || (invitation.InviteeID != nil && *invitation.InviteeID != *user.Uuid) {
+		return nil, apperr.NotFound("invitation not found")
+	}
+	return invitation, nil
+}
+
+// Decline turns down a personal invitation found for its invitee
+func (s *InvitationService) Decline(invitation *models.Invitation) error {
+	if invitation.InviteeID == nil {
+		return apperr.Conflict("only personal invitations can be declined")
+	}
+
+	now := time.Now()
+
+	if !invitation.Usable(now) {
+		return apperr.Gone(errInvitationUsed.Error())
+	}
+
+	declined, err := s.invitations.DeclineInvitation(*invitation.ID, now)
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	// it was accepted or revoked while we looked at it
+	if !declined {
+		return apperr.Gone(errInvitationUsed.Error())
+	}
+
+	invitation.DeclinedAt = &now
+	return nil
+}
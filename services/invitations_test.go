@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+func TestCreateInvitation(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	invitee := addUser(store, "Invitee")
+	classroom := createClassroom(t, svc, owner)
+
+	// teachers can invite students but not other teachers
+	_, err := svc.Invitations.Create(classroom, owner, services.RoleTeacher, services.NewInvitation{Role: services.RoleTeacher})
+	expectCode(t, err, apperr.CodeForbidden)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{ExpiresAt: &past})
+	expectCode(t, err, apperr.CodeValidation)
+
+	_, err = svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{Username: str("nobody")})
+	expectCode(t, err, apperr.CodeValidation)
+
+	link, err := svc.Invitations.Create(classroom, owner, services.RoleTeacher, services.NewInvitation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Role != services.RoleStudent || link.InviteeID != nil || link.MaxUses != nil || link.ExpiresAt == nil || len(link.Token) == 0 {
+		t.Fatalf("expected an unlimited student link that expires, got %+v", link)
+	}
+
+	// naming someone makes the invitation theirs alone, whatever the limit asked for
+	many := 5
+	personal, err := svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{Role: services.RoleTeacher, Username: str("invitee"), MaxUses: &many})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if personal.InviteeID == nil || *personal.InviteeID != *invitee.Uuid || *personal.MaxUses != 1 {
+		t.Fatalf("expected a single use invitation for the invitee, got %+v", personal)
+	}
+
+	if stored := store.Invitation(*personal.ID); stored == nil || stored.Token != personal.Token {
+		t.Fatalf("expected the invitation to be stored, got %+v", stored)
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	classroom := createClassroom(t, svc, owner)
+	other := createClassroom(t, svc, owner)
+
+	invitation, err := svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{Role: services.RoleTeacher})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = svc.Invitations.Revoke(other, services.RoleOwner, *invitation.ID)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	_, err = svc.Invitations.Revoke(classroom, services.RoleTeacher, *invitation.ID)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	revoked, err := svc.Invitations.Revoke(classroom, services.RoleOwner, *invitation.ID)
+	if err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("expected the invitation to be revoked, got %+v, %v", revoked, err)
+	}
+
+	// revoking it again keeps the time it was first revoked
+	again, err := svc.Invitations.Revoke(classroom, services.RoleOwner, *invitation.ID)
+	if err != nil || !again.RevokedAt.Equal(*revoked.RevokedAt) {
+		t.Fatalf("expected the invitation to stay revoked as it was, got %+v, %v", again, err)
+	}
+
+	listed, total, err := svc.Invitations.ForClassroom(classroom, services.Page{Limit: 10, Sort: "created_at"})
+	if err != nil || total != 0 || len(listed) != 0 {
+		t.Fatalf("expected revoked invitations to be left out, got %d, %v", total, err)
+	}
+}
+
+func TestFindInvitation(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	invitee := addUser(store, "invitee")
+	stranger := addUser(store, "stranger")
+	classroom := createClassroom(t, svc, owner)
+
+	link, _ := svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{})
+	personal, _ := svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{Username: invitee.Username})
+
+	found, err := svc.Invitations.Find(stranger, link.Token)
+	if err != nil || found.Classroom.ClassId == nil || *found.Classroom.ClassId != *classroom.ClassId {
+		t.Fatalf("expected anyone to find a link with its classroom, got %+v, %v", found, err)
+	}
+
+	// personal invitations do not exist for anyone but the invitee
+	_, err = svc.Invitations.Find(stranger, personal.Token)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	if _, err = svc.Invitations.Find(invitee, personal.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = svc.Invitations.Find(invitee, "nope")
+	expectCode(t, err, apperr.CodeNotFound)
+
+	if err = svc.Classrooms.Trash(classroom); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Invitations.Find(stranger, link.Token)
+	expectCode(t, err, apperr.CodeNotFound)
+}
+
+func TestDeclineInvitation(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	invitee := addUser(store, "invitee")
+	classroom := createClassroom(t, svc, owner)
+
+	link, _ := svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{})
+	err := svc.Invitations.Decline(link)
+	expectCode(t, err, apperr.CodeConflict)
+
+	page := services.Page{Limit: 10, Sort: "created_at"}
+
+	personal, _ := svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{Username: invitee.Username})
+	if pending, total, _ := svc.Invitations.Pending(invitee, page); total != 1 || *pending[0].ID != *personal.ID {
+		t.Fatalf("expected the invitation to wait on the invitee, got %d", total)
+	}
+
+	if err = svc.Invitations.Decline(personal); err != nil {
+		t.Fatal(err)
+	}
+	if stored := store.Invitation(*personal.ID); stored.DeclinedAt == nil {
+		t.Fatal("expected the invitation to be declined")
+	}
+	if _, total, _ := svc.Invitations.Pending(invitee, page); total != 0 {
+		t.Fatalf("expected nothing to wait on the invitee, got %d", total)
+	}
+
+	// the copy was loaded before the invitee accepted it elsewhere
+	accepted, _ := svc.Invitations.Create(classroom, owner, services.RoleOwner, services.NewInvitation{Username: invitee.Username})
+	store.AddInvitation(models.Invitation{ID: accepted.ID, ClassID: accepted.ClassID, Token: accepted.Token, Role: accepted.Role, InviteeID: accepted.InviteeID, MaxUses: accepted.MaxUses, Uses: 1})
+
+	err = svc.Invitations.Decline(accepted)
+	expectCode(t, err, apperr.CodeGone)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// TrashRetention is how long a deleted classroom stays in the trash before
+// it is purged
+const TrashRetention = 30 * 24 * time.Hour
+
+// SetArchived archives a classroom, leaving it visible but read-only, or
+// makes an archived one editable again
+func (s *ClassroomService) SetArchived(classroom *models.Classroom, archived bool) error {
+	if classroom.Done == archived {
+		if archived {
+			return apperr.Conflict("classroom is already archived")
+		}
+		return apperr.Conflict("classroom is not archived")
+	}
+
+	changed := *classroom
+	changed.Done = archived
+	changed.ArchivedAt = nil
+
+	if archived {
+		now := time.Now()
+		changed.ArchivedAt = &now
+	}
+
+	err := s.classrooms.UpdateClassroom(&changed, "done", "archived_at")
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*classroom = changed
+	return nil
+}
+
+// Trash moves a classroom to its owner's trash, where it stays for
+// TrashRetention before it is purged
+func (s *ClassroomService) Trash(classroom *models.Classroom) error {
+	now := time.Now()
+
+	changed := *classroom
+	changed.IsDeleted = true
+	changed.TrashedAt = &now
+
+	err := s.classrooms.UpdateClassroom(&changed, "is_deleted", "trashed_at")
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	*classroom = changed
+	return nil
+}
+
+// ListTrash pages through the classrooms in a user's trash
+func (s *ClassroomService) ListTrash(user *models.Users, page Page) ([]models.Classroom, int64, error) {
+	classrooms, total, err := s.classrooms.ListTrash(*user.Uuid, page)
+
+	if err != nil {
+		return nil, 0, apperr.Internal(err, "could not get classrooms")
+	}
+	return classrooms, total, nil
+}
+
+// Restore takes one of a user's classrooms back out of the trash
+func (s *ClassroomService) Restore(user *models.Users, classId string) (*models.Classroom, error) {
+	classroom, err := s.classrooms.TrashedClassroom(classId, *user.Uuid)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not get classroom")
+	}
+
+	// only the owner knows a trashed classroom exists
+	if classroom == nil {
+		return nil, apperr.NotFound("classroom not found")
+	}
+
+	classroom.IsDeleted = false
+	classroom.TrashedAt = nil
+
+	err = s.classrooms.UpdateClassroom(classroom, "is_deleted", "trashed_at")
+
+	if err != nil {
+		return nil, writeFailed(err, "database update failed")
+	}
+	return classroom, nil
+}
+
+// PurgeTrash permanently deletes the classrooms that have been in the trash
+// for longer than TrashRetention, with everything that belongs to them. It
+// returns how many were purged and the content hashes their attachments
+// used, which are left for the caller to release even when it fails part way.
+func (s *ClassroomService) PurgeTrash(now time.Time) (int, []string, error) {
+	cutoff := now.Add(-TrashRetention)
+
+	expired, err := s.classrooms.ExpiredTrash(cutoff)
+
+	if err != nil {
+		return 0, nil, apperr.Internal(err, "could not get trashed classrooms")
+	}
+
+	purged := 0
+	hashes := []string{}
+
+	for _, classId := range expired {
+		var deleted bool
+		var used []string
+
+		err = s.work.Do(func(stores Stores) error {
+			var err error
+
+			deleted, used, err = stores.Classrooms.PurgeClassroom(classId, cutoff)
+			return err
+		})
+
+		if err != nil {
+			return purged, hashes, apperr.Internal(err, "could not purge classroom")
+		}
+
+		if deleted {
+			purged++
+			hashes = append(hashes, used...)
+		}
+	}
+	return purged, hashes, nil
+}
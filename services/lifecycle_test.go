@@ -0,0 +1,133 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+func TestSetArchived(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	classroom := createClassroom(t, svc, owner)
+
+	err := svc.Classrooms.SetArchived(classroom, false)
+	expectCode(t, err, apperr.CodeConflict)
+
+	if err = svc.Classrooms.SetArchived(classroom, true); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.Classroom(*classroom.ClassId); !stored.Done || stored.ArchivedAt == nil {
+		t.Fatalf("expected the classroom to be archived, got %+v", stored)
+	}
+
+	err = svc.Classrooms.SetArchived(classroom, true)
+	expectCode(t, err, apperr.CodeConflict)
+
+	if err = svc.Classrooms.SetArchived(classroom, false); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.Classroom(*classroom.ClassId); stored.Done || stored.ArchivedAt != nil {
+		t.Fatalf("expected the classroom to be editable again, got %+v", stored)
+	}
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	teacher := addUser(store, "teacher")
+	classroom := createClassroom(t, svc, owner)
+
+	if err := svc.Classrooms.Trash(classroom); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.Classroom(*classroom.ClassId); stored != nil {
+		t.Fatalf("expected a trashed classroom to be gone, got %+v", stored)
+	}
+
+	page := services.Page{Limit: 10, Sort: "trashed_at", Desc: true}
+
+	trash, total, err := svc.Classrooms.ListTrash(owner, page)
+	if err != nil || total != 1 || *trash[0].ClassId != *classroom.ClassId {
+		t.Fatalf("expected the classroom in the owner's trash, got %d, %v", total, err)
+	}
+
+	// only the owner knows a trashed classroom exists
+	if _, total, _ := svc.Classrooms.ListTrash(teacher, page); total != 0 {
+		t.Fatalf("expected nothing in someone else's trash, got %d", total)
+	}
+	_, err = svc.Classrooms.Restore(teacher, *classroom.ClassId)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	restored, err := svc.Classrooms.Restore(owner, *classroom.ClassId)
+	if err != nil || restored.IsDeleted || restored.TrashedAt != nil {
+		t.Fatalf("expected the classroom to be restored, got %+v, %v", restored, err)
+	}
+	if stored, _ := store.Classroom(*classroom.ClassId); stored == nil {
+		t.Fatal("expected the classroom to be live again")
+	}
+
+	_, err = svc.Classrooms.Restore(owner, *classroom.ClassId)
+	expectCode(t, err, apperr.CodeNotFound)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	student := addUser(store, "student")
+
+	expired := createClassroom(t, svc, owner)
+	recent := createClassroom(t, svc, owner)
+	join(t, svc, expired, student)
+
+	attachments := []models.Attachment{
+		{ID: str("first"), ClassID: expired.ClassId, Hash: "shared"},
+		{ID: str("second"), ClassID: expired.ClassId, Hash: "shared"},
+	}
+	createAssignment(t, svc, owner, services.NewAssignment{ClassID: expired.ClassId, Title: str("essay")})
+	if _, err := svc.Assignments.Create(owner, services.NewAssignment{ClassID: expired.ClassId, Title: str("notes")}, attachments); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Notify([]string{*student.Uuid}, expired.ClassId, services.NotifyRoleChanged, "you are a teacher now")
+
+	now := time.Now()
+	long := now.Add(-services.TrashRetention - time.Hour)
+
+	for _, classroom := range []*models.Classroom{expired, recent} {
+		if err := svc.Classrooms.Trash(classroom); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// trashed longer ago than the trash keeps classrooms
+	trashed, _ := store.TrashedClassroom(*expired.ClassId, *owner.Uuid)
+	trashed.TrashedAt = &long
+	store.AddClassroom(*trashed)
+
+	purged, hashes, err := svc.Classrooms.PurgeTrash(now)
+	if err != nil || purged != 1 {
+		t.Fatalf("expected one classroom to be purged, got %d, %v", purged, err)
+	}
+	if len(hashes) != 1 || hashes[0] != "shared" {
+		t.Fatalf("expected the content of the attachments to be handed back once, got %v", hashes)
+	}
+
+	page := services.Page{Limit: 10, Sort: "trashed_at"}
+
+	trash, total, _ := svc.Classrooms.ListTrash(owner, page)
+	if total != 1 || *trash[0].ClassId != *recent.ClassId {
+		t.Fatalf("expected only the recent classroom to be left in the trash, got %d", total)
+	}
+
+	if membership, _ := store.Membership(*expired.ClassId, *student.Uuid); membership != nil {
+		t.Fatalf("expected the memberships to go with the classroom, got %+v", membership)
+	}
+	if notifications := store.Notifications(*student.Uuid); len(notifications) != 0 {
+		t.Fatalf("expected the notifications to go with the classroom, got %+v", notifications)
+	}
+}
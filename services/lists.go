@@ -0,0 +1,46 @@
+package services
+
+import "time"
+
+// Page asks a store for one page of a list, sorted by one of the fields the
+// list allows. Stores return one row past Limit, so callers can tell whether
+// another page exists.
+type Page struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+	// After continues a list sorted by created_at past the row it names,
+	// instead of skipping Offset rows
+	After *Cursor
+}
+
+// Cursor names the row a page of a created_at sorted list ended on
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// ClassroomFilter narrows the classrooms a user sees to a role they hold in
+// them, whether they are archived and a search on the name
+type ClassroomFilter struct {
+	Role   string
+	Done   *bool
+	Search string
+}
+
+// MemberFilter narrows the active members of a classroom to a role and a
+// search on their name or username
+type MemberFilter struct {
+	Role   string
+	Search string
+}
+
+// AssignmentFilter narrows the assignments of a classroom
+type AssignmentFilter struct {
+	// PublishedBy keeps only assignments students can see at that time
+	PublishedBy *time.Time
+	Draft       *bool
+	Type        string
+	CategoryID  *uint
+}
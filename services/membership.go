@@ -0,0 +1,465 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+type MembershipService struct {
+	classrooms ClassroomStore
+	members    MembershipStore
+	work       UnitOfWork
+}
+
+var (
+	errOwnerChanged = errors.New("classroom owner changed")
+	errNotTeacher   = errors.New("new owner is not a teacher")
+)
+
+// active reports whether a membership row makes its user a member right now
+func active(membership *models.ClassroomCollaborator) bool {
+	return membership != nil && !membership.IsRemoved && membership.Status == models.MembershipActive
+}
+
+// Role resolves the user's role in a classroom, empty when they never joined it
+func (s *MembershipService) Role(classroom *models.Classroom, user *models.Users) (string, error) {
+	if classroom.OwnerID != nil && *classroom.OwnerID == *user.Uuid {
+		return RoleOwner, nil
+	}
+
+	membership, err := s.members.Membership(*classroom.ClassId, *user.Uuid)
+	if err != nil || membership == nil {
+		return "", err
+	}
+
+	// a join request grants nothing until it is approved
+	if membership.Status == models.MembershipPending || membership.Status == models.MembershipRejected {
+		return "", nil
+	}
+
+	if membership.IsRemoved {
+		return RoleRemoved, nil
+	}
+	return membership.Role, nil
+}
+
+// Authorize loads a live classroom and the user's role in it, failing unless
+// that role holds the permission. Archived classrooms only allow reads and
+// the few permissions that deal with the archive itself.
+func (s *MembershipService) Authorize(classId string, user *models.Users, permission string, readOnly bool) (*models.Classroom, string, error) {
+	classroom, err := s.classrooms.Classroom(classId)
+
+	if err != nil || classroom == nil {
+		return nil, "", apperr.NotFound("classroom not found")
+	}
+
+	role, err := s.Role(classroom, user)
+
+	if err != nil {
+		return nil, "", apperr.Internal(err, "could not resolve classroom role")
+	}
+
+	if !Can(role, permission) {
+		return nil, "", apperr.Forbidden("you do not have permission to do this in this classroom")
+	}
+
+	if classroom.Done && !readOnly && !archivedAllowed[permission] {
+		return nil, "", apperr.Forbidden("this classroom is archived and read-only")
+	}
+
+	return classroom, role, nil
+}
+
+// NormalizeJoinCode accepts codes typed in lower case or with spaces and dashes
+func NormalizeJoinCode(code string) string {
+	return strings.Map(func(c rune) rune {
+		if c == ' ' || c == '-' {
+			return -1
+		}
+		return c
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// JoinableClassroom finds the classroom a join code opens, or the reason it
+// cannot be used when the code is unknown, switched off or expired
+func (s *MembershipService) JoinableClassroom(code string) (*models.Classroom, error) {
+	code = NormalizeJoinCode(code)
+
+	if len(code) == 0 {
+		return nil, apperr.NotFound("no classroom uses this join code")
+	}
+
+	classroom, err := s.classrooms.ClassroomByJoinCode(code)
+
+	if err != nil || classroom == nil {
+		return nil, apperr.NotFound("no classroom uses this join code")
+	}
+
+	if classroom.JoinMode == models.JoinClosed || classroom.Done {
+		return nil, apperr.Forbidden("this classroom is closed to new members")
+	}
+
+	if classroom.JoinMode == models.JoinInviteOnly {
+		return nil, apperr.Forbidden("this classroom can only be joined by invitation")
+	}
+
+	if !classroom.JoinEnabled {
+		return nil, apperr.Forbidden("joining with a code is turned off for this classroom")
+	}
+
+	if classroom.JoinExpiresAt != nil && !time.Now().Before(*classroom.JoinExpiresAt) {
+		return nil, apperr.Gone("this join code has expired")
+	}
+
+	return classroom, nil
+}
+
+// Join enrols the user as a student of the classroom a join code opens. In
+// classrooms that need approval it files a pending join request instead.
+// Members who were removed can join again the same way.
+func (s *MembershipService) Join(user *models.Users, code string) (*models.ClassroomCollaborator, error) {
+	classroom, err := s.JoinableClassroom(code)
+	if err != nil {
+		return nil, err
+	}
+
+	// a join code only ever makes students, teachers are invited
+	collaborator := models.ClassroomCollaborator{
+		ClassID: classroom.ClassId,
+		UserID:  user.Uuid,
+		Role:    RoleStudent,
+		Status:  models.MembershipActive,
+	}
+
+	if classroom.JoinMode == models.JoinApproval {
+		now := time.Now()
+		collaborator.Status = models.MembershipPending
+		collaborator.RequestedAt = &now
+	}
+
+	existing, err := s.members.Membership(*classroom.ClassId, *user.Uuid)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not check membership")
+	}
+
+	if existing != nil && existing.Status == models.MembershipPending {
+		return nil, apperr.Conflict("your request to join is waiting for approval")
+	}
+
+	if active(existing) {
+		return nil, apperr.Conflict("you are already a member of this classroom")
+	}
+
+	// a rejected request can be made again, and a removed member can come back
+	if existing != nil {
+		err = s.members.UpdateMembership(&collaborator, "role", "is_removed", "status", "requested_at", "decided_at", "decided_by")
+	} else {
+		err = s.members.CreateMembership(&collaborator)
+	}
+
+	if errors.Is(err, ErrDuplicate) {
+		return nil, apperr.Conflict("you are already a member of this classroom")
+	}
+
+	if err != nil {
+		return nil, writeFailed(err, "database insertion failed")
+	}
+
+	return &collaborator, nil
+}
+
+// Remove takes a member out of a classroom. Anyone may leave, removing
+// someone else needs the permission for their role, and the owner has to hand
+// the classroom over first so it is never left without one.
+func (s *MembershipService) Remove(classroom *models.Classroom, actor *models.Users, actorRole string, userId string) (*models.ClassroomCollaborator, error) {
+	membership, err := s.members.Membership(*classroom.ClassId, userId)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not get member")
+	}
+
+	if !active(membership) {
+		return nil, apperr.NotFound("member not found")
+	}
+
+	if classroom.OwnerID != nil && *classroom.OwnerID == userId {
+		return nil, apperr.Conflict("the owner cannot leave, transfer ownership to another teacher first")
+	}
+
+	if userId != *actor.Uuid {
+		permission := PermRemoveMember
+		if membership.Role == RoleTeacher {
+			permission = PermRemoveTeacher
+		}
+
+		if !Can(actorRole, permission) {
+			return nil, apperr.Forbidden("you do not have permission to remove this member")
+		}
+	}
+
+	membership.IsRemoved = true
+
+	err = s.members.UpdateMembership(membership, "is_removed")
+
+	if err != nil {
+		return nil, writeFailed(err, "database update failed")
+	}
+	return membership, nil
+}
+
+// Members pages through the active members of a classroom
+func (s *MembershipService) Members(classroom *models.Classroom, filter MemberFilter, page Page) ([]models.ClassroomCollaborator, int64, error) {
+	if len(filter.Role) != 0 && filter.Role != RoleTeacher && filter.Role != RoleStudent {
+		return nil, 0, apperr.BadRequest("role must be teacher or student")
+	}
+
+	members, total, err := s.members.ListMembers(*classroom.ClassId, filter, page)
+
+	if err != nil {
+		return nil, 0, apperr.Internal(err, "could not get members")
+	}
+	return members, total, nil
+}
+
+// enrol adds a user to a classroom with a role, reviving a membership they
+// were removed from and settling a pending join request. It reports false
+// when they already hold that role, or are an active teacher, who is never
+// turned back into a student this way.
+func enrol(stores Stores, classId *string, userId *string, role string) (*models.ClassroomCollaborator, bool, error) {
+	existing, err := stores.Members.Membership(*classId, *userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing == nil {
+		collaborator := models.ClassroomCollaborator{
+			ClassID: classId,
+			UserID:  userId,
+			Role:    role,
+			Status:  models.MembershipActive,
+		}
+
+		err = stores.Members.CreateMembership(&collaborator)
+		return &collaborator, err == nil, err
+	}
+
+	if active(existing) && (existing.Role == role || existing.Role == RoleTeacher) {
+		return existing, false, nil
+	}
+
+	existing.Role = role
+	existing.IsRemoved = false
+	existing.Status = models.MembershipActive
+
+	err = stores.Members.UpdateMembership(existing, "role", "is_removed", "status")
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, true, nil
+}
+
+// AcceptInvitation joins the invitation's classroom with the invitation's
+// role, counting the use in the same transaction so concurrent accepts cannot
+// use it more often than it allows
+func (s *MembershipService) AcceptInvitation(user *models.Users, invitation *models.Invitation) (*models.ClassroomCollaborator, error) {
+	if !invitation.Usable(time.Now()) {
+		return nil, apperr.Gone(errInvitationUsed.Error())
+	}
+
+	if invitation.Classroom.JoinMode == models.JoinClosed || invitation.Classroom.Done {
+		return nil, apperr.Forbidden("this classroom is closed to new members")
+	}
+
+	if invitation.Classroom.OwnerID != nil && *invitation.Classroom.OwnerID == *user.Uuid {
+		return nil, apperr.Conflict("you own this classroom")
+	}
+
+	var collaborator *models.ClassroomCollaborator
+	joined := false
+
+	err := s.work.Do(func(stores Stores) error {
+		var err error
+
+		collaborator, joined, err = enrol(stores, invitation.ClassID, user.Uuid, invitation.Role)
+		if err != nil || !joined {
+			return err
+		}
+
+		used, err := stores.Invitations.UseInvitation(*invitation.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvitationUsed
+		}
+		return nil
+	})
+
+	if errors.Is(err, errInvitationUsed) {
+		return nil, apperr.Gone(errInvitationUsed.Error())
+	}
+
+	// a concurrent accept of another invitation can win the insert
+	if errors.Is(err, ErrDuplicate) {
+		return nil, apperr.Conflict("you are already a member of this classroom")
+	}
+
+	if err != nil {
+		return nil, writeFailed(err, "database update failed")
+	}
+
+	if !joined {
+		return nil, apperr.Conflict("you are already a member of this classroom")
+	}
+	return collaborator, nil
+}
+
+// TransferOwnership hands the classroom to another of its teachers; the old
+// owner stays on as a teacher
+func (s *MembershipService) TransferOwnership(classroom *models.Classroom, owner *models.Users, userId string) error {
+	if userId == *owner.Uuid {
+		return apperr.Conflict("you already own this classroom")
+	}
+
+	err := s.work.Do(func(stores Stores) error {
+		// checked in the unit of work, so a removal or demotion racing the
+		// transfer cannot leave the classroom to someone outside it
+		member, err := stores.Members.ActiveMember(*classroom.ClassId, userId)
+		if err != nil {
+			return err
+		}
+		if member == nil || member.Role != RoleTeacher {
+			return errNotTeacher
+		}
+
+		// only move ownership away from the caller if they still hold it
+		changed, err := stores.Classrooms.ChangeOwner(*classroom.ClassId, *owner.Uuid, userId)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return errOwnerChanged
+		}
+
+		_, _, err = enrol(stores, classroom.ClassId, owner.Uuid, RoleTeacher)
+		if err != nil {
+			return err
+		}
+
+		return stores.Notifications.Notify([]string{userId}, classroom.ClassId, NotifyOwnership, "you are now the owner of "+classroomName(classroom))
+	})
+
+	if errors.Is(err, errNotTeacher) {
+		return apperr.InvalidField("user_id", "must be a teacher of this classroom")
+	}
+
+	if errors.Is(err, errOwnerChanged) {
+		return apperr.Conflict("ownership of this classroom has already changed")
+	}
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	classroom.OwnerID = &userId
+	return nil
+}
+
+// ChangeRole promotes a student to teacher or demotes a teacher to student;
+// the owner's role only changes by transferring ownership
+func (s *MembershipService) ChangeRole(classroom *models.Classroom, userId string, role string) (*models.ClassroomCollaborator, error) {
+	if classroom.OwnerID != nil && *classroom.OwnerID == userId {
+		return nil, apperr.Conflict("the owner's role cannot be changed, transfer ownership instead")
+	}
+
+	member, err := s.members.ActiveMember(*classroom.ClassId, userId)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not get member")
+	}
+
+	if member == nil {
+		return nil, apperr.NotFound("member not found")
+	}
+
+	if member.Role == role {
+		return nil, apperr.Conflict("member already has this role")
+	}
+
+	changed := *member
+	changed.Role = role
+
+	err = s.work.Do(func(stores Stores) error {
+		err := stores.Members.UpdateMembership(&changed, "role")
+		if err != nil {
+			return err
+		}
+
+		return stores.Notifications.Notify([]string{userId}, classroom.ClassId, NotifyRoleChanged, "you are now a "+role+" in "+classroomName(classroom))
+	})
+
+	if err != nil {
+		return nil, writeFailed(err, "database update failed")
+	}
+	return &changed, nil
+}
+
+// JoinRequests pages through the requests waiting on a teacher
+func (s *MembershipService) JoinRequests(classroom *models.Classroom, page Page) ([]models.ClassroomCollaborator, int64, error) {
+	requests, total, err := s.members.ListJoinRequests(*classroom.ClassId, page)
+
+	if err != nil {
+		return nil, 0, apperr.Internal(err, "could not get join requests")
+	}
+	return requests, total, nil
+}
+
+// DecideJoinRequests approves or rejects the pending join requests of the
+// users, or all of them when userIds is nil, and tells each requester
+func (s *MembershipService) DecideJoinRequests(classroom *models.Classroom, decider *models.Users, userIds []string, approve bool) ([]models.ClassroomCollaborator, error) {
+	status, kind, message := models.MembershipActive, NotifyJoinApproved, "your request to join "+classroomName(classroom)+" was approved"
+	if !approve {
+		status, kind, message = models.MembershipRejected, NotifyJoinRejected, "your request to join "+classroomName(classroom)+" was declined"
+	}
+
+	var decided []models.ClassroomCollaborator
+
+	err := s.work.Do(func(stores Stores) error {
+		var err error
+
+		decided, err = stores.Members.JoinRequests(*classroom.ClassId, userIds)
+		if err != nil || len(decided) == 0 {
+			return err
+		}
+
+		requesters := make([]string, 0, len(decided))
+		for _, request := range decided {
+			requesters = append(requesters, *request.UserID)
+		}
+
+		now := time.Now()
+
+		err = stores.Members.DecideJoinRequests(*classroom.ClassId, requesters, status, *decider.Uuid, now)
+		if err != nil {
+			return err
+		}
+
+		for i := range decided {
+			decided[i].Status = status
+			decided[i].DecidedAt = &now
+			decided[i].DecidedBy = decider.Uuid
+		}
+
+		return stores.Notifications.Notify(requesters, classroom.ClassId, kind, message)
+	})
+
+	if err != nil {
+		return nil, writeFailed(err, "database update failed")
+	}
+	return decided, nil
+}
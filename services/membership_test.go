@@ -0,0 +1,495 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+func TestNormalizeJoinCode(t *testing.T) {
+	for code, want := range map[string]string{
+		"abcd1234":    "ABCD1234",
+		" ab-cd 12 ":  "ABCD12",
+		"ABCD-1234 ":  "ABCD1234",
+		"   ":         "",
+		"a - b - c  ": "ABC",
+	} {
+		if got := services.NormalizeJoinCode(code); got != want {
+			t.Errorf("NormalizeJoinCode(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, teacher)
+
+	_, err := svc.Membership.Join(student, "unknown")
+	expectCode(t, err, apperr.CodeNotFound)
+
+	// codes may be typed loosely
+	membership, err := svc.Membership.Join(student, " "+strings.ToLower((*classroom.JoinCode)[:4])+"-"+(*classroom.JoinCode)[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if membership.Role != services.RoleStudent || membership.Status != models.MembershipActive {
+		t.Fatalf("expected an active student, got %+v", membership)
+	}
+
+	_, err = svc.Membership.Join(student, *classroom.JoinCode)
+	expectCode(t, err, apperr.CodeConflict)
+
+	// the owner is already a member
+	_, err = svc.Membership.Join(teacher, *classroom.JoinCode)
+	expectCode(t, err, apperr.CodeConflict)
+}
+
+func TestJoinRefused(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	for _, tc := range []struct {
+		name   string
+		change func(classroom *models.Classroom)
+		code   string
+	}{
+		{"closed", func(c *models.Classroom) { c.JoinMode = models.JoinClosed }, apperr.CodeForbidden},
+		{"archived", func(c *models.Classroom) { c.Done = true }, apperr.CodeForbidden},
+		{"invite only", func(c *models.Classroom) { c.JoinMode = models.JoinInviteOnly }, apperr.CodeForbidden},
+		{"code disabled", func(c *models.Classroom) { c.JoinEnabled = false }, apperr.CodeForbidden},
+		{"code expired", func(c *models.Classroom) { c.JoinExpiresAt = &past }, apperr.CodeGone},
+		{"trashed", func(c *models.Classroom) { c.IsDeleted = true }, apperr.CodeNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, store := newServices()
+
+			teacher := addUser(store, "teacher")
+			student := addUser(store, "student")
+			classroom := createClassroom(t, svc, teacher)
+
+			setClassroom(t, store, *classroom.ClassId, tc.change)
+
+			_, err := svc.Membership.Join(student, *classroom.JoinCode)
+			expectCode(t, err, tc.code)
+		})
+	}
+}
+
+func TestJoinNeedingApproval(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, teacher)
+
+	setClassroom(t, store, *classroom.ClassId, func(c *models.Classroom) { c.JoinMode = models.JoinApproval })
+
+	request := join(t, svc, classroom, student)
+	if request.Status != models.MembershipPending || request.RequestedAt == nil {
+		t.Fatalf("expected a pending join request, got %+v", request)
+	}
+
+	// a pending request grants nothing
+	_, _, err := svc.Membership.Authorize(*classroom.ClassId, student, services.PermViewClassroom, true)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	_, err = svc.Membership.Join(student, *classroom.JoinCode)
+	expectCode(t, err, apperr.CodeConflict)
+
+	// a rejected request can be made again
+	request.Status = models.MembershipRejected
+	store.AddMembership(*request)
+
+	request = join(t, svc, classroom, student)
+	if request.Status != models.MembershipPending {
+		t.Fatalf("expected the request to be pending again, got %+v", request)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	student := addUser(store, "student")
+	outsider := addUser(store, "outsider")
+	classroom := createClassroom(t, svc, owner)
+	join(t, svc, classroom, student)
+
+	_, role, err := svc.Membership.Authorize(*classroom.ClassId, student, services.PermViewClassroom, true)
+	if err != nil || role != services.RoleStudent {
+		t.Fatalf("expected a student to view the classroom, got %q, %v", role, err)
+	}
+
+	_, _, err = svc.Membership.Authorize(*classroom.ClassId, student, services.PermEditClassroom, false)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	_, _, err = svc.Membership.Authorize(*classroom.ClassId, outsider, services.PermViewClassroom, true)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	_, _, err = svc.Membership.Authorize("missing", owner, services.PermViewClassroom, true)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	// an archived classroom can be read but not changed
+	setClassroom(t, store, *classroom.ClassId, func(c *models.Classroom) { c.Done = true })
+
+	_, _, err = svc.Membership.Authorize(*classroom.ClassId, owner, services.PermEditClassroom, true)
+	if err != nil {
+		t.Fatalf("expected reads of an archived classroom, got %v", err)
+	}
+
+	_, _, err = svc.Membership.Authorize(*classroom.ClassId, owner, services.PermEditClassroom, false)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	_, _, err = svc.Membership.Authorize(*classroom.ClassId, owner, services.PermArchiveClassroom, false)
+	if err != nil {
+		t.Fatalf("expected the owner to unarchive, got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	teacher := addUser(store, "teacher")
+	student := addUser(store, "student")
+	other := addUser(store, "other")
+	classroom := createClassroom(t, svc, owner)
+
+	join(t, svc, classroom, student)
+	join(t, svc, classroom, other)
+
+	// teachers are invited, not joined
+	store.AddMembership(models.ClassroomCollaborator{
+		ClassID: classroom.ClassId,
+		UserID:  teacher.Uuid,
+		Role:    services.RoleTeacher,
+		Status:  models.MembershipActive,
+	})
+
+	// students cannot remove each other, and only the owner removes teachers
+	_, err := svc.Membership.Remove(classroom, student, services.RoleStudent, *other.Uuid)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	_, err = svc.Membership.Remove(classroom, other, services.RoleStudent, *teacher.Uuid)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	// the owner has to hand the classroom over first
+	_, err = svc.Membership.Remove(classroom, owner, services.RoleOwner, *owner.Uuid)
+	expectCode(t, err, apperr.CodeConflict)
+
+	_, err = svc.Membership.Remove(classroom, teacher, services.RoleTeacher, *other.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// anyone may leave
+	_, err = svc.Membership.Remove(classroom, student, services.RoleStudent, *student.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role, _ := svc.Membership.Role(classroom, student)
+	if role != services.RoleRemoved {
+		t.Fatalf("expected the student to be removed, got %q", role)
+	}
+
+	_, err = svc.Membership.Remove(classroom, owner, services.RoleOwner, *student.Uuid)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	_, err = svc.Membership.Remove(classroom, owner, services.RoleOwner, *teacher.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	teacher := addUser(store, "teacher")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, owner)
+	join(t, svc, classroom, student)
+
+	store.AddMembership(models.ClassroomCollaborator{
+		ClassID: classroom.ClassId,
+		UserID:  teacher.Uuid,
+		Role:    services.RoleTeacher,
+		Status:  models.MembershipActive,
+	})
+
+	err := svc.Membership.TransferOwnership(classroom, owner, *owner.Uuid)
+	expectCode(t, err, apperr.CodeConflict)
+
+	// only teachers can take a classroom over, and a refused transfer changes nothing
+	err = svc.Membership.TransferOwnership(classroom, owner, *student.Uuid)
+	expectCode(t, err, apperr.CodeValidation)
+
+	if stored, _ := store.Classroom(*classroom.ClassId); *stored.OwnerID != *owner.Uuid {
+		t.Fatalf("expected the owner to keep the classroom, got %v", *stored.OwnerID)
+	}
+
+	stale := *classroom
+
+	err = svc.Membership.TransferOwnership(classroom, owner, *teacher.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role, _ := svc.Membership.Role(classroom, teacher)
+	if role != services.RoleOwner {
+		t.Fatalf("expected the teacher to own the classroom, got %q", role)
+	}
+
+	role, _ = svc.Membership.Role(classroom, owner)
+	if role != services.RoleTeacher {
+		t.Fatalf("expected the old owner to stay on as a teacher, got %q", role)
+	}
+
+	if notifications := store.Notifications(*teacher.Uuid); len(notifications) != 1 || notifications[0].Type != services.NotifyOwnership {
+		t.Fatalf("expected the new owner to be told, got %+v", notifications)
+	}
+
+	// a second transfer from the old owner loses
+	err = svc.Membership.TransferOwnership(&stale, owner, *teacher.Uuid)
+	expectCode(t, err, apperr.CodeConflict)
+}
+
+func TestChangeRole(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, owner)
+	join(t, svc, classroom, student)
+
+	_, err := svc.Membership.ChangeRole(classroom, *owner.Uuid, services.RoleStudent)
+	expectCode(t, err, apperr.CodeConflict)
+
+	_, err = svc.Membership.ChangeRole(classroom, "missing", services.RoleTeacher)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	_, err = svc.Membership.ChangeRole(classroom, *student.Uuid, services.RoleStudent)
+	expectCode(t, err, apperr.CodeConflict)
+
+	member, err := svc.Membership.ChangeRole(classroom, *student.Uuid, services.RoleTeacher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role, _ := svc.Membership.Role(classroom, student)
+	if member.Role != services.RoleTeacher || role != services.RoleTeacher {
+		t.Fatalf("expected a teacher, got %q and %q", member.Role, role)
+	}
+
+	if notifications := store.Notifications(*student.Uuid); len(notifications) != 1 || notifications[0].Type != services.NotifyRoleChanged {
+		t.Fatalf("expected the member to be told, got %+v", notifications)
+	}
+}
+
+func TestDecideJoinRequests(t *testing.T) {
+	svc, store := newServices()
+
+	teacher := addUser(store, "teacher")
+	first := addUser(store, "first")
+	second := addUser(store, "second")
+	third := addUser(store, "third")
+	classroom := createClassroom(t, svc, teacher)
+
+	setClassroom(t, store, *classroom.ClassId, func(c *models.Classroom) { c.JoinMode = models.JoinApproval })
+
+	for _, user := range []*models.Users{first, second, third} {
+		join(t, svc, classroom, user)
+	}
+
+	requests, total, err := svc.Membership.JoinRequests(classroom, services.Page{Limit: 2, Sort: "requested_at"})
+	if err != nil || total != 3 || len(requests) != 3 {
+		t.Fatalf("expected a page of two requests and one past it, of three, got %d of %d, %v", len(requests), total, err)
+	}
+
+	decided, err := svc.Membership.DecideJoinRequests(classroom, teacher, []string{*first.Uuid}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decided) != 1 || decided[0].Status != models.MembershipActive || *decided[0].DecidedBy != *teacher.Uuid {
+		t.Fatalf("expected the first request to be approved, got %+v", decided)
+	}
+
+	role, _ := svc.Membership.Role(classroom, first)
+	if role != services.RoleStudent {
+		t.Fatalf("expected an approved student, got %q", role)
+	}
+
+	// nil decides every request still pending
+	decided, err = svc.Membership.DecideJoinRequests(classroom, teacher, nil, false)
+	if err != nil || len(decided) != 2 {
+		t.Fatalf("expected the other two to be rejected, got %d, %v", len(decided), err)
+	}
+
+	for _, user := range []*models.Users{second, third} {
+		notifications := store.Notifications(*user.Uuid)
+		if len(notifications) != 1 || notifications[0].Type != services.NotifyJoinRejected {
+			t.Fatalf("expected %s to be told, got %+v", *user.Username, notifications)
+		}
+	}
+
+	requests, _, _ = svc.Membership.JoinRequests(classroom, services.Page{Limit: 2, Sort: "requested_at"})
+	if len(requests) != 0 {
+		t.Fatalf("expected no requests left, got %d", len(requests))
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	first := addUser(store, "first")
+	second := addUser(store, "second")
+	classroom := createClassroom(t, svc, owner)
+
+	single := 1
+	store.AddInvitation(models.Invitation{ID: str("invitation"), ClassID: classroom.ClassId, Role: services.RoleTeacher, MaxUses: &single})
+
+	invitation := func() *models.Invitation {
+		invitation := store.Invitation("invitation")
+		invitation.Classroom = *classroom
+		return invitation
+	}
+
+	_, err := svc.Membership.AcceptInvitation(owner, invitation())
+	expectCode(t, err, apperr.CodeConflict)
+
+	// an invitation revives a member who was removed
+	membership := join(t, svc, classroom, first)
+	membership.IsRemoved = true
+	store.AddMembership(*membership)
+
+	collaborator, err := svc.Membership.AcceptInvitation(first, invitation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collaborator.Role != services.RoleTeacher || collaborator.IsRemoved {
+		t.Fatalf("expected an active teacher, got %+v", collaborator)
+	}
+
+	if used := store.Invitation("invitation"); used.Uses != 1 {
+		t.Fatalf("expected one use to be counted, got %d", used.Uses)
+	}
+
+	_, err = svc.Membership.AcceptInvitation(second, invitation())
+	expectCode(t, err, apperr.CodeGone)
+}
+
+func TestAcceptInvitationUsedMeanwhile(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, owner)
+
+	single := 1
+	store.AddInvitation(models.Invitation{ID: str("invitation"), ClassID: classroom.ClassId, Role: services.RoleStudent, MaxUses: &single})
+
+	// the copy was loaded before someone else used the invitation up
+	invitation := store.Invitation("invitation")
+	invitation.Classroom = *classroom
+
+	_, err := store.UseInvitation("invitation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = svc.Membership.AcceptInvitation(student, invitation)
+	expectCode(t, err, apperr.CodeGone)
+
+	// the membership written before the use was refused is rolled back
+	role, _ := svc.Membership.Role(classroom, student)
+	if role != "" {
+		t.Fatalf("expected the student not to join, got %q", role)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	classroom := createClassroom(t, svc, owner)
+
+	for _, name := range []string{"carol", "alice", "bob"} {
+		join(t, svc, classroom, addUser(store, name))
+	}
+
+	page := services.Page{Limit: 2, Sort: "name"}
+
+	members, total, err := svc.Membership.Members(classroom, services.MemberFilter{Role: services.RoleStudent}, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one row past the limit tells there is another page
+	if total != 3 || len(members) != 3 || *members[0].User.Name != "alice" || *members[1].User.Name != "bob" {
+		t.Fatalf("expected alice and bob first of three students, got %d, %+v", total, members)
+	}
+
+	page.Offset = 2
+	members, _, _ = svc.Membership.Members(classroom, services.MemberFilter{Role: services.RoleStudent}, page)
+	if len(members) != 1 || *members[0].User.Name != "carol" {
+		t.Fatalf("expected carol on the second page, got %+v", members)
+	}
+
+	members, total, _ = svc.Membership.Members(classroom, services.MemberFilter{Search: "AL"}, services.Page{Limit: 10, Sort: "name"})
+	if total != 1 || *members[0].User.Name != "alice" {
+		t.Fatalf("expected the search to find alice, got %+v", members)
+	}
+
+	_, _, err = svc.Membership.Members(classroom, services.MemberFilter{Role: services.RoleOwner}, page)
+	expectCode(t, err, apperr.CodeBadRequest)
+}
+
+func TestJoinAfterRemoval(t *testing.T) {
+	svc, store := newServices()
+
+	owner := addUser(store, "owner")
+	student := addUser(store, "student")
+	classroom := createClassroom(t, svc, owner)
+	join(t, svc, classroom, student)
+
+	_, err := svc.Membership.Remove(classroom, owner, services.RoleOwner, *student.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	membership := join(t, svc, classroom, student)
+	if membership.IsRemoved || membership.Status != models.MembershipActive {
+		t.Fatalf("expected the membership to be revived, got %+v", membership)
+	}
+
+	role, _ := svc.Membership.Role(classroom, student)
+	if role != services.RoleStudent {
+		t.Fatalf("expected the student back, got %q", role)
+	}
+
+	// in classrooms that need approval they ask again like anyone else
+	_, err = svc.Membership.Remove(classroom, owner, services.RoleOwner, *student.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setClassroom(t, store, *classroom.ClassId, func(c *models.Classroom) { c.JoinMode = models.JoinApproval })
+
+	membership = join(t, svc, classroom, student)
+	if membership.IsRemoved || membership.Status != models.MembershipPending {
+		t.Fatalf("expected a pending request, got %+v", membership)
+	}
+
+	role, _ = svc.Membership.Role(classroom, student)
+	if role != "" {
+		t.Fatalf("expected a pending request to grant nothing, got %q", role)
+	}
+}
@@ -0,0 +1,1187 @@
+// Package memstore implements the service stores in memory, as a fake for
+// exercising the business rules in services without a database
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+type membershipKey struct {
+	classId string
+	userId  string
+}
+
+// Store keeps rows by value in maps, so callers cannot change them in place
+type Store struct {
+	mu sync.Mutex
+	// work lets one unit of work run at a time
+	work        sync.Mutex
+	users       map[string]models.Users
+	sessions    map[string]models.Session
+	classrooms  map[string]models.Classroom
+	members     map[membershipKey]models.ClassroomCollaborator
+	assignments map[string]models.Assignments
+	attachments map[string]models.Attachment
+	invitations map[string]models.Invitation
+	// notifications are kept in the order they were queued
+	notifications []models.Notification
+	categories    map[uint]string
+	nextId        uint
+}
+
+func New() *Store {
+	return &Store{
+		users:       map[string]models.Users{},
+		sessions:    map[string]models.Session{},
+		classrooms:  map[string]models.Classroom{},
+		members:     map[membershipKey]models.ClassroomCollaborator{},
+		assignments: map[string]models.Assignments{},
+		attachments: map[string]models.Attachment{},
+		invitations: map[string]models.Invitation{},
+		categories:  map[uint]string{},
+	}
+}
+
+// Stores returns the store as every services store
+func (s *Store) Stores() services.Stores {
+	return services.Stores{
+		Users:         s,
+		Sessions:      s,
+		Classrooms:    s,
+		Members:       s,
+		Assignments:   s,
+		Notifications: s,
+		Invitations:   s,
+		Work:          s,
+	}
+}
+
+// Do runs fn against the store and puts every row back as it was when fn fails
+func (s *Store) Do(fn func(stores services.Stores) error) error {
+	s.work.Lock()
+	defer s.work.Unlock()
+
+	s.mu.Lock()
+	saved := s.copy()
+	s.mu.Unlock()
+
+	err := fn(s.Stores())
+
+	if err != nil {
+		s.mu.Lock()
+		s.users, s.sessions = saved.users, saved.sessions
+		s.classrooms, s.members = saved.classrooms, saved.members
+		s.assignments, s.attachments = saved.assignments, saved.attachments
+		s.invitations, s.notifications = saved.invitations, saved.notifications
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// copy duplicates the rows of the store
+func (s *Store) copy() *Store {
+	saved := New()
+
+	for key, row := range s.users {
+		saved.users[key] = row
+	}
+	for key, row := range s.sessions {
+		saved.sessions[key] = row
+	}
+	for key, row := range s.classrooms {
+		saved.classrooms[key] = row
+	}
+	for key, row := range s.members {
+		saved.members[key] = row
+	}
+	for key, row := range s.assignments {
+		saved.assignments[key] = row
+	}
+	for key, row := range s.attachments {
+		saved.attachments[key] = row
+	}
+	for key, row := range s.invitations {
+		saved.invitations[key] = row
+	}
+	saved.notifications = append([]models.Notification(nil), s.notifications...)
+	return saved
+}
+
+// AddUser seeds a user as it is, e.g. with a legacy plaintext password
+func (s *Store) AddUser(user models.Users) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[*user.Uuid] = user
+}
+
+// AddClassroom seeds a classroom as it is, e.g. archived or trashed
+func (s *Store) AddClassroom(classroom models.Classroom) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.classrooms[*classroom.ClassId] = classroom
+}
+
+// AddMembership seeds a membership row as it is, e.g. a pending join request
+func (s *Store) AddMembership(membership models.ClassroomCollaborator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.members[membershipKey{*membership.ClassID, *membership.UserID}] = membership
+}
+
+// AddInvitation seeds an invitation as it is
+func (s *Store) AddInvitation(invitation models.Invitation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.invitations[*invitation.ID] = invitation
+}
+
+// Invitation returns an invitation as it is stored, nil when there is none
+func (s *Store) Invitation(id string) *models.Invitation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invitation, ok := s.invitations[id]
+	if !ok {
+		return nil
+	}
+	return &invitation
+}
+
+// Notifications returns the notifications queued for a user, oldest first
+func (s *Store) Notifications(userId string) []models.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications := []models.Notification{}
+	for _, notification := range s.notifications {
+		if *notification.UserID == userId {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications
+}
+
+// AddCategory seeds a grade category of a classroom and returns its id
+func (s *Store) AddCategory(classId string) uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextId++
+	s.categories[s.nextId] = classId
+	return s.nextId
+}
+
+// timeKey makes times sort as strings
+func timeKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000")
+}
+
+func stringKey(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// paginate sorts rows by the page's field, ties broken by id, and cuts out
+// the page with one row past the limit, as the database stores do. key
+// returns the value a row is sorted by for a field.
+func paginate[T any](rows []T, page services.Page, key func(row *T, field string) string, id func(row *T) string) []T {
+	less := func(a, b *T) bool {
+		keyA, keyB := key(a, page.Sort), key(b, page.Sort)
+		if keyA != keyB {
+			return keyA < keyB
+		}
+		return id(a) < id(b)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if page.Desc {
+			return less(&rows[j], &rows[i])
+		}
+		return less(&rows[i], &rows[j])
+	})
+
+	start := page.Offset
+	if page.After != nil {
+		after := timeKey(page.After.CreatedAt)
+
+		start = sort.Search(len(rows), func(i int) bool {
+			rowKey, rowId := key(&rows[i], "created_at"), id(&rows[i])
+			if page.Desc {
+				return rowKey < after || (rowKey == after && rowId < page.After.ID)
+			}
+			return rowKey > after || (rowKey == after && rowId > page.After.ID)
+		})
+	}
+
+	if start > len(rows) {
+		start = len(rows)
+	}
+
+	end := start + page.Limit + 1
+	if end > len(rows) {
+		end = len(rows)
+	}
+	return rows[start:end]
+}
+
+/*------------------------------------------------ users ------------------------------------------------------*/
+
+func (s *Store) UsernameTaken(username string) (bool, error) {
+	user, _ := s.UserByUsername(username)
+	return user != nil, nil
+}
+
+func (s *Store) UserByUsername(username string) (*models.Users, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Username != nil && strings.EqualFold(*user.Username, username) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Store) UserByID(userId string) (*models.Users, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if !ok {
+		return nil, nil
+	}
+
+	user.Collaborations = nil
+	for _, membership := range s.members {
+		if *membership.UserID == userId && !membership.IsRemoved && membership.Status == models.MembershipActive {
+			user.Collaborations = append(user.Collaborations, membership)
+		}
+	}
+	return &user, nil
+}
+
+func (s *Store) CreateUser(user *models.Users) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[*user.Uuid]; ok {
+		return services.ErrDuplicate
+	}
+
+	for _, existing := range s.users {
+		if existing.Username != nil && user.Username != nil && strings.EqualFold(*existing.Username, *user.Username) {
+			return services.ErrDuplicate
+		}
+	}
+
+	s.users[*user.Uuid] = *user
+	return nil
+}
+
+func (s *Store) SetPassword(userId string, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userId]
+	if ok {
+		user.Password = &passwordHash
+		s.users[userId] = user
+	}
+	return nil
+}
+
+/*------------------------------------------------ sessions ------------------------------------------------------*/
+
+func (s *Store) CreateSession(session *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, existing := range s.sessions {
+		if id == *session.ID || existing.RefreshTokenHash == session.RefreshTokenHash {
+			return services.ErrDuplicate
+		}
+	}
+
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+
+	s.sessions[*session.ID] = *session
+	return nil
+}
+
+func (s *Store) LiveSession(id string, userId string, now time.Time) (*models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || *session.UserID != userId || session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+// sessionWhere finds the first session matching a condition
+func (s *Store) sessionWhere(match func(session *models.Session) bool) *models.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		if match(&session) {
+			return &session
+		}
+	}
+	return nil
+}
+
+func (s *Store) SessionByToken(tokenHash string) (*models.Session, error) {
+	return s.sessionWhere(func(session *models.Session) bool {
+		return session.RefreshTokenHash == tokenHash
+	}), nil
+}
+
+func (s *Store) SessionByPreviousToken(tokenHash string) (*models.Session, error) {
+	return s.sessionWhere(func(session *models.Session) bool {
+		return session.PreviousTokenHash == tokenHash
+	}), nil
+}
+
+func (s *Store) RotateToken(id string, from string, to string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.RefreshTokenHash != from {
+		return false, nil
+	}
+
+	session.RefreshTokenHash, session.PreviousTokenHash, session.LastUsedAt = to, from, at
+	s.sessions[id] = session
+	return true, nil
+}
+
+func (s *Store) RevokeSessions(userId string, id string, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked int64
+
+	for key, session := range s.sessions {
+		if *session.UserID != userId || session.RevokedAt != nil || (len(id) != 0 && key != id) {
+			continue
+		}
+
+		session.RevokedAt = &at
+		s.sessions[key] = session
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (s *Store) ListSessions(userId string, now time.Time, page services.Page) ([]models.Session, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := []models.Session{}
+
+	for _, session := range s.sessions {
+		if *session.UserID == userId && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	total := int64(len(sessions))
+
+	sessions = paginate(sessions, page, func(session *models.Session, field string) string {
+		if field == "last_used_at" {
+			return timeKey(session.LastUsedAt)
+		}
+		return timeKey(session.CreatedAt)
+	}, func(session *models.Session) string {
+		return *session.ID
+	})
+	return sessions, total, nil
+}
+
+/*------------------------------------------------ classrooms ------------------------------------------------------*/
+
+func (s *Store) Classroom(classId string) (*models.Classroom, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classroom, ok := s.classrooms[classId]
+	if !ok || classroom.IsDeleted {
+		return nil, nil
+	}
+	return &classroom, nil
+}
+
+func (s *Store) LoadOwner(classroom *models.Classroom) error {
+	if classroom.OwnerID == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classroom.Owner = s.users[*classroom.OwnerID]
+	return nil
+}
+
+func (s *Store) ClassroomByJoinCode(code string) (*models.Classroom, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, classroom := range s.classrooms {
+		if classroom.JoinCode != nil && *classroom.JoinCode == code && !classroom.IsDeleted {
+			return &classroom, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Store) UnusedJoinCode() (string, error) {
+	for {
+		code, err := utils.GenerateJoinCode()
+		if err != nil {
+			return "", err
+		}
+
+		classroom, _ := s.ClassroomByJoinCode(code)
+		if classroom == nil {
+			return code, nil
+		}
+	}
+}
+
+func (s *Store) CreateClassroom(classroom *models.Classroom) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.classrooms[*classroom.ClassId]; ok {
+		return services.ErrDuplicate
+	}
+
+	if classroom.CreatedAt.IsZero() {
+		classroom.CreatedAt = time.Now()
+	}
+
+	s.classrooms[*classroom.ClassId] = *classroom
+	return nil
+}
+
+// UpdateClassroom stores the whole row; the columns only matter to a database
+func (s *Store) UpdateClassroom(classroom *models.Classroom, columns ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.classrooms[*classroom.ClassId] = *classroom
+	return nil
+}
+
+func (s *Store) ListClassrooms(userId string, filter services.ClassroomFilter, page services.Page) ([]models.Classroom, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classrooms := []models.Classroom{}
+
+	for _, classroom := range s.classrooms {
+		owned := classroom.OwnerID != nil && *classroom.OwnerID == userId
+		membership, member := s.members[membershipKey{*classroom.ClassId, userId}]
+		member = member && !membership.IsRemoved && membership.Status == models.MembershipActive
+
+		switch {
+		case classroom.IsDeleted || (!owned && !member):
+			continue
+		case filter.Role == services.RoleOwner && !owned:
+			continue
+		case len(filter.Role) != 0 && filter.Role != services.RoleOwner && (owned || membership.Role != filter.Role):
+			continue
+		case filter.Done != nil && classroom.Done != *filter.Done:
+			continue
+		case len(filter.Search) != 0 && !strings.Contains(strings.ToLower(stringKey(classroom.ClassName)), strings.ToLower(filter.Search)):
+			continue
+		}
+
+		if classroom.OwnerID != nil {
+			classroom.Owner = s.users[*classroom.OwnerID]
+		}
+		classrooms = append(classrooms, classroom)
+	}
+
+	total := int64(len(classrooms))
+
+	classrooms = paginate(classrooms, page, func(classroom *models.Classroom, field string) string {
+		if field == "class_name" {
+			return stringKey(classroom.ClassName)
+		}
+		return timeKey(classroom.CreatedAt)
+	}, func(classroom *models.Classroom) string {
+		return *classroom.ClassId
+	})
+	return classrooms, total, nil
+}
+
+func (s *Store) ChangeOwner(classId string, from string, to string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classroom, ok := s.classrooms[classId]
+	if !ok || classroom.OwnerID == nil || *classroom.OwnerID != from {
+		return false, nil
+	}
+
+	classroom.OwnerID = &to
+	s.classrooms[classId] = classroom
+	return true, nil
+}
+
+func (s *Store) TrashedClassroom(classId string, ownerId string) (*models.Classroom, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classroom, ok := s.classrooms[classId]
+	if !ok || !classroom.IsDeleted || classroom.OwnerID == nil || *classroom.OwnerID != ownerId {
+		return nil, nil
+	}
+	return &classroom, nil
+}
+
+func trashedKey(classroom *models.Classroom) string {
+	if classroom.TrashedAt == nil {
+		return ""
+	}
+	return timeKey(*classroom.TrashedAt)
+}
+
+func (s *Store) ListTrash(ownerId string, page services.Page) ([]models.Classroom, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classrooms := []models.Classroom{}
+
+	for _, classroom := range s.classrooms {
+		if classroom.IsDeleted && classroom.OwnerID != nil && *classroom.OwnerID == ownerId {
+			classrooms = append(classrooms, classroom)
+		}
+	}
+
+	total := int64(len(classrooms))
+
+	classrooms = paginate(classrooms, page, func(classroom *models.Classroom, field string) string {
+		switch field {
+		case "class_name":
+			return stringKey(classroom.ClassName)
+		case "created_at":
+			return timeKey(classroom.CreatedAt)
+		}
+		return trashedKey(classroom)
+	}, func(classroom *models.Classroom) string {
+		return *classroom.ClassId
+	})
+	return classrooms, total, nil
+}
+
+func (s *Store) ExpiredTrash(cutoff time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classIds := []string{}
+
+	for classId, classroom := range s.classrooms {
+		if classroom.IsDeleted && classroom.TrashedAt != nil && classroom.TrashedAt.Before(cutoff) {
+			classIds = append(classIds, classId)
+		}
+	}
+	return classIds, nil
+}
+
+// PurgeClassroom deletes the classroom with its memberships, assignments,
+// attachments, invitations and notifications, as the database cascades do
+func (s *Store) PurgeClassroom(classId string, cutoff time.Time) (bool, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classroom, ok := s.classrooms[classId]
+	if !ok || !classroom.IsDeleted || classroom.TrashedAt == nil || !classroom.TrashedAt.Before(cutoff) {
+		return false, nil, nil
+	}
+
+	delete(s.classrooms, classId)
+
+	for key := range s.members {
+		if key.classId == classId {
+			delete(s.members, key)
+		}
+	}
+	for id, assignment := range s.assignments {
+		if stringKey(assignment.ClassID) == classId {
+			delete(s.assignments, id)
+		}
+	}
+	for id, invitation := range s.invitations {
+		if stringKey(invitation.ClassID) == classId {
+			delete(s.invitations, id)
+		}
+	}
+
+	seen := map[string]bool{}
+	hashes := []string{}
+
+	for id, attachment := range s.attachments {
+		if stringKey(attachment.ClassID) != classId {
+			continue
+		}
+		if !seen[attachment.Hash] {
+			seen[attachment.Hash] = true
+			hashes = append(hashes, attachment.Hash)
+		}
+		delete(s.attachments, id)
+	}
+
+	kept := []models.Notification{}
+	for _, notification := range s.notifications {
+		if stringKey(notification.ClassID) != classId {
+			kept = append(kept, notification)
+		}
+	}
+	s.notifications = kept
+	return true, hashes, nil
+}
+
+/*------------------------------------------------ membership ------------------------------------------------------*/
+
+func (s *Store) Membership(classId string, userId string) (*models.ClassroomCollaborator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	membership, ok := s.members[membershipKey{classId, userId}]
+	if !ok {
+		return nil, nil
+	}
+	return &membership, nil
+}
+
+func (s *Store) ActiveMember(classId string, userId string) (*models.ClassroomCollaborator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	membership, ok := s.members[membershipKey{classId, userId}]
+	if !ok || membership.IsRemoved || membership.Status != models.MembershipActive {
+		return nil, nil
+	}
+
+	membership.User = s.users[userId]
+	return &membership, nil
+}
+
+func (s *Store) ActiveMemberships(userId string, classIds []string) ([]models.ClassroomCollaborator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memberships := []models.ClassroomCollaborator{}
+
+	for _, classId := range classIds {
+		membership, ok := s.members[membershipKey{classId, userId}]
+		if ok && !membership.IsRemoved && membership.Status == models.MembershipActive {
+			memberships = append(memberships, membership)
+		}
+	}
+	return memberships, nil
+}
+
+func (s *Store) ListMembers(classId string, filter services.MemberFilter, page services.Page) ([]models.ClassroomCollaborator, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := []models.ClassroomCollaborator{}
+	search := strings.ToLower(filter.Search)
+
+	for key, membership := range s.members {
+		if key.classId != classId || membership.IsRemoved || membership.Status != models.MembershipActive {
+			continue
+		}
+		if len(filter.Role) != 0 && membership.Role != filter.Role {
+			continue
+		}
+
+		membership.User = s.users[key.userId]
+
+		name, username := strings.ToLower(stringKey(membership.User.Name)), strings.ToLower(stringKey(membership.User.Username))
+		if len(search) != 0 && !strings.Contains(name, search) && !strings.Contains(username, search) {
+			continue
+		}
+		members = append(members, membership)
+	}
+
+	total := int64(len(members))
+
+	members = paginate(members, page, func(membership *models.ClassroomCollaborator, field string) string {
+		switch field {
+		case "username":
+			return stringKey(membership.User.Username)
+		case "role":
+			return membership.Role
+		}
+		return stringKey(membership.User.Name)
+	}, func(membership *models.ClassroomCollaborator) string {
+		return *membership.UserID
+	})
+	return members, total, nil
+}
+
+func requestedKey(request *models.ClassroomCollaborator) string {
+	if request.RequestedAt == nil {
+		return ""
+	}
+	return timeKey(*request.RequestedAt)
+}
+
+func (s *Store) ListJoinRequests(classId string, page services.Page) ([]models.ClassroomCollaborator, int64, error) {
+	requests, _ := s.JoinRequests(classId, nil)
+
+	total := int64(len(requests))
+
+	requests = paginate(requests, page, func(request *models.ClassroomCollaborator, field string) string {
+		switch field {
+		case "name":
+			return stringKey(request.User.Name)
+		case "username":
+			return stringKey(request.User.Username)
+		}
+		return requestedKey(request)
+	}, func(request *models.ClassroomCollaborator) string {
+		return *request.UserID
+	})
+	return requests, total, nil
+}
+
+func (s *Store) JoinRequests(classId string, userIds []string) ([]models.ClassroomCollaborator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := map[string]bool{}
+	for _, userId := range userIds {
+		wanted[userId] = true
+	}
+
+	requests := []models.ClassroomCollaborator{}
+
+	for key, membership := range s.members {
+		if key.classId != classId || membership.Status != models.MembershipPending {
+			continue
+		}
+		if userIds != nil && !wanted[key.userId] {
+			continue
+		}
+
+		membership.User = s.users[key.userId]
+		requests = append(requests, membership)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requestedKey(&requests[i]) < requestedKey(&requests[j])
+	})
+	return requests, nil
+}
+
+func (s *Store) DecideJoinRequests(classId string, userIds []string, status string, decidedBy string, decidedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, userId := range userIds {
+		key := membershipKey{classId, userId}
+
+		membership, ok := s.members[key]
+		if !ok || membership.Status != models.MembershipPending {
+			continue
+		}
+
+		membership.Status = status
+		membership.DecidedBy = &decidedBy
+		membership.DecidedAt = &decidedAt
+		s.members[key] = membership
+	}
+	return nil
+}
+
+func (s *Store) CreateMembership(membership *models.ClassroomCollaborator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := membershipKey{*membership.ClassID, *membership.UserID}
+
+	if _, ok := s.members[key]; ok {
+		return services.ErrDuplicate
+	}
+
+	s.members[key] = *membership
+	return nil
+}
+
+// UpdateMembership stores the whole row; the columns only matter to a database
+func (s *Store) UpdateMembership(membership *models.ClassroomCollaborator, columns ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.members[membershipKey{*membership.ClassID, *membership.UserID}] = *membership
+	return nil
+}
+
+/*------------------------------------------------ assignments ------------------------------------------------------*/
+
+func (s *Store) Assignment(id string) (*models.Assignments, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assignment, ok := s.assignments[id]
+	if !ok || assignment.IsDeleted {
+		return nil, nil
+	}
+	return &assignment, nil
+}
+
+func (s *Store) ListAssignments(classId string, filter services.AssignmentFilter, page services.Page) ([]models.Assignments, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assignments := []models.Assignments{}
+
+	for _, assignment := range s.assignments {
+		switch {
+		case assignment.IsDeleted || assignment.ClassID == nil || *assignment.ClassID != classId:
+			continue
+		case filter.PublishedBy != nil && !assignment.IsPublished(*filter.PublishedBy):
+			continue
+		case filter.Draft != nil && assignment.IsDraft != *filter.Draft:
+			continue
+		case len(filter.Type) != 0 && stringKey(assignment.Type) != filter.Type:
+			continue
+		case filter.CategoryID != nil && (assignment.CategoryID == nil || *assignment.CategoryID != *filter.CategoryID):
+			continue
+		}
+
+		assignment.Classroom = s.classrooms[classId]
+		if assignment.AutherId != nil {
+			assignment.CreatedBy = s.users[*assignment.AutherId]
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	total := int64(len(assignments))
+
+	assignments = paginate(assignments, page, func(assignment *models.Assignments, field string) string {
+		switch field {
+		case "title":
+			return stringKey(assignment.Title)
+		case "due_at":
+			if assignment.DueAt == nil {
+				return ""
+			}
+			return timeKey(*assignment.DueAt)
+		}
+		return timeKey(assignment.CreatedAt)
+	}, func(assignment *models.Assignments) string {
+		return *assignment.ID
+	})
+	return assignments, total, nil
+}
+
+func (s *Store) CategoryInClassroom(classId string, categoryId uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.categories[categoryId] == classId, nil
+}
+
+func (s *Store) CreateAssignment(assignment *models.Assignments) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.assignments[*assignment.ID]; ok {
+		return services.ErrDuplicate
+	}
+
+	if assignment.CreatedAt.IsZero() {
+		assignment.CreatedAt = time.Now()
+	}
+
+	s.assignments[*assignment.ID] = *assignment
+	return nil
+}
+
+// UpdateAssignment stores the whole row; the columns only matter to a database
+func (s *Store) UpdateAssignment(assignment *models.Assignments, columns ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.assignments[*assignment.ID] = *assignment
+	return nil
+}
+
+func (s *Store) CreateAttachments(attachments []models.Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, attachment := range attachments {
+		if _, ok := s.attachments[*attachment.ID]; ok {
+			return services.ErrDuplicate
+		}
+	}
+
+	for _, attachment := range attachments {
+		s.attachments[*attachment.ID] = attachment
+	}
+	return nil
+}
+
+/*------------------------------------------------ notifications ------------------------------------------------------*/
+
+func (s *Store) Notify(userIds []string, classId *string, kind string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range userIds {
+		s.nextId++
+		s.notifications = append(s.notifications, models.Notification{
+			ID:        s.nextId,
+			UserID:    &userIds[i],
+			ClassID:   classId,
+			Type:      kind,
+			Message:   message,
+			CreatedAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+func (s *Store) ListNotifications(userId string, unread bool, page services.Page) ([]models.Notification, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications := []models.Notification{}
+
+	for _, notification := range s.notifications {
+		if *notification.UserID == userId && (!unread || notification.ReadAt == nil) {
+			notifications = append(notifications, notification)
+		}
+	}
+
+	total := int64(len(notifications))
+
+	notifications = paginate(notifications, page, func(notification *models.Notification, field string) string {
+		return timeKey(notification.CreatedAt)
+	}, func(notification *models.Notification) string {
+		// ids sort as numbers, as they do in the database
+		return fmt.Sprintf("%020d", notification.ID)
+	})
+	return notifications, total, nil
+}
+
+func (s *Store) CountUnread(userId string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var unread int64
+
+	for _, notification := range s.notifications {
+		if *notification.UserID == userId && notification.ReadAt == nil {
+			unread++
+		}
+	}
+	return unread, nil
+}
+
+func (s *Store) MarkRead(userId string, id uint, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, notification := range s.notifications {
+		if notification.ID != id || *notification.UserID != userId {
+			continue
+		}
+
+		if notification.ReadAt == nil {
+			s.notifications[i].ReadAt = &at
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *Store) MarkAllRead(userId string, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updated int64
+
+	for i, notification := range s.notifications {
+		if *notification.UserID == userId && notification.ReadAt == nil {
+			s.notifications[i].ReadAt = &at
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+/*------------------------------------------------ invitations ------------------------------------------------------*/
+
+func (s *Store) ClassInvitation(classId string, id string) (*models.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invitation, ok := s.invitations[id]
+	if !ok || stringKey(invitation.ClassID) != classId {
+		return nil, nil
+	}
+	return &invitation, nil
+}
+
+func (s *Store) InvitationByToken(token string) (*models.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, invitation := range s.invitations {
+		if invitation.Token == token {
+			invitation.Classroom = s.classrooms[stringKey(invitation.ClassID)]
+			return &invitation, nil
+		}
+	}
+	return nil, nil
+}
+
+// pageInvitations counts invitations and cuts out a page of them
+func pageInvitations(invitations []models.Invitation, page services.Page) ([]models.Invitation, int64, error) {
+	total := int64(len(invitations))
+
+	invitations = paginate(invitations, page, func(invitation *models.Invitation, field string) string {
+		if field == "expires_at" {
+			if invitation.ExpiresAt == nil {
+				return ""
+			}
+			return timeKey(*invitation.ExpiresAt)
+		}
+		return timeKey(invitation.CreatedAt)
+	}, func(invitation *models.Invitation) string {
+		return *invitation.ID
+	})
+	return invitations, total, nil
+}
+
+func (s *Store) ListInvitations(classId string, page services.Page) ([]models.Invitation, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invitations := []models.Invitation{}
+
+	for _, invitation := range s.invitations {
+		if stringKey(invitation.ClassID) != classId || invitation.RevokedAt != nil {
+			continue
+		}
+
+		if invitation.InviteeID != nil {
+			invitation.Invitee = s.users[*invitation.InviteeID]
+		}
+		invitations = append(invitations, invitation)
+	}
+	return pageInvitations(invitations, page)
+}
+
+func (s *Store) ListPendingInvitations(userId string, now time.Time, page services.Page) ([]models.Invitation, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invitations := []models.Invitation{}
+
+	for _, invitation := range s.invitations {
+		classroom, ok := s.classrooms[stringKey(invitation.ClassID)]
+
+		switch {
+		case !ok || classroom.IsDeleted:
+			continue
+		case stringKey(invitation.InviteeID) != userId || invitation.RevokedAt != nil || invitation.DeclinedAt != nil:
+			continue
+		case invitation.Uses != 0 || (invitation.ExpiresAt != nil && !invitation.ExpiresAt.After(now)):
+			continue
+		}
+
+		invitation.Classroom = classroom
+		invitations = append(invitations, invitation)
+	}
+	return pageInvitations(invitations, page)
+}
+
+func (s *Store) CreateInvitation(invitation *models.Invitation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.invitations[*invitation.ID]; ok {
+		return services.ErrDuplicate
+	}
+
+	if invitation.CreatedAt.IsZero() {
+		invitation.CreatedAt = time.Now()
+	}
+
+	stored := *invitation
+	stored.Classroom, stored.Invitee = models.Classroom{}, models.Users{}
+	s.invitations[*invitation.ID] = stored
+	return nil
+}
+
+// UpdateInvitation stores the whole row; the columns only matter to a database
+func (s *Store) UpdateInvitation(invitation *models.Invitation, columns ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *invitation
+	stored.Classroom, stored.Invitee = models.Classroom{}, models.Users{}
+	s.invitations[*invitation.ID] = stored
+	return nil
+}
+
+func (s *Store) UseInvitation(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invitation, ok := s.invitations[id]
+	if !ok || invitation.RevokedAt != nil || invitation.DeclinedAt != nil {
+		return false, nil
+	}
+	if invitation.MaxUses != nil && invitation.Uses >= *invitation.MaxUses {
+		return false, nil
+	}
+
+	invitation.Uses++
+	s.invitations[id] = invitation
+	return true, nil
+}
+
+func (s *Store) DeclineInvitation(id string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invitation, ok := s.invitations[id]
+	if !ok || invitation.RevokedAt != nil || invitation.DeclinedAt != nil || invitation.Uses != 0 {
+		return false, nil
+	}
+
+	invitation.DeclinedAt = &at
+	s.invitations[id] = invitation
+	return true, nil
+}
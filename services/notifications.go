@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+)
+
+// notification types
+const (
+	NotifyJoinApproved = "join_request_approved"
+	NotifyJoinRejected = "join_request_rejected"
+	NotifyOwnership    = "ownership_transferred"
+	NotifyRoleChanged  = "role_changed"
+)
+
+// classroomName is how notifications refer to a classroom
+func classroomName(classroom *models.Classroom) string {
+	if classroom.ClassName == nil {
+		return "the classroom"
+	}
+	return *classroom.ClassName
+}
+
+// NotificationService lets users read the notifications queued for them
+type NotificationService struct {
+	notifications NotificationStore
+}
+
+// List pages through a user's notifications, only the unread ones when
+// unread is set. Besides the total of the list it counts every unread one.
+func (s *NotificationService) List(user *models.Users, unread bool, page Page) ([]models.Notification, int64, int64, error) {
+	notifications, total, err := s.notifications.ListNotifications(*user.Uuid, unread, page)
+
+	if err != nil {
+		return nil, 0, 0, apperr.Internal(err, "could not get notifications")
+	}
+
+	unreadCount, err := s.notifications.CountUnread(*user.Uuid)
+
+	if err != nil {
+		return nil, 0, 0, apperr.Internal(err, "could not get notifications")
+	}
+	return notifications, total, unreadCount, nil
+}
+
+// MarkRead marks one of a user's notifications read. Someone else's
+// notification is as good as missing.
+func (s *NotificationService) MarkRead(user *models.Users, notificationId string) error {
+	id, err := strconv.ParseUint(notificationId, 10, 64)
+	if err != nil {
+		return apperr.NotFound("notification not found")
+	}
+
+	found, err := s.notifications.MarkRead(*user.Uuid, uint(id), time.Now())
+
+	if err != nil {
+		return writeFailed(err, "database update failed")
+	}
+
+	if !found {
+		return apperr.NotFound("notification not found")
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of a user read and returns how many there were
+func (s *NotificationService) MarkAllRead(user *models.Users) (int64, error) {
+	updated, err := s.notifications.MarkAllRead(*user.Uuid, time.Now())
+
+	if err != nil {
+		return 0, writeFailed(err, "database update failed")
+	}
+	return updated, nil
+}
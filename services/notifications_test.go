@@ -0,0 +1,60 @@
+package services_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+func TestNotifications(t *testing.T) {
+	svc, store := newServices()
+
+	student := addUser(store, "student")
+	teacher := addUser(store, "teacher")
+
+	for _, message := range []string{"first", "second", "third"} {
+		_ = store.Notify([]string{*student.Uuid}, nil, services.NotifyRoleChanged, message)
+	}
+
+	page := services.Page{Limit: 2, Sort: "created_at", Desc: true}
+
+	notifications, total, unread, err := svc.Notifications.List(student, false, page)
+	if err != nil || total != 3 || unread != 3 || len(notifications) != 3 {
+		t.Fatalf("expected a page of three unread notifications, got %d of %d, %d unread, %v", len(notifications), total, unread, err)
+	}
+
+	read := strconv.FormatUint(uint64(notifications[0].ID), 10)
+
+	// someone else's notification is as good as missing
+	expectCode(t, svc.Notifications.MarkRead(teacher, read), apperr.CodeNotFound)
+	expectCode(t, svc.Notifications.MarkRead(student, "nope"), apperr.CodeNotFound)
+
+	if err = svc.Notifications.MarkRead(student, read); err != nil {
+		t.Fatal(err)
+	}
+	first := store.Notifications(*student.Uuid)[2].ReadAt
+
+	// marking it again keeps the time it was first read
+	if err = svc.Notifications.MarkRead(student, read); err != nil {
+		t.Fatal(err)
+	}
+	if again := store.Notifications(*student.Uuid)[2].ReadAt; again == nil || !again.Equal(*first) {
+		t.Fatalf("expected the first read time to be kept, got %v", again)
+	}
+
+	_, total, unread, _ = svc.Notifications.List(student, true, page)
+	if total != 2 || unread != 2 {
+		t.Fatalf("expected two unread notifications, got %d of %d", unread, total)
+	}
+
+	updated, err := svc.Notifications.MarkAllRead(student)
+	if err != nil || updated != 2 {
+		t.Fatalf("expected two notifications to be marked read, got %d, %v", updated, err)
+	}
+
+	if _, _, unread, _ = svc.Notifications.List(student, false, page); unread != 0 {
+		t.Fatalf("expected nothing unread, got %d", unread)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+// roles a caller can hold inside a classroom
+const (
+	RoleOwner   = "owner"
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
+	RoleRemoved = "removed"
+)
+
+// permissions enforced on classroom scoped routes
+const (
+	PermViewClassroom     = "classroom:view"
+	PermEditClassroom     = "classroom:edit"
+	PermExitClassroom     = "classroom:exit"
+	PermViewMembers       = "members:view"
+	PermRemoveMember      = "members:remove"
+	PermRemoveTeacher     = "members:remove_teacher"
+	PermInviteStudent     = "members:invite"
+	PermInviteTeacher     = "members:invite_teacher"
+	PermApproveMembers    = "members:approve"
+	PermManageJoinCode    = "classroom:join_code"
+	PermChangeRoles       = "members:change_role"
+	PermTransferOwnership = "classroom:transfer"
+	PermArchiveClassroom  = "classroom:archive"
+	PermDeleteClassroom   = "classroom:delete"
+	PermViewAssignments   = "assignments:view"
+	PermManageAssignments = "assignments:manage"
+	PermViewComments      = "comments:view"
+	PermPostComment       = "comments:post"
+	PermModerateComments  = "comments:moderate"
+	PermSubmitWork        = "submissions:submit"
+	PermReviewWork        = "submissions:review"
+	PermManageGrades      = "grades:manage"
+)
+
+// classPermissions is the permission matrix: which roles may do what.
+// Removed members and non-members are denied everything.
+var classPermissions = map[string][]string{
+	PermViewClassroom:     {RoleOwner, RoleTeacher, RoleStudent},
+	PermEditClassroom:     {RoleOwner},
+	PermExitClassroom:     {RoleOwner, RoleTeacher, RoleStudent},
+	PermViewMembers:       {RoleOwner, RoleTeacher, RoleStudent},
+	PermRemoveMember:      {RoleOwner, RoleTeacher},
+	PermRemoveTeacher:     {RoleOwner},
+	PermInviteStudent:     {RoleOwner, RoleTeacher},
+	PermInviteTeacher:     {RoleOwner},
+	PermApproveMembers:    {RoleOwner, RoleTeacher},
+	PermManageJoinCode:    {RoleOwner, RoleTeacher},
+	PermChangeRoles:       {RoleOwner},
+	PermTransferOwnership: {RoleOwner},
+	PermArchiveClassroom:  {RoleOwner},
+	PermDeleteClassroom:   {RoleOwner},
+	PermViewAssignments:   {RoleOwner, RoleTeacher, RoleStudent},
+	PermManageAssignments: {RoleOwner, RoleTeacher},
+	PermViewComments:      {RoleOwner, RoleTeacher, RoleStudent},
+	PermPostComment:       {RoleOwner, RoleTeacher, RoleStudent},
+	PermModerateComments:  {RoleOwner, RoleTeacher},
+	PermSubmitWork:        {RoleStudent},
+	PermReviewWork:        {RoleOwner, RoleTeacher},
+	PermManageGrades:      {RoleOwner, RoleTeacher},
+}
+
+// permissions that still apply to an archived classroom; anything else that
+// changes it is refused until it is unarchived
+var archivedAllowed = map[string]bool{
+	PermExitClassroom:     true,
+	PermTransferOwnership: true,
+	PermArchiveClassroom:  true,
+	PermDeleteClassroom:   true,
+}
+
+// Can reports whether a classroom role holds a permission
+func Can(role string, permission string) bool {
+	for _, allowed := range classPermissions[permission] {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+// Package services holds the business rules for users and their sessions,
+// classrooms, membership, assignments, invitations and notifications. The
+// rules read and write through the store interfaces below, implemented with
+// GORM in services/gormstore and in memory in services/memstore, so they can
+// be exercised without a database. Errors are *apperr.Error values ready to
+// be sent to the client.
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+// ErrDuplicate is returned by stores when a write breaks a unique constraint
+var ErrDuplicate = errors.New("duplicate record")
+
+// UserStore keeps user accounts. Lookups return nil when there is no match.
+type UserStore interface {
+	// UsernameTaken compares usernames case-insensitively
+	UsernameTaken(username string) (bool, error)
+	UserByUsername(username string) (*models.Users, error)
+	// UserByID loads the user with their active memberships
+	UserByID(userId string) (*models.Users, error)
+	CreateUser(user *models.Users) error
+	SetPassword(userId string, passwordHash string) error
+}
+
+// SessionStore keeps login sessions. Only digests of refresh tokens are
+// stored, and lookups return nil when there is no match.
+type SessionStore interface {
+	CreateSession(session *models.Session) error
+	// LiveSession loads a user's session that is neither revoked nor expired at now
+	LiveSession(id string, userId string, now time.Time) (*models.Session, error)
+	SessionByToken(tokenHash string) (*models.Session, error)
+	// SessionByPreviousToken loads the session a refresh token was rotated out of
+	SessionByPreviousToken(tokenHash string) (*models.Session, error)
+	// RotateToken replaces the refresh token digest of a session, reporting
+	// false when it was rotated meanwhile
+	RotateToken(id string, from string, to string, at time.Time) (bool, error)
+	// RevokeSessions revokes a user's live sessions, only the one with id
+	// when it is not empty, and counts them
+	RevokeSessions(userId string, id string, at time.Time) (int64, error)
+	// ListSessions pages through a user's live sessions at now and counts all of them
+	ListSessions(userId string, now time.Time, page Page) ([]models.Session, int64, error)
+}
+
+// ClassroomStore keeps classrooms. Trashed classrooms are only returned by
+// the methods for the trash.
+type ClassroomStore interface {
+	Classroom(classId string) (*models.Classroom, error)
+	LoadOwner(classroom *models.Classroom) error
+	ClassroomByJoinCode(code string) (*models.Classroom, error)
+	UnusedJoinCode() (string, error)
+	// ListClassrooms pages through the classrooms a user owns or is an active
+	// member of, with their owners, and counts all of them
+	ListClassrooms(userId string, filter ClassroomFilter, page Page) ([]models.Classroom, int64, error)
+	CreateClassroom(classroom *models.Classroom) error
+	// UpdateClassroom writes the named columns of classroom
+	UpdateClassroom(classroom *models.Classroom, columns ...string) error
+	// ChangeOwner hands a classroom to another user, reporting false when it
+	// was no longer owned by from
+	ChangeOwner(classId string, from string, to string) (bool, error)
+	// TrashedClassroom loads a classroom in its owner's trash, nil when there is none
+	TrashedClassroom(classId string, ownerId string) (*models.Classroom, error)
+	// ListTrash pages through the classrooms in a user's trash and counts all of them
+	ListTrash(ownerId string, page Page) ([]models.Classroom, int64, error)
+	// ExpiredTrash lists the ids of the classrooms trashed before cutoff
+	ExpiredTrash(cutoff time.Time) ([]string, error)
+	// PurgeClassroom deletes a classroom that is still in the trash since
+	// before cutoff, with its attachments and notifications, which do not
+	// cascade. It reports false when the classroom was restored meanwhile,
+	// and returns the content hashes the attachments used.
+	PurgeClassroom(classId string, cutoff time.Time) (bool, []string, error)
+}
+
+// MembershipStore keeps the membership rows of classrooms, including
+// removed members and join requests
+type MembershipStore interface {
+	Membership(classId string, userId string) (*models.ClassroomCollaborator, error)
+	// ActiveMember loads an active member with their user, nil when there is none
+	ActiveMember(classId string, userId string) (*models.ClassroomCollaborator, error)
+	// ActiveMemberships loads a user's active memberships of the given classrooms
+	ActiveMemberships(userId string, classIds []string) ([]models.ClassroomCollaborator, error)
+	// ListMembers pages through the active members of a classroom with their
+	// users, and counts all of them
+	ListMembers(classId string, filter MemberFilter, page Page) ([]models.ClassroomCollaborator, int64, error)
+	// ListJoinRequests pages through the pending join requests of a
+	// classroom with their users, and counts all of them
+	ListJoinRequests(classId string, page Page) ([]models.ClassroomCollaborator, int64, error)
+	// JoinRequests loads pending join requests with their users, oldest
+	// first, limited to some users when userIds is not nil
+	JoinRequests(classId string, userIds []string) ([]models.ClassroomCollaborator, error)
+	// DecideJoinRequests settles the pending join requests of the users
+	DecideJoinRequests(classId string, userIds []string, status string, decidedBy string, decidedAt time.Time) error
+	CreateMembership(membership *models.ClassroomCollaborator) error
+	// UpdateMembership writes the named columns of membership
+	UpdateMembership(membership *models.ClassroomCollaborator, columns ...string) error
+}
+
+// AssignmentStore keeps assignments. Deleted assignments are never returned.
+type AssignmentStore interface {
+	Assignment(id string) (*models.Assignments, error)
+	// ListAssignments pages through the assignments of a classroom with their
+	// classroom and author, and counts all of them
+	ListAssignments(classId string, filter AssignmentFilter, page Page) ([]models.Assignments, int64, error)
+	CategoryInClassroom(classId string, categoryId uint) (bool, error)
+	CreateAssignment(assignment *models.Assignments) error
+	CreateAttachments(attachments []models.Attachment) error
+	// UpdateAssignment writes the named columns of assignment
+	UpdateAssignment(assignment *models.Assignments, columns ...string) error
+}
+
+// NotificationStore queues notifications for users and keeps track of the
+// ones they read
+type NotificationStore interface {
+	// Notify queues the same notification for each user
+	Notify(userIds []string, classId *string, kind string, message string) error
+	// ListNotifications pages through a user's notifications, only the unread
+	// ones when unread is set, and counts all of them
+	ListNotifications(userId string, unread bool, page Page) ([]models.Notification, int64, error)
+	CountUnread(userId string) (int64, error)
+	// MarkRead marks one of a user's notifications read, keeping the time it
+	// was first read, and reports false when the user has no such notification
+	MarkRead(userId string, id uint, at time.Time) (bool, error)
+	// MarkAllRead marks a user's unread notifications read and counts them
+	MarkAllRead(userId string, at time.Time) (int64, error)
+}
+
+// InvitationStore keeps invitations into classrooms. Lookups return nil when
+// there is no match.
+type InvitationStore interface {
+	// ClassInvitation loads an invitation into a classroom by id
+	ClassInvitation(classId string, id string) (*models.Invitation, error)
+	// InvitationByToken loads an invitation with its classroom
+	InvitationByToken(token string) (*models.Invitation, error)
+	// ListInvitations pages through the invitations of a classroom that were
+	// not revoked, with their invitees, and counts all of them
+	ListInvitations(classId string, page Page) ([]models.Invitation, int64, error)
+	// ListPendingInvitations pages through the personal invitations into live
+	// classrooms still waiting on a user at now, with their classrooms, and
+	// counts all of them
+	ListPendingInvitations(userId string, now time.Time, page Page) ([]models.Invitation, int64, error)
+	CreateInvitation(invitation *models.Invitation) error
+	// UpdateInvitation writes the named columns of invitation
+	UpdateInvitation(invitation *models.Invitation, columns ...string) error
+	// UseInvitation counts one use of an invitation, reporting false when it
+	// was revoked, declined or used up meanwhile
+	UseInvitation(id string) (bool, error)
+	// DeclineInvitation turns down an invitation, reporting false when it was
+	// revoked, declined or used meanwhile
+	DeclineInvitation(id string, at time.Time) (bool, error)
+}
+
+// UnitOfWork runs writes that have to happen together. Do calls fn with
+// stores bound to one transaction, keeping every write when fn returns nil
+// and none of them otherwise. fn is called again when the transaction lost a
+// conflict with a concurrent one, so it must not keep state between calls.
+type UnitOfWork interface {
+	Do(fn func(stores Stores) error) error
+}
+
+// Stores is everything the services read and write through
+type Stores struct {
+	Users         UserStore
+	Sessions      SessionStore
+	Classrooms    ClassroomStore
+	Members       MembershipStore
+	Assignments   AssignmentStore
+	Notifications NotificationStore
+	Invitations   InvitationStore
+	Work          UnitOfWork
+}
+
+// Services bundles the domain services built on one set of stores
+type Services struct {
+	Users         *UserService
+	Sessions      *SessionService
+	Classrooms    *ClassroomService
+	Membership    *MembershipService
+	Assignments   *AssignmentService
+	Invitations   *InvitationService
+	Notifications *NotificationService
+}
+
+func New(stores Stores, avatars utils.AvatarProvider) *Services {
+	membership := &MembershipService{classrooms: stores.Classrooms, members: stores.Members, work: stores.Work}
+
+	return &Services{
+		Users:         &UserService{users: stores.Users, avatars: avatars},
+		Sessions:      &SessionService{sessions: stores.Sessions},
+		Classrooms:    &ClassroomService{classrooms: stores.Classrooms, members: stores.Members, work: stores.Work},
+		Membership:    membership,
+		Assignments:   &AssignmentService{assignments: stores.Assignments, membership: membership, work: stores.Work},
+		Invitations:   &InvitationService{invitations: stores.Invitations, users: stores.Users},
+		Notifications: &NotificationService{notifications: stores.Notifications},
+	}
+}
+
+// writeFailed reports a failed insert or update, as a conflict when it broke
+// a unique constraint
+func writeFailed(err error, message string) error {
+	if errors.Is(err, ErrDuplicate) {
+		return apperr.Conflict("this conflicts with an existing record")
+	}
+	return apperr.Internal(err, message)
+}
@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/services/memstore"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+func newServices() (*services.Services, *memstore.Store) {
+	store := memstore.New()
+	return services.New(store.Stores(), utils.InitialsAvatar{}), store
+}
+
+func str(s string) *string {
+	return &s
+}
+
+// expectCode fails unless err is an *apperr.Error with the code
+func expectCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	appErr := apperr.As(err)
+	if appErr == nil {
+		t.Fatalf("expected a %s error, got %v", code, err)
+	}
+	if appErr.Code != code {
+		t.Fatalf("expected a %s error, got %s: %s", code, appErr.Code, appErr.Message)
+	}
+}
+
+func register(t *testing.T, svc *services.Services, username string) *models.Users {
+	t.Helper()
+
+	user, err := svc.Users.Register(context.Background(), services.NewUser{
+		Username: str(username),
+		Name:     str(username),
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// addUser seeds a user without hashing a password, which register does slowly
+func addUser(store *memstore.Store, username string) *models.Users {
+	user := models.Users{Uuid: str("user-" + username), Username: str(username), Name: str(username)}
+	store.AddUser(user)
+	return &user
+}
+
+func createClassroom(t *testing.T, svc *services.Services, owner *models.Users) *models.Classroom {
+	t.Helper()
+
+	classroom, err := svc.Classrooms.Create(owner, services.NewClassroom{ClassName: str("maths")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return classroom
+}
+
+// join enrols user with the classroom's join code
+func join(t *testing.T, svc *services.Services, classroom *models.Classroom, user *models.Users) *models.ClassroomCollaborator {
+	t.Helper()
+
+	membership, err := svc.Membership.Join(user, *classroom.JoinCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return membership
+}
+
+// setClassroom changes a classroom the way a handler outside the services would
+func setClassroom(t *testing.T, store *memstore.Store, classId string, change func(classroom *models.Classroom)) {
+	t.Helper()
+
+	classroom, err := store.Classroom(classId)
+	if err != nil || classroom == nil {
+		t.Fatalf("classroom %s not found: %v", classId, err)
+	}
+
+	change(classroom)
+	store.AddClassroom(*classroom)
+}
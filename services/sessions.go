@@ -0,0 +1,156 @@
+package services
+
+import (
+	"time"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+)
+
+// RefreshTokenTTL is how long a session lasts without being refreshed
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// SessionService opens login sessions and rotates their refresh tokens.
+// Signing the access tokens that go with a session is left to the caller.
+type SessionService struct {
+	sessions SessionStore
+}
+
+// Open starts a session for user and returns it with its first refresh token
+func (s *SessionService) Open(user *models.Users) (*models.Session, string, error) {
+	sessionId, err := utils.GenerateUUid()
+	if err != nil {
+		return nil, "", apperr.Internal(err, "could not create session")
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, "", apperr.Internal(err, "could not create session")
+	}
+
+	now := time.Now()
+
+	session := models.Session{
+		ID:               &sessionId,
+		UserID:           user.Uuid,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		ExpiresAt:        now.Add(RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+
+	err = s.sessions.CreateSession(&session)
+
+	if err != nil {
+		return nil, "", apperr.Internal(err, "could not create session")
+	}
+	return &session, refreshToken, nil
+}
+
+// Live loads a session an access token names, nil when it was revoked or
+// expired or belongs to someone else
+func (s *SessionService) Live(sessionId string, userId string) (*models.Session, error) {
+	session, err := s.sessions.LiveSession(sessionId, userId, time.Now())
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not get session")
+	}
+	return session, nil
+}
+
+// Refresh exchanges a refresh token for a new one, returning the session it
+// belongs to. A token that was already rotated out being replayed means it
+// leaked, so the session it belonged to is revoked.
+func (s *SessionService) Refresh(refreshToken string) (*models.Session, string, error) {
+	now := time.Now()
+	tokenHash := utils.HashToken(refreshToken)
+
+	session, err := s.sessions.SessionByToken(tokenHash)
+
+	if err != nil {
+		return nil, "", apperr.Internal(err, "could not refresh session")
+	}
+
+	if session == nil {
+		reused, err := s.sessions.SessionByPreviousToken(tokenHash)
+
+		if err != nil {
+			return nil, "", apperr.Internal(err, "could not refresh session")
+		}
+
+		if reused != nil {
+			_, err = s.sessions.RevokeSessions(*reused.UserID, *reused.ID, now)
+
+			if err != nil {
+				return nil, "", apperr.Internal(err, "could not refresh session")
+			}
+		}
+		return nil, "", apperr.Unauthenticated("invalid refresh token")
+	}
+
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, "", apperr.Unauthenticated("session expired")
+	}
+
+	rotated, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, "", apperr.Internal(err, "could not refresh session")
+	}
+
+	rotatedHash := utils.HashToken(rotated)
+
+	changed, err := s.sessions.RotateToken(*session.ID, tokenHash, rotatedHash, now)
+
+	if err != nil {
+		return nil, "", apperr.Internal(err, "could not refresh session")
+	}
+
+	// a concurrent refresh already rotated this token
+	if !changed {
+		return nil, "", apperr.Unauthenticated("invalid refresh token")
+	}
+
+	session.PreviousTokenHash = tokenHash
+	session.RefreshTokenHash = rotatedHash
+	session.LastUsedAt = now
+	return session, rotated, nil
+}
+
+// Revoke ends one of a user's live sessions
+func (s *SessionService) Revoke(user *models.Users, sessionId string) error {
+	// an empty id would revoke every session
+	if len(sessionId) == 0 {
+		return apperr.NotFound("session not found")
+	}
+
+	revoked, err := s.sessions.RevokeSessions(*user.Uuid, sessionId, time.Now())
+
+	if err != nil {
+		return apperr.Internal(err, "could not revoke session")
+	}
+
+	if revoked == 0 {
+		return apperr.NotFound("session not found")
+	}
+	return nil
+}
+
+// RevokeAll ends every live session of a user, e.g. after a token leak
+func (s *SessionService) RevokeAll(user *models.Users) error {
+	_, err := s.sessions.RevokeSessions(*user.Uuid, "", time.Now())
+
+	if err != nil {
+		return apperr.Internal(err, "could not logout")
+	}
+	return nil
+}
+
+// List pages through a user's live sessions
+func (s *SessionService) List(user *models.Users, page Page) ([]models.Session, int64, error) {
+	sessions, total, err := s.sessions.ListSessions(*user.Uuid, time.Now(), page)
+
+	if err != nil {
+		return nil, 0, apperr.Internal(err, "could not get sessions")
+	}
+	return sessions, total, nil
+}
@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+)
+
+func openSession(t *testing.T, svc *services.Services, user *models.Users) (*models.Session, string) {
+	t.Helper()
+
+	session, refreshToken, err := svc.Sessions.Open(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session, refreshToken
+}
+
+func TestRefreshRotation(t *testing.T) {
+	svc, store := newServices()
+
+	user := addUser(store, "frank")
+	session, first := openSession(t, svc, user)
+	other, otherToken := openSession(t, svc, user)
+
+	_, _, err := svc.Sessions.Refresh("made up")
+	expectCode(t, err, apperr.CodeUnauthenticated)
+
+	refreshed, second, err := svc.Sessions.Refresh(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *refreshed.ID != *session.ID || second == first {
+		t.Fatalf("expected the same session with a new refresh token, got %s", *refreshed.ID)
+	}
+
+	// replaying the old token means it leaked, so the whole session dies
+	_, _, err = svc.Sessions.Refresh(first)
+	expectCode(t, err, apperr.CodeUnauthenticated)
+
+	_, _, err = svc.Sessions.Refresh(second)
+	expectCode(t, err, apperr.CodeUnauthenticated)
+
+	if live, _ := svc.Sessions.Live(*session.ID, *user.Uuid); live != nil {
+		t.Fatalf("expected the leaked session to be revoked, got %+v", live)
+	}
+
+	// other sessions are untouched
+	if live, _ := svc.Sessions.Live(*other.ID, *user.Uuid); live == nil {
+		t.Fatal("expected the other session to stay live")
+	}
+	if _, _, err = svc.Sessions.Refresh(otherToken); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	svc, store := newServices()
+
+	user := addUser(store, "grace")
+	stranger := addUser(store, "heidi")
+
+	session, refreshToken := openSession(t, svc, user)
+	second, _ := openSession(t, svc, user)
+	theirs, _ := openSession(t, svc, stranger)
+
+	page := services.Page{Limit: 10, Sort: "last_used_at", Desc: true}
+
+	if _, total, _ := svc.Sessions.List(user, page); total != 2 {
+		t.Fatalf("expected two sessions, got %d", total)
+	}
+
+	// a session is only found by the user it belongs to
+	expectCode(t, svc.Sessions.Revoke(stranger, *session.ID), apperr.CodeNotFound)
+	expectCode(t, svc.Sessions.Revoke(user, ""), apperr.CodeNotFound)
+
+	if err := svc.Sessions.Revoke(user, *session.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectCode(t, svc.Sessions.Revoke(user, *session.ID), apperr.CodeNotFound)
+
+	_, _, err := svc.Sessions.Refresh(refreshToken)
+	expectCode(t, err, apperr.CodeUnauthenticated)
+
+	if err = svc.Sessions.RevokeAll(user); err != nil {
+		t.Fatal(err)
+	}
+	if live, _ := svc.Sessions.Live(*second.ID, *user.Uuid); live != nil {
+		t.Fatal("expected every session of the user to be revoked")
+	}
+	if live, _ := svc.Sessions.Live(*theirs.ID, *stranger.Uuid); live == nil {
+		t.Fatal("expected someone else's session to stay live")
+	}
+	if _, total, _ := svc.Sessions.List(user, page); total != 0 {
+		t.Fatalf("expected no live sessions, got %d", total)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// NewUser is what a visitor registers with
+type NewUser struct {
+	Username *string
+	Name     *string
+	Password string
+}
+
+type UserService struct {
+	users   UserStore
+	avatars utils.AvatarProvider
+}
+
+func usernameTaken() error {
+	return apperr.Conflict("username is already taken").WithFields(map[string]string{"username": "is already taken"})
+}
+
+// Register creates an account with a hashed password and a generated avatar
+func (s *UserService) Register(ctx context.Context, incoming NewUser) (*models.Users, error) {
+	taken, err := s.users.UsernameTaken(*incoming.Username)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not check username")
+	}
+
+	if taken {
+		return nil, usernameTaken()
+	}
+
+	passwordHash, err := utils.HashPassword(incoming.Password)
+
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return nil, apperr.InvalidField("password", "must be at most 72 bytes")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err, "could not hash password")
+	}
+
+	uuid, _ := utils.GenerateUUid()
+
+	user := models.Users{
+		Uuid:     &uuid,
+		Username: incoming.Username,
+		Name:     incoming.Name,
+		Password: &passwordHash,
+	}
+
+	user.ProfilePicture = DefaultAvatar(ctx, s.avatars, &user)
+
+	err = s.users.CreateUser(&user)
+
+	// someone registered the same username since the check above
+	if errors.Is(err, ErrDuplicate) {
+		return nil, usernameTaken()
+	}
+
+	if err != nil {
+		return nil, writeFailed(err, "database insertion failed")
+	}
+
+	return &user, nil
+}
+
+// Login checks a username and password, upgrading how the password is stored
+// when it is plaintext or hashed with an old cost
+func (s *UserService) Login(username string, password string) (*models.Users, error) {
+	user, err := s.users.UserByUsername(username)
+
+	if err != nil || user == nil || user.Password == nil {
+		utils.RejectPassword(password)
+		return nil, apperr.Unauthenticated("invalid username or password")
+	}
+
+	passwordOk, needsRehash := utils.CheckPassword(*user.Password, password)
+
+	if !passwordOk {
+		return nil, apperr.Unauthenticated("invalid username or password")
+	}
+
+	if needsRehash {
+		passwordHash, err := utils.HashPassword(password)
+		if err == nil {
+			err = s.users.SetPassword(*user.Uuid, passwordHash)
+		}
+		if err != nil {
+			log.Printf("could not rehash password for %s: %v", *user.Uuid, err)
+		} else {
+			user.Password = &passwordHash
+		}
+	}
+
+	return user, nil
+}
+
+// Profile loads a user with their active memberships
+func (s *UserService) Profile(userId string) (*models.Users, error) {
+	user, err := s.users.UserByID(userId)
+
+	if err != nil {
+		return nil, apperr.Internal(err, "could not get user")
+	}
+
+	if user == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	return user, nil
+}
+
+// DefaultAvatar is the generated picture for a user without an upload, nil
+// when the provider fails
+func DefaultAvatar(ctx context.Context, provider utils.AvatarProvider, user *models.Users) *string {
+	if provider == nil {
+		provider = utils.InitialsAvatar{}
+	}
+
+	name := ""
+	if user.Name != nil {
+		name = *user.Name
+	} else if user.Username != nil {
+		name = *user.Username
+	}
+
+	picture, err := provider.AvatarURL(ctx, *user.Uuid, name)
+	if err != nil {
+		return nil
+	}
+	return &picture
+}
@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/swayanshu-2003/classroom-backend/apperr"
+	"github.com/swayanshu-2003/classroom-backend/models"
+	"github.com/swayanshu-2003/classroom-backend/services"
+	"github.com/swayanshu-2003/classroom-backend/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRegister(t *testing.T) {
+	svc, _ := newServices()
+
+	user := register(t, svc, "Alice")
+
+	if user.Password == nil || *user.Password == "password123" || !utils.IsPasswordHash(*user.Password) {
+		t.Fatalf("expected the password to be stored hashed, got %v", user.Password)
+	}
+	if user.ProfilePicture == nil {
+		t.Fatal("expected a generated avatar")
+	}
+
+	// usernames are unique ignoring case
+	_, err := svc.Users.Register(context.Background(), services.NewUser{Username: str("alice"), Password: "password123"})
+	expectCode(t, err, apperr.CodeConflict)
+
+	// bcrypt stops at 72 bytes, which 25 multibyte characters already pass
+	_, err = svc.Users.Register(context.Background(), services.NewUser{Username: str("bob"), Password: strings.Repeat("密", 25)})
+	expectCode(t, err, apperr.CodeValidation)
+}
+
+func TestLogin(t *testing.T) {
+	svc, _ := newServices()
+
+	user := register(t, svc, "alice")
+
+	loggedIn, err := svc.Users.Login("alice", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loggedIn.Uuid != *user.Uuid {
+		t.Fatalf("expected to log in as %s, got %s", *user.Uuid, *loggedIn.Uuid)
+	}
+
+	_, err = svc.Users.Login("alice", "wrong password")
+	expectCode(t, err, apperr.CodeUnauthenticated)
+
+	_, err = svc.Users.Login("nobody", "password123")
+	expectCode(t, err, apperr.CodeUnauthenticated)
+}
+
+func TestLoginRehashesPlaintextPasswords(t *testing.T) {
+	svc, store := newServices()
+
+	store.AddUser(models.Users{Uuid: str("legacy"), Username: str("legacy"), Password: str("plaintext")})
+
+	_, err := svc.Users.Login("legacy", "plaintext")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ := store.UserByID("legacy")
+	if !utils.IsPasswordHash(*user.Password) {
+		t.Fatal("expected the plaintext password to be replaced by a hash")
+	}
+
+	_, err = svc.Users.Login("legacy", "plaintext")
+	if err != nil {
+		t.Fatalf("expected the rehashed password to keep working, got %v", err)
+	}
+}
+
+func TestLoginRehashesOldCosts(t *testing.T) {
+	svc, store := newServices()
+
+	cheap, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	store.AddUser(models.Users{Uuid: str("cheap"), Username: str("cheap"), Password: str(string(cheap))})
+
+	// a failed login leaves the hash alone
+	_, err := svc.Users.Login("cheap", "wrong password")
+	expectCode(t, err, apperr.CodeUnauthenticated)
+
+	if user, _ := store.UserByID("cheap"); *user.Password != string(cheap) {
+		t.Fatal("expected a failed login not to rehash")
+	}
+
+	if _, err = svc.Users.Login("cheap", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ := store.UserByID("cheap")
+	if cost, _ := bcrypt.Cost([]byte(*user.Password)); cost != utils.PasswordCost {
+		t.Fatalf("expected the hash to be upgraded to cost %d, got %d", utils.PasswordCost, cost)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	svc, _ := newServices()
+
+	teacher := register(t, svc, "teacher")
+	student := register(t, svc, "student")
+
+	classroom := createClassroom(t, svc, teacher)
+	join(t, svc, classroom, student)
+
+	profile, err := svc.Users.Profile(*student.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profile.Collaborations) != 1 {
+		t.Fatalf("expected one membership, got %d", len(profile.Collaborations))
+	}
+
+	_, err = svc.Users.Profile("missing")
+	expectCode(t, err, apperr.CodeNotFound)
+}
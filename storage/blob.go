@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrBlobNotFound is returned when a key has no stored content
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded file contents addressed by key
+type BlobStore interface {
+	// Put stores the content under key, replacing anything already there
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Get opens the content stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether content is stored under key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the content stored under key, if any
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a download link for key that stops working after expiry
+	SignedURL(ctx context.Context, key string, filename string, expiry time.Duration) (string, error)
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// testBlobStore runs the behaviour every BlobStore shares
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	key := "sha256/ab/abcdef"
+	content := []byte("hello blob")
+
+	exists, err := store.Exists(ctx, key)
+	if err != nil || exists {
+		t.Fatalf("expected no blob before Put, got %v, %v", exists, err)
+	}
+
+	_, err = store.Get(ctx, key)
+	if !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("expected ErrBlobNotFound, got %v", err)
+	}
+
+	err = store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err = store.Exists(ctx, key)
+	if err != nil || !exists {
+		t.Fatalf("expected the blob after Put, got %v, %v", exists, err)
+	}
+
+	reader, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("read back %q, %v", got, err)
+	}
+
+	// Put replaces what is stored
+	replaced := []byte("replaced")
+	err = store.Put(ctx, key, bytes.NewReader(replaced), int64(len(replaced)), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err = store.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(got, replaced) {
+		t.Fatalf("expected the replaced content, got %q", got)
+	}
+
+	err = store.Delete(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err = store.Exists(ctx, key)
+	if err != nil || exists {
+		t.Fatalf("expected no blob after Delete, got %v, %v", exists, err)
+	}
+
+	// deleting what is not there is not an error
+	err = store.Delete(ctx, key)
+	if err != nil {
+		t.Fatalf("expected a second Delete to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalBlobStore keeps blobs as files under Root. Downloads go through the
+// API's file route, authorised by an HMAC signature over the key and expiry.
+type LocalBlobStore struct {
+	Root    string
+	BaseURL string
+	Secret  []byte
+}
+
+func NewLocalBlobStore(root string, baseURL string, secret []byte) (*LocalBlobStore, error) {
+	err := os.MkdirAll(root, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalBlobStore{
+		Root:    root,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Secret:  secret,
+	}, nil
+}
+
+// path maps a key to a file under Root, refusing keys that would escape it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(clean)), nil
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return err
+	}
+
+	// write to a temp file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+func (s *LocalBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalBlobStore) SignedURL(ctx context.Context, key string, filename string, expiry time.Duration) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("name", filename)
+	query.Set("sig", s.sign(key, expires, filename))
+
+	return s.BaseURL + "/api/v1/files/" + key + "?" + query.Encode(), nil
+}
+
+// Verify checks a signature produced by SignedURL and that it has not expired
+func (s *LocalBlobStore) Verify(key string, expires string, filename string, signature string) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+
+	expected := s.sign(key, expires, filename)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func (s *LocalBlobStore) sign(key string, expires string, filename string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte("file\n" + key + "\n" + expires + "\n" + filename))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalStore(t *testing.T) *LocalBlobStore {
+	t.Helper()
+
+	store, err := NewLocalBlobStore(t.TempDir(), "http://localhost:5600/", []byte("test secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	testBlobStore(t, newTestLocalStore(t))
+}
+
+func TestLocalBlobStoreRejectsEscapingKeys(t *testing.T) {
+	store := newTestLocalStore(t)
+
+	for _, key := range []string{"../outside", "a/../../outside", "", "/"} {
+		if _, err := store.Exists(context.Background(), key); err == nil {
+			t.Errorf("expected key %q to be refused", key)
+		}
+	}
+}
+
+// signedQuery signs a url for key and returns its query
+func signedQuery(t *testing.T, store *LocalBlobStore, key string, filename string, expiry time.Duration) url.Values {
+	t.Helper()
+
+	signed, err := store.SignedURL(context.Background(), key, filename, expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(signed, "http://localhost:5600/api/v1/files/"+key+"?") {
+		t.Fatalf("unexpected signed url %s", signed)
+	}
+	return parsed.Query()
+}
+
+func TestLocalBlobStoreSignedURL(t *testing.T) {
+	store := newTestLocalStore(t)
+	key := "sha256/ab/abcdef"
+
+	query := signedQuery(t, store, key, "notes.txt", time.Minute)
+
+	if !store.Verify(key, query.Get("expires"), query.Get("name"), query.Get("sig")) {
+		t.Fatal("expected a fresh signature to verify")
+	}
+
+	if store.Verify("sha256/ab/other", query.Get("expires"), query.Get("name"), query.Get("sig")) {
+		t.Error("expected the signature to be bound to its key")
+	}
+
+	if store.Verify(key, query.Get("expires"), "other.txt", query.Get("sig")) {
+		t.Error("expected the signature to be bound to its filename")
+	}
+
+	later := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	if store.Verify(key, later, query.Get("name"), query.Get("sig")) {
+		t.Error("expected the signature to be bound to its expiry")
+	}
+
+	other := &LocalBlobStore{Root: store.Root, BaseURL: store.BaseURL, Secret: []byte("another secret")}
+	if other.Verify(key, query.Get("expires"), query.Get("name"), query.Get("sig")) {
+		t.Error("expected the signature to be bound to its secret")
+	}
+
+	expired := signedQuery(t, store, key, "notes.txt", -time.Second)
+
+	if store.Verify(key, expired.Get("expires"), expired.Get("name"), expired.Get("sig")) {
+		t.Error("expected an expired signature to be refused")
+	}
+}
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -9,20 +11,73 @@ import (
 
 type Config struct {
 	Host     string
+	Port     int
 	DBName   string
 	Username string
 	Password string
 	SSLmode  string
+
+	ConnectTimeout  time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// dsnValue quotes a value for a key=value connection string, so passwords
+// with spaces or quotes survive
+func dsnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
 }
 
 func NewConnection(config *Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Username, config.Password, config.DBName, config.SSLmode)
+		dsnValue(config.Host), dsnValue(config.Username), dsnValue(config.Password), dsnValue(config.DBName), dsnValue(config.SSLmode))
+
+	if config.Port != 0 {
+		dsn += fmt.Sprintf(" port=%d", config.Port)
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// libpq takes whole seconds
+	if seconds := int(config.ConnectTimeout.Seconds()); seconds > 0 {
+		dsn += fmt.Sprintf(" connect_timeout=%d", seconds)
+	}
+
+	db, err := Open(dsn)
 
 	if err != nil {
 		return db, err
 	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return db, err
+	}
+
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
 	return db, nil
 }
+
+// Open connects to the database a connection string names, with the gorm
+// settings the handlers rely on
+func Open(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{
+		// unique violations come back as gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
+}
+
+// Close closes the connection pool behind db
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newTestS3Store connects to the S3 compatible server named by TEST_S3_ENDPOINT,
+// e.g. a local MinIO, with a fresh bucket; the test is skipped without one
+func newTestS3Store(t *testing.T) *S3BlobStore {
+	t.Helper()
+
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if len(endpoint) == 0 {
+		t.Skip("no S3 server: set TEST_S3_ENDPOINT, TEST_S3_ACCESS_KEY and TEST_S3_SECRET_KEY")
+	}
+
+	store, err := NewS3BlobStore(context.Background(), S3Config{
+		Endpoint:  endpoint,
+		Region:    "us-east-1",
+		Bucket:    fmt.Sprintf("classroom-test-%d", time.Now().UnixNano()),
+		AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+		UseSSL:    os.Getenv("TEST_S3_USE_SSL") == "true",
+	})
+	if err != nil {
+		t.Fatalf("could not connect to TEST_S3_ENDPOINT: %v", err)
+	}
+
+	t.Cleanup(func() {
+		store.client.RemoveBucketWithOptions(context.Background(), store.bucket, minio.RemoveBucketOptions{ForceDelete: true})
+	})
+	return store
+}
+
+func TestS3BlobStore(t *testing.T) {
+	testBlobStore(t, newTestS3Store(t))
+}
+
+func TestS3BlobStoreSignedURL(t *testing.T) {
+	store := newTestS3Store(t)
+	ctx := context.Background()
+	key := "sha256/ab/abcdef"
+
+	content := "signed content"
+	err := store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(expiry time.Duration, wait time.Duration) (int, string) {
+		signed, err := store.SignedURL(ctx, key, "notes.txt", expiry)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(wait)
+
+		res, err := http.Get(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	status, body := fetch(time.Minute, 0)
+	if status != http.StatusOK || body != content {
+		t.Fatalf("expected the content through a fresh link, got %d %q", status, body)
+	}
+
+	// presigned links last whole seconds
+	status, _ = fetch(time.Second, 2*time.Second)
+	if status != http.StatusForbidden {
+		t.Fatalf("expected an expired link to be refused, got %d", status)
+	}
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// transactionAttempts bounds how often a transaction is retried after postgres
+// aborted it to resolve a conflict with a concurrent one
+const transactionAttempts = 4
+
+// postgres aborts one side of a conflict with these codes; running the
+// transaction again usually succeeds
+var retryableCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// Retryable reports whether err aborted a transaction that may succeed when run again
+func Retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && retryableCodes[pgErr.Code]
+}
+
+// serializable makes a transaction behave as if it ran alone, so a check it
+// reads stays true until it commits; postgres aborts it with a serialization
+// failure instead when a concurrent one invalidated what it read
+var serializable = &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+// Transaction is the unit of work for writes that touch several rows: fn runs
+// in one serializable transaction that commits when it returns nil and rolls
+// back otherwise. A transaction aborted by a serialization failure or
+// deadlock is run again, so fn must not keep state from an earlier attempt.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	// inside another transaction this is a savepoint; only the outermost
+	// transaction can be run again
+	if _, nested := db.Statement.ConnPool.(gorm.TxCommitter); nested {
+		return db.Transaction(fn)
+	}
+
+	var err error
+
+	for attempt := 1; attempt <= transactionAttempts; attempt++ {
+		err = db.Transaction(fn, serializable)
+
+		if !Retryable(err) {
+			return err
+		}
+
+		// back off with jitter so the conflicting transactions do not collide again
+		backoff := time.Duration(attempt*attempt) * 10 * time.Millisecond
+		time.Sleep(backoff/2 + time.Duration(rand.Int63n(int64(backoff))))
+	}
+	return err
+}
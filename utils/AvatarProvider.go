@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AvatarProvider picks a default profile picture for a new user. seed is
+// stable per user so the same user always gets the same avatar.
+type AvatarProvider interface {
+	AvatarURL(ctx context.Context, seed string, name string) (string, error)
+}
+
+// InitialsAvatar draws the user's initials on a colour derived from the
+// seed and returns it as an inline SVG data url. It never touches the network.
+type InitialsAvatar struct{}
+
+var avatarColors = []string{
+	"#1a73e8", "#d93025", "#188038", "#e37400", "#9334e6",
+	"#007b83", "#c5221f", "#3f51b5", "#795548", "#546e7a",
+}
+
+func (InitialsAvatar) AvatarURL(ctx context.Context, seed string, name string) (string, error) {
+	sum := sha256.Sum256([]byte(seed))
+	color := avatarColors[int(sum[0])%len(avatarColors)]
+
+	svg := fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="128" height="128" viewBox="0 0 128 128">`+
+			`<rect width="128" height="128" fill="%s"/>`+
+			`<text x="50%%" y="50%%" dy=".35em" text-anchor="middle" fill="#ffffff" font-family="Arial, sans-serif" font-size="52">%s</text>`+
+			`</svg>`,
+		color, html.EscapeString(initials(name)),
+	)
+
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg)), nil
+}
+
+// initials takes the first letter of the first two words of a name
+func initials(name string) string {
+	letters := []rune{}
+
+	for _, word := range strings.Fields(name) {
+		for _, c := range word {
+			if unicode.IsLetter(c) || unicode.IsDigit(c) {
+				letters = append(letters, unicode.ToUpper(c))
+				break
+			}
+		}
+		if len(letters) == 2 {
+			break
+		}
+	}
+
+	if len(letters) == 0 {
+		return "?"
+	}
+	return string(letters)
+}
+
+// RemoteAvatar asks randomuser.me for a picture, bounded by Timeout, and
+// falls back to Fallback on any failure so sign up never depends on it.
+type RemoteAvatar struct {
+	URL      string
+	Timeout  time.Duration
+	Client   *http.Client
+	Fallback AvatarProvider
+}
+
+func NewRemoteAvatar(timeout time.Duration) *RemoteAvatar {
+	return &RemoteAvatar{
+		URL:      "https://randomuser.me/api/?inc=picture&results=1",
+		Timeout:  timeout,
+		Client:   &http.Client{Timeout: timeout},
+		Fallback: InitialsAvatar{},
+	}
+}
+
+type pictureResponse struct {
+	Results []struct {
+		Picture struct {
+			Large string `json:"large"`
+		} `json:"picture"`
+	} `json:"results"`
+}
+
+func (p *RemoteAvatar) AvatarURL(ctx context.Context, seed string, name string) (string, error) {
+	picture, err := p.fetch(ctx)
+
+	if err != nil && p.Fallback != nil {
+		return p.Fallback.AvatarURL(ctx, seed, name)
+	}
+	return picture, err
+}
+
+func (p *RemoteAvatar) fetch(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := p.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("avatar provider answered %d", res.StatusCode)
+	}
+
+	var body pictureResponse
+
+	err = json.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(&body)
+	if err != nil {
+		return "", err
+	}
+
+	if len(body.Results) == 0 {
+		return "", errors.New("avatar provider returned no pictures")
+	}
+
+	picture, err := url.Parse(body.Results[0].Picture.Large)
+	if err != nil || picture.Scheme != "https" {
+		return "", errors.New("avatar provider returned an invalid url")
+	}
+
+	return picture.String(), nil
+}
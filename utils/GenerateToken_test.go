@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var secret = []byte("a secret of enough length for signing")
+
+func TestAccessToken(t *testing.T) {
+	token, expiresAt, err := GenerateAccessToken(secret, "user", "session", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseAccessToken(secret, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user" || claims.SessionID != "session" || !claims.ExpiresAt.Time.Equal(expiresAt.Truncate(time.Second)) {
+		t.Fatalf("expected the claims to survive, got %+v", claims)
+	}
+
+	if _, err = ParseAccessToken([]byte("another secret of enough length"), token); err == nil {
+		t.Error("expected a token signed with another secret to be refused")
+	}
+
+	expired, _, _ := GenerateAccessToken(secret, "user", "session", -time.Minute)
+	if _, err = ParseAccessToken(secret, expired); err == nil {
+		t.Error("expected an expired token to be refused")
+	}
+}
+
+func TestAccessTokenRefusesForgedClaims(t *testing.T) {
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Minute))
+
+	forged := map[string]jwt.Claims{
+		// unsigned tokens must not get past the signature check
+		"unsigned":   AccessTokenClaims{SessionID: "session", RegisteredClaims: jwt.RegisteredClaims{Subject: "user", ExpiresAt: expiry}},
+		"no expiry":  AccessTokenClaims{SessionID: "session", RegisteredClaims: jwt.RegisteredClaims{Subject: "user"}},
+		"no session": AccessTokenClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user", ExpiresAt: expiry}},
+	}
+
+	for name, claims := range forged {
+		method, key := jwt.SigningMethod(jwt.SigningMethodHS256), interface{}(secret)
+		if name == "unsigned" {
+			method, key = jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType
+		}
+
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = ParseAccessToken(secret, token); err == nil {
+			t.Errorf("expected a token with %s to be refused", name)
+		}
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	token, err := GenerateRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if HashToken(token) != HashToken(token) || HashToken(token) == token || len(HashToken(token)) != 64 {
+		t.Fatalf("expected a stable sha256 digest, got %q", HashToken(token))
+	}
+
+	other, _ := GenerateRefreshToken()
+	if other == token {
+		t.Fatal("expected refresh tokens to be random")
+	}
+}
@@ -1,9 +1,8 @@
 package utils
 
 import (
-	"math/rand"
-	"strconv"
-	"time"
+	"crypto/rand"
+	"math/big"
 
 	"github.com/google/uuid"
 )
@@ -17,17 +16,30 @@ func GenerateUUid() (string, error) {
 	return newUUID.String(), nil
 }
 
+// GenerateClassroomId returns the stable internal id of a new classroom.
+// People join with the separate join code, never with this id.
 func GenerateClassroomId() *string {
-	// Seed the random number generator to get different results each time
-	rand.Seed(time.Now().UnixNano())
+	id := uuid.NewString()
+	return &id
+}
+
+// join codes leave out 0/O, 1/I/L so they survive being read aloud or copied by hand
+const joinCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
 
-	// Generate a random number between 10000 and 99999
-	min := 10000
-	max := 99999
-	randomNumber := rand.Intn(max-min+1) + min
+const JoinCodeLength = 7
 
-	// Convert the random number to a string
-	randomString := strconv.Itoa(randomNumber)
+// GenerateJoinCode returns a random join code drawn from crypto/rand
+func GenerateJoinCode() (string, error) {
+	code := make([]byte, JoinCodeLength)
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = joinCodeAlphabet[n.Int64()]
+	}
 
-	return &randomString
+	return string(code), nil
 }
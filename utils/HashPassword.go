@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordCost is the bcrypt work factor for new hashes; stored hashes
+// with a different cost are upgraded on the next successful login
+var PasswordCost = 12
+
+// dummyHash is compared against when the user does not exist so a missing
+// username takes as long to reject as a wrong password
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("classroom-dummy-password"), PasswordCost)
+
+// HashPassword hashes a plaintext password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// IsPasswordHash reports whether a stored password is already a bcrypt hash
+func IsPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// CheckPassword verifies a password against the stored value. Legacy
+// plaintext rows are compared in constant time. needsRehash is true when
+// the password matched but the stored value should be replaced by a fresh hash
+func CheckPassword(stored string, password string) (ok bool, needsRehash bool) {
+	if !IsPasswordHash(stored) {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(password)) != 1 {
+			return false, false
+		}
+		return true, true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(stored))
+	return true, err != nil || cost != PasswordCost
+}
+
+// RejectPassword burns the same time as a real check, for unknown users
+func RejectPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPasswordHash(hash) || IsPasswordHash("correct horse") {
+		t.Fatalf("expected only the hash to look like one, got %q", hash)
+	}
+
+	cheap, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+
+	cases := []struct {
+		name       string
+		stored     string
+		password   string
+		ok, rehash bool
+	}{
+		{"current hash", hash, "correct horse", true, false},
+		{"wrong password", hash, "wrong horse", false, false},
+		{"older cost", string(cheap), "correct horse", true, true},
+		{"plaintext", "correct horse", "correct horse", true, true},
+		{"wrong plaintext", "correct horse", "correct", false, false},
+	}
+
+	for _, c := range cases {
+		ok, rehash := CheckPassword(c.stored, c.password)
+		if ok != c.ok || rehash != c.rehash {
+			t.Errorf("%s: expected ok %v and rehash %v, got %v and %v", c.name, c.ok, c.rehash, ok, rehash)
+		}
+	}
+}
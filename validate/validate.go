@@ -0,0 +1,293 @@
+// Package validate checks request structs against their `validate` tags.
+//
+// A tag is a comma separated list of rules:
+//
+//	required    strings must not be blank, pointers not nil, slices not empty
+//	min=N       strings and slices need at least N characters or items, numbers a value of N
+//	max=N       the same as min, as an upper bound
+//	oneof=a b   strings must be one of the space separated values
+//	username    strings may only use letters, digits, '_', '.' and '-'
+//	url         strings must be absolute http or https urls
+//	password    strings need 8 characters and at most 72 bytes as sent, spaces
+//	            included, since bcrypt hashes no more than that
+//
+// Rules other than required are skipped for nil pointers and empty strings,
+// so optional fields are only checked when they are sent. A blank string sent
+// through a pointer still has to meet its min and max. Structs inside slices
+// are checked as well, keyed like "criteria[2].title".
+//
+// Tags reports a malformed tag without waiting for a request to reach it.
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcrypt refuses passwords longer than maxPasswordBytes
+const (
+	minPasswordChars = 8
+	maxPasswordBytes = 72
+)
+
+// Struct validates v, a struct or a pointer to one, and returns a message per
+// invalid field keyed by its json name. It returns nil when v is valid.
+func Struct(v interface{}) map[string]string {
+	fields := map[string]string{}
+	checkStruct(reflect.Indirect(reflect.ValueOf(v)), "", fields)
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+func checkStruct(value reflect.Value, prefix string, fields map[string]string) {
+	kind := value.Type()
+
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := prefix + jsonName(field)
+		fieldValue := value.Field(i)
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			if message := checkField(fieldValue, tag); len(message) != 0 {
+				fields[name] = message
+				continue
+			}
+		}
+
+		if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.Struct {
+			for j := 0; j < fieldValue.Len(); j++ {
+				checkStruct(fieldValue.Index(j), fmt.Sprintf("%s[%d].", name, j), fields)
+			}
+		}
+	}
+}
+
+// Tags checks the validate tags of v, a struct or a pointer to one, and of the
+// structs inside its slices. It returns an error for the first unknown rule, bad
+// argument or rule that cannot apply to its field.
+func Tags(v interface{}) error {
+	return checkTags(reflect.Indirect(reflect.ValueOf(v)).Type())
+}
+
+func checkTags(kind reflect.Type) error {
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			for _, rule := range strings.Split(tag, ",") {
+				name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+				if err := checkRule(fieldType, name, arg); err != nil {
+					return fmt.Errorf("%s.%s: %w", kind.Name(), field.Name, err)
+				}
+			}
+		}
+
+		if fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Struct {
+			if err := checkTags(fieldType.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRule reports whether a rule can be applied to values of kind
+func checkRule(kind reflect.Type, name string, arg string) error {
+	switch name {
+	case "required":
+		return nil
+
+	case "min", "max":
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return fmt.Errorf("bad %s argument %q", name, arg)
+		}
+		if !measurable(kind.Kind()) {
+			return fmt.Errorf("%s does not apply to %s", name, kind)
+		}
+		return nil
+
+	case "oneof":
+		if len(strings.Fields(arg)) == 0 {
+			return fmt.Errorf("oneof needs at least one value")
+		}
+		fallthrough
+
+	case "username", "url", "password":
+		if kind.Kind() != reflect.String {
+			return fmt.Errorf("%s does not apply to %s", name, kind)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown rule %q", name)
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if len(name) == 0 || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// checkField returns the message for the first rule value breaks
+func checkField(value reflect.Value, tag string) string {
+	sent := false
+
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			if hasRule(tag, "required") {
+				return "is required"
+			}
+			return ""
+		}
+		// a pointer that was sent counts as present, even when it points at zero
+		value = value.Elem()
+		sent = true
+	}
+
+	blank := value.Kind() == reflect.String && len(strings.TrimSpace(value.String())) == 0
+
+	if blank {
+		if hasRule(tag, "required") {
+			return "is required"
+		}
+		if !sent {
+			return ""
+		}
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		if name == "required" && sent {
+			continue
+		}
+
+		// a blank string that was sent is only held to its length
+		if blank && name != "min" && name != "max" {
+			continue
+		}
+
+		if message := applyRule(value, name, arg); len(message) != 0 {
+			return message
+		}
+	}
+	return ""
+}
+
+func hasRule(tag string, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func applyRule(value reflect.Value, name string, arg string) string {
+	switch name {
+	case "required":
+		if value.Kind() == reflect.Slice && value.Len() == 0 {
+			return "is required"
+		}
+		if value.Kind() != reflect.Slice && value.Kind() != reflect.String && value.IsZero() {
+			return "is required"
+		}
+
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic("validate: bad " + name + " argument " + arg)
+		}
+
+		size, unit := measure(value)
+		if name == "min" && size < limit {
+			return "must be at least " + arg + unit
+		}
+		if name == "max" && size > limit {
+			return "must be at most " + arg + unit
+		}
+
+	case "oneof":
+		allowed := strings.Fields(arg)
+		for _, option := range allowed {
+			if value.String() == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(allowed, ", ")
+
+	case "username":
+		for _, c := range value.String() {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+				return "may only contain letters, digits, '_', '.' and '-'"
+			}
+		}
+
+	case "password":
+		if utf8.RuneCountInString(value.String()) < minPasswordChars {
+			return "must be at least 8 characters"
+		}
+		if len(value.String()) > maxPasswordBytes {
+			return "must be at most 72 bytes"
+		}
+
+	case "url":
+		parsed, err := url.Parse(value.String())
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+			return "must be an http or https url"
+		}
+
+	default:
+		panic("validate: unknown rule " + name)
+	}
+	return ""
+}
+
+func measurable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Slice, reflect.Map,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// measure is what min and max compare against, with the unit to report it in
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(strings.TrimSpace(value.String()))), " characters"
+	case reflect.Slice, reflect.Map:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	panic("validate: min and max do not apply to " + value.Kind().String())
+}
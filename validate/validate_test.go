@@ -0,0 +1,91 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+type optional struct {
+	Username *string `json:"username" validate:"min=1,max=32"`
+	Link     *string `json:"link" validate:"max=2048,url"`
+	Note     string  `json:"note" validate:"min=3"`
+}
+
+func TestSentBlankStringsKeepTheirLength(t *testing.T) {
+	blank := "  "
+
+	fields := Struct(optional{Username: &blank, Link: &blank})
+
+	if fields["username"] != "must be at least 1 characters" {
+		t.Errorf("expected a blank username to be too short, got %v", fields)
+	}
+
+	// a blank link clears it, and a note that was left out is not checked
+	if _, ok := fields["link"]; ok {
+		t.Errorf("expected a blank link to pass, got %v", fields)
+	}
+	if _, ok := fields["note"]; ok {
+		t.Errorf("expected a missing note to pass, got %v", fields)
+	}
+
+	if fields := Struct(optional{}); fields != nil {
+		t.Errorf("expected nothing sent to be valid, got %v", fields)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	type register struct {
+		Password string `json:"password" validate:"required,password"`
+	}
+
+	cases := map[string]string{
+		"  secret  ":            "",
+		strings.Repeat("a", 72): "",
+		"short":                 "must be at least 8 characters",
+		strings.Repeat("a", 73): "must be at most 72 bytes",
+		strings.Repeat("密", 25): "must be at most 72 bytes",
+	}
+
+	for password, want := range cases {
+		if got := Struct(register{Password: password})["password"]; got != want {
+			t.Errorf("expected %q for a password of %d bytes, got %q", want, len(password), got)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	if err := Tags(optional{}); err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		Points int `validate:"max=ten"`
+	}
+
+	broken := []interface{}{
+		&struct {
+			Name string `validate:"required,shiny"`
+		}{},
+		struct {
+			Count int `validate:"min="`
+		}{},
+		struct {
+			Open bool `validate:"min=1"`
+		}{},
+		struct {
+			Age int `validate:"url"`
+		}{},
+		struct {
+			Kind string `validate:"oneof="`
+		}{},
+		struct {
+			Rows []row `validate:"max=5"`
+		}{},
+	}
+
+	for _, v := range broken {
+		if err := Tags(v); err == nil {
+			t.Errorf("expected the tags of %T to be refused", v)
+		}
+	}
+}